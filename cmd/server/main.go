@@ -12,18 +12,23 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/toricodesthings/file-processing-service/internal/budget"
 	"github.com/toricodesthings/file-processing-service/internal/config"
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	archiveextractor "github.com/toricodesthings/file-processing-service/internal/extractors/archive"
 	audioextractor "github.com/toricodesthings/file-processing-service/internal/extractors/audio"
 	codeextractor "github.com/toricodesthings/file-processing-service/internal/extractors/code"
 	ebookextractor "github.com/toricodesthings/file-processing-service/internal/extractors/ebook"
+	emailextractor "github.com/toricodesthings/file-processing-service/internal/extractors/email"
 	imageextractor "github.com/toricodesthings/file-processing-service/internal/extractors/image"
 	officeextractor "github.com/toricodesthings/file-processing-service/internal/extractors/office"
 	opendocumentextractor "github.com/toricodesthings/file-processing-service/internal/extractors/opendocument"
@@ -32,19 +37,30 @@ import (
 	structuredextractor "github.com/toricodesthings/file-processing-service/internal/extractors/structured"
 	videoextractor "github.com/toricodesthings/file-processing-service/internal/extractors/video"
 	"github.com/toricodesthings/file-processing-service/internal/hybrid"
+	"github.com/toricodesthings/file-processing-service/internal/jobs"
+	appmetrics "github.com/toricodesthings/file-processing-service/internal/metrics"
 	"github.com/toricodesthings/file-processing-service/internal/ocr"
+	"github.com/toricodesthings/file-processing-service/internal/tracing"
+	"github.com/toricodesthings/file-processing-service/internal/transcribe"
 	"github.com/toricodesthings/file-processing-service/internal/types"
-	"golang.org/x/sync/semaphore"
+	"github.com/toricodesthings/file-processing-service/internal/upstream"
+	"github.com/toricodesthings/file-processing-service/internal/usage"
+	"github.com/toricodesthings/file-processing-service/internal/vision"
 	"golang.org/x/time/rate"
 )
 
+const maxOptionsHeaderBytes = 16 << 10
+
 var (
 	cfg config.Config
 
-	requestSem *semaphore.Weighted
-	extractRt  *extract.Router
-	extractReg *extract.Registry
-	hybridProc *hybrid.Processor
+	requestSem   *appmetrics.Semaphore
+	extractRt    *extract.Router
+	extractReg   *extract.Registry
+	hybridProc   *hybrid.Processor
+	jobManager   *jobs.Manager
+	resultCache  extract.ResultCache
+	ocrPageCache hybrid.PageCache
 
 	// Per-IP rate limiters
 	limiters = &sync.Map{}
@@ -84,12 +100,33 @@ func main() {
 	if err := cfg.Validate(); err != nil {
 		panic(err)
 	}
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Endpoint:    cfg.OTelEndpoint,
+		ServiceName: cfg.OTelServiceName,
+		SampleRatio: cfg.OTelSampleRatio,
+	})
+	if err != nil {
+		panic(err)
+	}
 
-	requestSem = semaphore.NewWeighted(cfg.MaxConcurrentRequests)
+	requestSem = appmetrics.NewSemaphore("requests", cfg.MaxConcurrentRequests)
+	appmetrics.WatchTempDir(os.TempDir(), "fileproc-")
 	ocr.SetConcurrencyLimit(cfg.MaxOCRConcurrent)
+	configureUpstreams(cfg)
+	configurePrices(cfg)
+	if err := configureBudgets(cfg); err != nil {
+		panic(err)
+	}
 
 	processor := hybrid.New(cfg)
 	hybridProc = processor
+	ocrPageCache, err = newPageCache(cfg)
+	if err != nil {
+		panic(err)
+	}
+	if ocrPageCache != nil {
+		processor.SetPageCache(ocrPageCache)
+	}
 	registry := extract.NewRegistry()
 	extractReg = registry
 
@@ -97,7 +134,7 @@ func main() {
 
 	// Register extractors — order matters: more-specific first
 	registry.Register(pdfextractor.New(processor, cfg.MaxPDFBytes))
-	registry.Register(imageextractor.New(cfg.DefaultOCRModel, cfg.DefaultVisionModel, cfg.VisionRequestTimeout, cfg.MaxImageBytes))
+	registry.Register(imageextractor.New(processor.OCRProviders(), cfg.DefaultOCRModel, cfg.DefaultVisionModel, cfg.VisionRequestTimeout, cfg.MaxImageBytes))
 	registry.Register(plaintextextractor.New(cfg.MaxCodeFileBytes))
 	registry.Register(plaintextextractor.NewHTML(cfg.MaxCodeFileBytes))
 	registry.Register(plaintextextractor.NewRTF(cfg.MaxCodeFileBytes))
@@ -114,25 +151,76 @@ func main() {
 	registry.Register(officeextractor.NewLegacy(cfg.LibreOfficeBinary, cfg.LibreOfficeTimeout, cfg.MaxFileBytes))
 	registry.Register(opendocumentextractor.New(cfg.MaxFileBytes))
 	registry.Register(ebookextractor.NewEPUB(cfg.MaxFileBytes))
+	registry.Register(emailextractor.New(registry, cfg.MaxFileBytes))
+	registry.Register(emailextractor.NewMSG(registry, cfg.MaxFileBytes))
 	registry.Register(audioX)
 	registry.Register(videoextractor.New(cfg.FFmpegBinary, cfg.FFmpegTimeout, audioX, cfg.MaxVideoBytes))
+	// Fallbacks run when the native extractor for the same type fails.
+	registry.RegisterPriority(officeextractor.NewFallback(cfg.LibreOfficeBinary, cfg.LibreOfficeTimeout, cfg.MaxFileBytes), extract.PriorityFallback)
+	registry.RegisterPriority(ebookextractor.NewHTMLFallback(cfg.MaxFileBytes), extract.PriorityFallback)
+	// Archives resolve their entries through this same registry (including nested archives).
+	registry.Register(archiveextractor.New(registry, cfg.MaxFileBytes, archiveextractor.Limits{
+		MaxEntries:    cfg.ArchiveMaxEntries,
+		MaxTotalBytes: cfg.ArchiveMaxTotalBytes,
+		MaxDepth:      cfg.ArchiveMaxDepth,
+		MaxRatio:      cfg.ArchiveMaxRatio,
+	}))
 
 	extractRt = extract.NewRouter(registry, cfg.MaxFileBytes, cfg.DownloadTimeout)
 	extractRt.SetSuccessHook(logExtractionSuccess)
+	resultCache, err = newResultCache(cfg)
+	if err != nil {
+		panic(err)
+	}
+	if resultCache != nil {
+		extractRt.SetCache(resultCache)
+	}
+
+	jobStore, err := newJobStore(cfg)
+	if err != nil {
+		panic(err)
+	}
+	jobManager = jobs.NewManager(jobStore, requestSem, runJob, jobs.Config{
+		Workers:             cfg.JobWorkers,
+		QueueSize:           cfg.JobQueueSize,
+		Timeout:             cfg.JobTimeout,
+		Retention:           cfg.JobRetention,
+		WebhookSecret:       cfg.JobWebhookSecret,
+		WebhookTimeout:      cfg.JobWebhookTimeout,
+		AllowPrivateWebhook: cfg.AllowPrivateDownloadURLs,
+	})
+	jobManager.Start(context.Background())
 
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/health", handleHealth)
 	mux.HandleFunc("/metrics", withInternalAuth(handleMetrics))
+	mux.HandleFunc("/capabilities", withInternalAuth(withMethod("GET", handleCapabilities)))
 
 	// Universal extraction endpoint — all file types route through here
 	mux.HandleFunc("/extract",
 		withInternalAuth(
 			withRateLimit(
 				withMethod("POST",
-					withConcurrencyLimit(func(w http.ResponseWriter, r *http.Request) {
-						handleUniversalExtract(w, r)
-					})))))
+					withTenant(
+						withConcurrencyLimit(func(w http.ResponseWriter, r *http.Request) {
+							handleUniversalExtract(w, r)
+						}))))))
+
+	// Same as /extract, streamed as server-sent progress events plus the final result.
+	mux.HandleFunc("/extract/stream",
+		withInternalAuth(
+			withRateLimit(
+				withMethod("POST",
+					withTenant(
+						withConcurrencyLimit(handleExtractStream))))))
+
+	// Classification only — which extractor a file would get, and why.
+	mux.HandleFunc("/detect",
+		withInternalAuth(
+			withRateLimit(
+				withMethod("POST",
+					withConcurrencyLimit(handleDetect)))))
 
 	// Low-cost preview endpoint — free extraction paths only
 	mux.HandleFunc("/preview",
@@ -143,14 +231,32 @@ func main() {
 						handlePreview(w, r)
 					})))))
 
+	// Batch extraction — NDJSON stream, one result line per item.
+	// Items acquire requestSem individually instead of the whole batch holding one slot.
+	mux.HandleFunc("/extract/batch",
+		withInternalAuth(
+			withRateLimit(
+				withMethod("POST", withTenant(handleBatchExtract)))))
+
+	// Async jobs — enqueue long extractions and poll or receive a webhook.
+	// Submission does not take requestSem; job workers acquire it when they run.
+	mux.HandleFunc("/jobs",
+		withInternalAuth(
+			withRateLimit(
+				withMethod("POST", withTenant(handleJobSubmit)))))
+	mux.HandleFunc("/jobs/{id}",
+		withInternalAuth(
+			withMethod("GET", handleJobStatus)))
+
 	maxHeaderBytes := 1 << 20
 	if cfg.MaxHeaderBytes > 0 {
 		maxHeaderBytes = cfg.MaxHeaderBytes
 	}
 
+	// Tracing sits outermost so the request span covers logging and recovery.
 	srv := &http.Server{
 		Addr:              ":" + cfg.Port,
-		Handler:           withLogging(withRecovery(mux)),
+		Handler:           tracing.Middleware(withLogging(withRecovery(mux))),
 		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
 		ReadTimeout:       cfg.ReadTimeout,
 		WriteTimeout:      cfg.WriteTimeout,
@@ -159,7 +265,11 @@ func main() {
 	}
 
 	if strings.TrimSpace(cfg.MistralAPIKey) == "" {
-		fmt.Fprintln(os.Stderr, "warning: MISTRAL_API_KEY not set (OCR will fail)")
+		if cfg.OCRProvider == ocr.ProviderMistral {
+			fmt.Fprintln(os.Stderr, "warning: MISTRAL_API_KEY not set (OCR will fail)")
+		} else {
+			fmt.Fprintln(os.Stderr, "warning: MISTRAL_API_KEY not set (requests with ocrProvider=mistral will fail)")
+		}
 	}
 	if strings.TrimSpace(cfg.OpenRouterAPIKey) == "" {
 		fmt.Fprintln(os.Stderr, "warning: OPENROUTER_API_KEY not set (vision classification will fall back to OCR-only)")
@@ -170,12 +280,29 @@ func main() {
 
 	go cleanupRateLimiters()
 
-	fmt.Printf("fileproc listening on %s (max concurrent: %d, OCR: %d)\n",
-		srv.Addr, cfg.MaxConcurrentRequests, cfg.MaxOCRConcurrent)
+	fmt.Printf("fileproc listening on %s (max concurrent: %d, OCR: %d via %s)\n",
+		srv.Addr, cfg.MaxConcurrentRequests, cfg.MaxOCRConcurrent, cfg.OCRProvider)
+
+	// On SIGTERM stop accepting requests, let in-flight ones finish and flush
+	// buffered spans before exiting.
+	stopCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-stopCtx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}()
 
 	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		panic(err)
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "tracing shutdown: %v\n", err)
+	}
 }
 
 func cleanupRateLimiters() {
@@ -195,9 +322,115 @@ func cleanupRateLimiters() {
 			active, total, runtime.NumGoroutine(), m.Alloc/(1<<20))
 
 		limiters = &sync.Map{}
+
+		if n := jobManager.Prune(context.Background()); n > 0 {
+			fmt.Printf("[jobs] pruned %d finished jobs\n", n)
+		}
+		if fc, ok := resultCache.(*extract.FileCache); ok {
+			if n := fc.Prune(context.Background()); n > 0 {
+				fmt.Printf("[cache] pruned %d expired results\n", n)
+			}
+		}
+		if fc, ok := ocrPageCache.(*hybrid.FilePageCache); ok {
+			if n := fc.Prune(context.Background()); n > 0 {
+				fmt.Printf("[cache] pruned %d expired OCR pages\n", n)
+			}
+		}
 	}
 }
 
+// configureUpstreams gives each third-party API its retry policy and token
+// bucket. Budgets are per minute; bursts allow one second's worth of requests.
+func configureUpstreams(c config.Config) {
+	for name, rpm := range map[string]int{
+		ocr.UpstreamName:        c.MistralRPM,
+		vision.UpstreamName:     c.OpenRouterRPM,
+		transcribe.UpstreamName: c.GroqRPM,
+	} {
+		upstream.Configure(name, upstream.Policy{
+			MaxRetries: c.UpstreamMaxRetries,
+			BaseDelay:  c.UpstreamBaseDelay,
+			MaxDelay:   c.UpstreamMaxDelay,
+			RatePerSec: float64(rpm) / 60,
+			Burst:      (rpm + 59) / 60,
+		})
+	}
+}
+
+// configurePrices converts the configured rates into per-unit prices for
+// usage estimates.
+func configurePrices(c config.Config) {
+	usage.SetPrices(usage.Prices{
+		usage.PriceKey(ocr.ProviderMistral, usage.UnitPages):            c.MistralPricePerPage,
+		usage.PriceKey(vision.UpstreamName, usage.UnitInputTokens):      c.OpenRouterPricePerMInputTokens / 1e6,
+		usage.PriceKey(vision.UpstreamName, usage.UnitOutputTokens):     c.OpenRouterPricePerMOutputTokens / 1e6,
+		usage.PriceKey(transcribe.UpstreamName, usage.UnitAudioSeconds): c.GroqPricePerAudioHour / 3600,
+	})
+}
+
+// configureBudgets enables per-tenant spend caps when either limit is set.
+func configureBudgets(c config.Config) error {
+	if c.BudgetDailyUSD <= 0 && c.BudgetMonthlyUSD <= 0 {
+		return nil
+	}
+	var store budget.Store = budget.NewMemoryStore()
+	if c.BudgetStore == "file" {
+		fs, err := budget.NewFileStore(c.BudgetStoreDir)
+		if err != nil {
+			return err
+		}
+		store = fs
+	}
+	budget.SetGuard(budget.NewGuard(store, budget.Limits{DailyUSD: c.BudgetDailyUSD, MonthlyUSD: c.BudgetMonthlyUSD}))
+	return nil
+}
+
+// newResultCache returns nil when caching is disabled so the router skips hashing lookups.
+func newResultCache(c config.Config) (extract.ResultCache, error) {
+	switch c.ResultCache {
+	case "memory":
+		return extract.NewMemoryCache(c.ResultCacheMaxEntries, c.ResultCacheTTL), nil
+	case "file":
+		return extract.NewFileCache(c.ResultCacheDir, c.ResultCacheTTL)
+	}
+	return nil, nil
+}
+
+func newPageCache(c config.Config) (hybrid.PageCache, error) {
+	switch c.OCRPageCache {
+	case "memory":
+		return hybrid.NewMemoryPageCache(c.OCRPageCacheMaxEntries, c.OCRPageCacheTTL), nil
+	case "file":
+		return hybrid.NewFilePageCache(c.OCRPageCacheDir, c.OCRPageCacheTTL)
+	}
+	return nil, nil
+}
+
+func newJobStore(c config.Config) (jobs.Store, error) {
+	if c.JobStore == "file" {
+		return jobs.NewFileStore(c.JobStoreDir)
+	}
+	return jobs.NewMemoryStore(), nil
+}
+
+// runJob executes one queued job. The presigned URL was validated at submit time.
+func runJob(ctx context.Context, req extract.UniversalExtractRequest, progress types.ProgressFunc) (extract.Result, error) {
+	start := time.Now()
+	ctx = budget.WithTenant(ctx, req.TenantID)
+	ctx, span := tracing.Start(tracing.Extract(ctx, req.TraceContext), "jobs.run")
+	res, err := extractRt.ExtractWithProgress(ctx, req, progress)
+	tracing.End(span, err)
+	if err != nil {
+		if res.Error != nil {
+			msg := sanitizeError(errors.New(*res.Error))
+			res.Error = &msg
+		}
+		return res, errors.New(sanitizeError(err))
+	}
+	fmt.Printf("[jobs] %s extracted in %s\n", sanitizeLogString(res.FileType), time.Since(start).Round(time.Millisecond))
+	return res, nil
+}
+
 // ---------- Handlers ----------
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -216,13 +449,36 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	writeJSON(w, code, map[string]any{
-		"status":  status,
-		"active":  active,
-		"version": "2.0.0",
+		"status":       status,
+		"active":       active,
+		"version":      "2.0.0",
+		"ocrProviders": hybridProc.OCRProviders().BreakerStates(),
+	})
+}
+
+// handleCapabilities lists what the live registry can extract, so clients
+// need not hard-code extensions, limits or the preview allow-list.
+func handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	infos := extractReg.Describe()
+	for i := range infos {
+		infos[i].Preview = isPreviewAllowed(infos[i].Name)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"extractors":     infos,
+		"maxFileSize":    cfg.MaxFileBytes,
+		"commonOptions":  []string{"cache", "chunking", "blocks", "tableFormat", "outputFormat"},
+		"previewOptions": []string{"previewMaxChars", "previewMaxPages", "minWordsThreshold"},
 	})
 }
 
+// handleMetrics serves the JSON summary, or Prometheus text when the scraper
+// asks for it via Accept or ?format=prometheus.
 func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if wantsPrometheus(r) {
+		promHandler.ServeHTTP(w, r)
+		return
+	}
+
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
 	total, active := metrics.get()
@@ -233,10 +489,26 @@ func handleMetrics(w http.ResponseWriter, r *http.Request) {
 		"goroutines":     runtime.NumGoroutine(),
 		"memAllocMB":     m.Alloc / (1 << 20),
 		"memSysMB":       m.Sys / (1 << 20),
+		"usage":          usage.Totals(),
 	})
 }
 
+var promHandler = appmetrics.Handler()
+
+func wantsPrometheus(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "prometheus" {
+		return true
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "text/plain") || strings.Contains(accept, "application/openmetrics-text")
+}
+
 func handleUniversalExtract(w http.ResponseWriter, r *http.Request) {
+	if wantsPageStream(r) {
+		handleExtractPages(w, r)
+		return
+	}
+
 	// Binary upload path — Worker streamed the R2 object directly.
 	// Detect via X-File-Name header (set only on the binary stream path);
 	// Content-Type alone is unreliable because the container proxy may strip it.
@@ -246,61 +518,32 @@ func handleUniversalExtract(w http.ResponseWriter, r *http.Request) {
 			fileName = "input.bin"
 		}
 
-		dl, err := extract.SaveBodyToTemp(r.Body, fileName, cfg.MaxFileBytes)
+		options, err := parseOptionsHeader(r)
 		if err != nil {
 			writeErr(w, http.StatusBadRequest, "bad_request", sanitizeError(err))
 			return
 		}
-		defer dl.Cleanup()
 
-		ext := strings.ToLower(filepath.Ext(fileName))
-		extractor, err := extractReg.Resolve(dl.MIMEType, ext)
+		dl, err := extract.SaveBodyToTemp(r.Body, fileName, cfg.MaxFileBytes)
 		if err != nil {
-			msg := sanitizeError(err)
-			writeJSON(w, http.StatusBadRequest, extract.Result{Success: false, MIMEType: dl.MIMEType, FileType: "unknown", Error: &msg})
-			return
-		}
-
-		if max := extractor.MaxFileSize(); max > 0 && dl.Size > max {
-			msg := fmt.Sprintf("file exceeds extractor limit (%dMB)", max/(1<<20))
-			writeJSON(w, http.StatusBadRequest, extract.Result{Success: false, MIMEType: dl.MIMEType, FileType: extractor.Name(), Error: &msg})
+			writeErr(w, http.StatusBadRequest, "bad_request", sanitizeError(err))
 			return
 		}
+		defer dl.Cleanup()
 
 		ctx, cancel := context.WithTimeout(r.Context(), cfg.UniversalExtractTimeout)
 		defer cancel()
 
-		job := extract.Job{
-			LocalPath: dl.Path,
-			FileName:  fileName,
-			MIMEType:  dl.MIMEType,
-			FileSize:  dl.Size,
-		}
-
-		res, err := extractor.Extract(ctx, job)
+		res, err := extractRt.ExtractFile(ctx, dl, fileName, options)
 		if err != nil {
-			if res.Error == nil {
-				msg := sanitizeError(err)
-				res.Error = &msg
-			}
-			res.Success = false
-			if res.MIMEType == "" {
-				res.MIMEType = dl.MIMEType
+			msg := sanitizeError(err)
+			if res.Error != nil {
+				msg = sanitizeError(errors.New(*res.Error))
 			}
+			res.Error = &msg
 			writeJSON(w, http.StatusBadRequest, res)
 			return
 		}
-
-		res.Success = true
-		if strings.TrimSpace(res.FileType) == "" {
-			res.FileType = extractor.Name()
-		}
-		if res.MIMEType == "" {
-			res.MIMEType = dl.MIMEType
-		}
-		if res.CharCount == 0 && res.Text != "" {
-			res.WordCount, res.CharCount = extract.BuildCounts(res.Text)
-		}
 		writeJSON(w, http.StatusOK, res)
 		return
 	}
@@ -333,6 +576,382 @@ func handleUniversalExtract(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, res)
 }
 
+// handleDetect accepts the same inputs as /extract and reports how the file
+// would be routed: its sniffed MIME type, its extension and the extractor
+// chosen from the two, without running it.
+func handleDetect(w http.ResponseWriter, r *http.Request) {
+	in, ok := readExtractInput(w, r)
+	if !ok {
+		return
+	}
+	defer in.cleanup()
+
+	ctx, cancel := context.WithTimeout(r.Context(), cfg.UniversalExtractTimeout)
+	defer cancel()
+
+	var (
+		d   extract.Detection
+		err error
+	)
+	if in.upload != nil {
+		d, err = extractRt.DetectFile(ctx, *in.upload, in.fileName)
+	} else {
+		d, err = extractRt.Detect(ctx, in.req)
+	}
+	if err != nil {
+		msg := sanitizeError(err)
+		if d.Error != nil {
+			msg = sanitizeError(errors.New(*d.Error))
+		}
+		d.Error = &msg
+		writeJSON(w, http.StatusBadRequest, d)
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// wantsPageStream reports whether an /extract client asked for pages as
+// NDJSON instead of one buffered result.
+func wantsPageStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+}
+
+// pageStreamSummary is the last line of a page stream: the usual result
+// fields without text or pages.
+type pageStreamSummary struct {
+	Done bool `json:"done"`
+	extract.Result
+}
+
+// handleExtractPages answers /extract as NDJSON: one line per page as soon as
+// its text is final, then a summary line with "done": true. Large PDFs are
+// never combined in memory.
+func handleExtractPages(w http.ResponseWriter, r *http.Request) {
+	in, ok := readExtractInput(w, r)
+	if !ok {
+		return
+	}
+	defer in.cleanup()
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(cfg.UniversalExtractTimeout + 10*time.Second))
+
+	ctx, cancel := context.WithTimeout(r.Context(), cfg.UniversalExtractTimeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	enc := json.NewEncoder(w)
+	emit := func(p extract.PageResult) {
+		_ = enc.Encode(p)
+		_ = rc.Flush()
+	}
+	var (
+		res extract.Result
+		err error
+	)
+	if in.upload != nil {
+		res, err = extractRt.StreamFilePages(ctx, *in.upload, in.fileName, in.options, emit)
+	} else {
+		res, err = extractRt.StreamPages(ctx, in.req, emit)
+	}
+	if err != nil {
+		msg := sanitizeError(err)
+		if res.Error != nil {
+			msg = sanitizeError(errors.New(*res.Error))
+		}
+		res.Error = &msg
+	}
+	_ = enc.Encode(pageStreamSummary{Done: true, Result: res})
+}
+
+// extractInput is the file an /extract-style request names: either an
+// uploaded body already saved to disk, or a presigned URL the router will
+// download.
+type extractInput struct {
+	upload   *extract.DownloadedFile
+	fileName string
+	options  map[string]any
+	req      extract.UniversalExtractRequest
+}
+
+func (in extractInput) cleanup() {
+	if in.upload != nil {
+		in.upload.Cleanup()
+	}
+}
+
+// readExtractInput parses and validates either request shape. It writes a
+// 400 and returns false when the request is unusable; otherwise the caller
+// must call cleanup.
+func readExtractInput(w http.ResponseWriter, r *http.Request) (extractInput, bool) {
+	if fileName := r.Header.Get("X-File-Name"); fileName != "" {
+		if strings.TrimSpace(fileName) == "" {
+			fileName = "input.bin"
+		}
+		options, err := parseOptionsHeader(r)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "bad_request", sanitizeError(err))
+			return extractInput{}, false
+		}
+		dl, err := extract.SaveBodyToTemp(r.Body, fileName, cfg.MaxFileBytes)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "bad_request", sanitizeError(err))
+			return extractInput{}, false
+		}
+		return extractInput{upload: &dl, fileName: fileName, options: options}, true
+	}
+
+	req, err := parseJSON[extract.UniversalExtractRequest](r, cfg.MaxJSONBodyBytes)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, "bad_request", sanitizeError(err))
+		return extractInput{}, false
+	}
+	if strings.TrimSpace(req.PresignedURL) == "" {
+		writeErr(w, http.StatusBadRequest, "validation_failed", "presignedUrl required")
+		return extractInput{}, false
+	}
+	if err := validatePresignedURL(req.PresignedURL, cfg.AllowedPresignedHostSuffixes, cfg.AllowPrivateDownloadURLs); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_failed", sanitizeError(err))
+		return extractInput{}, false
+	}
+	return extractInput{req: req}, true
+}
+
+// handleExtractStream accepts the same inputs as /extract and answers with
+// server-sent events: "progress" events while the file is processed, then a
+// single "result" event carrying the usual Result. Input errors found before
+// the stream starts get a plain JSON 400.
+func handleExtractStream(w http.ResponseWriter, r *http.Request) {
+	in, ok := readExtractInput(w, r)
+	if !ok {
+		return
+	}
+	defer in.cleanup()
+
+	// Like batches, a stream may outlive the server-wide WriteTimeout.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(cfg.UniversalExtractTimeout + 10*time.Second))
+
+	ctx, cancel := context.WithTimeout(r.Context(), cfg.UniversalExtractTimeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	sse := &sseWriter{w: w, rc: rc}
+	stopPings := sse.keepAlive(15 * time.Second)
+	progress := func(ev types.ProgressEvent) { sse.send("progress", ev) }
+	var (
+		res extract.Result
+		err error
+	)
+	if in.upload != nil {
+		res, err = extractRt.ExtractFileWithProgress(ctx, *in.upload, in.fileName, in.options, progress)
+	} else {
+		res, err = extractRt.ExtractWithProgress(ctx, in.req, progress)
+	}
+	stopPings()
+	if err != nil {
+		msg := sanitizeError(err)
+		if res.Error != nil {
+			msg = sanitizeError(errors.New(*res.Error))
+		}
+		res.Error = &msg
+	}
+	sse.send("result", res)
+}
+
+// sseWriter serializes server-sent events; progress arrives from several
+// page workers at once.
+type sseWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (s *sseWriter) send(event string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data)
+	_ = s.rc.Flush()
+}
+
+// keepAlive writes an SSE comment every interval so idle proxies keep the
+// connection open during long OCR or transcription calls. The returned
+// function stops it and waits until no further write can happen.
+func (s *sseWriter) keepAlive(interval time.Duration) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				s.mu.Lock()
+				_, _ = io.WriteString(s.w, ": ping\n\n")
+				_ = s.rc.Flush()
+				s.mu.Unlock()
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+type batchExtractRequest struct {
+	Items       []extract.UniversalExtractRequest `json:"items"`
+	Parallelism int                               `json:"parallelism,omitempty"`
+}
+
+type batchSummary struct {
+	Done      bool `json:"done"`
+	Total     int  `json:"total"`
+	Succeeded int  `json:"succeeded"`
+	Failed    int  `json:"failed"`
+}
+
+func handleBatchExtract(w http.ResponseWriter, r *http.Request) {
+	req, err := parseJSON[batchExtractRequest](r, cfg.MaxJSONBodyBytes)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, "bad_request", sanitizeError(err))
+		return
+	}
+	if len(req.Items) == 0 {
+		writeErr(w, http.StatusBadRequest, "validation_failed", "items required")
+		return
+	}
+	if len(req.Items) > cfg.BatchMaxItems {
+		writeErr(w, http.StatusBadRequest, "validation_failed", fmt.Sprintf("batch exceeds %d items", cfg.BatchMaxItems))
+		return
+	}
+
+	parallelism := cfg.BatchParallelism
+	if req.Parallelism > 0 && req.Parallelism < parallelism {
+		parallelism = req.Parallelism
+	}
+
+	// The server-wide WriteTimeout is sized for single files; a batch streams
+	// for as long as its own budget allows.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(cfg.BatchTimeout + 10*time.Second))
+
+	ctx, cancel := context.WithTimeout(r.Context(), cfg.BatchTimeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	enc := json.NewEncoder(w)
+	summary := batchSummary{Done: true, Total: len(req.Items)}
+
+	metrics.incActive()
+	defer metrics.decActive()
+
+	extractRt.ExtractBatch(ctx, req.Items, extract.BatchOptions{
+		Parallelism: parallelism,
+		Sem:         requestSem,
+		Validate: func(item extract.UniversalExtractRequest) error {
+			return validatePresignedURL(item.PresignedURL, cfg.AllowedPresignedHostSuffixes, cfg.AllowPrivateDownloadURLs)
+		},
+	}, func(item extract.BatchItem) {
+		if item.Success {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+			if item.Error != nil {
+				msg := sanitizeError(errors.New(*item.Error))
+				item.Error = &msg
+			}
+		}
+		_ = enc.Encode(item)
+		_ = rc.Flush()
+	})
+
+	_ = enc.Encode(summary)
+}
+
+type jobSubmitRequest struct {
+	extract.UniversalExtractRequest
+	WebhookURL string `json:"webhookUrl,omitempty"`
+}
+
+func handleJobSubmit(w http.ResponseWriter, r *http.Request) {
+	req, err := parseJSON[jobSubmitRequest](r, cfg.MaxJSONBodyBytes)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, "bad_request", sanitizeError(err))
+		return
+	}
+
+	if strings.TrimSpace(req.PresignedURL) == "" {
+		writeErr(w, http.StatusBadRequest, "validation_failed", "presignedUrl required")
+		return
+	}
+	if err := validatePresignedURL(req.PresignedURL, cfg.AllowedPresignedHostSuffixes, cfg.AllowPrivateDownloadURLs); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_failed", sanitizeError(err))
+		return
+	}
+
+	webhookURL := strings.TrimSpace(req.WebhookURL)
+	if webhookURL != "" {
+		if !jobManager.WebhooksEnabled() {
+			writeErr(w, http.StatusBadRequest, "validation_failed", "webhooks are not configured on this server")
+			return
+		}
+		if err := validateWebhookURL(webhookURL, cfg.JobWebhookAllowedHostSuffixes, cfg.AllowPrivateDownloadURLs); err != nil {
+			writeErr(w, http.StatusBadRequest, "validation_failed", sanitizeError(err))
+			return
+		}
+	}
+
+	req.TenantID = budget.Tenant(r.Context())
+	req.TraceContext = tracing.Inject(r.Context())
+	rec, err := jobManager.Submit(r.Context(), req.UniversalExtractRequest, webhookURL)
+	if errors.Is(err, jobs.ErrQueueFull) {
+		w.Header().Set("Retry-After", "30")
+		writeErr(w, http.StatusServiceUnavailable, "capacity", "Job queue is full")
+		return
+	}
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "internal_error", sanitizeError(err))
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+rec.ID)
+	writeJSON(w, http.StatusAccepted, rec)
+}
+
+func handleJobStatus(w http.ResponseWriter, r *http.Request) {
+	rec, err := jobManager.Get(r.Context(), r.PathValue("id"))
+	if errors.Is(err, jobs.ErrNotFound) {
+		writeErr(w, http.StatusNotFound, "not_found", "Job not found")
+		return
+	}
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "internal_error", sanitizeError(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
+
 func handlePreview(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 
@@ -351,6 +970,12 @@ func handlePreview(w http.ResponseWriter, r *http.Request) {
 		}
 
 		var err error
+		options, err = parseOptionsHeader(r)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "bad_request", sanitizeError(err))
+			return
+		}
+
 		dl, err = extract.SaveBodyToTemp(r.Body, fileName, cfg.MaxFileBytes)
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]any{"success": false, "error": sanitizeError(err)})
@@ -391,7 +1016,7 @@ func handlePreview(w http.ResponseWriter, r *http.Request) {
 	defer dl.Cleanup()
 
 	ext := strings.ToLower(filepath.Ext(fileName))
-	extractor, err := extractReg.Resolve(dl.MIMEType, ext)
+	extractor, err := extractReg.ResolveContext(r.Context(), dl.MIMEType, ext)
 	if err != nil {
 		msg := sanitizeError(err)
 		writeJSON(w, http.StatusBadRequest, extract.Result{Success: false, MIMEType: dl.MIMEType, FileType: "unknown", Error: &msg})
@@ -453,7 +1078,7 @@ func handlePreview(w http.ResponseWriter, r *http.Request) {
 		Options:   options,
 	}
 
-	res, err := extractor.Extract(ctx, job)
+	res, err := extract.RunExtractor(ctx, extractor, job)
 	if err != nil {
 		if res.Error == nil {
 			msg := sanitizeError(err)
@@ -507,6 +1132,22 @@ func withInternalAuth(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// withTenant attaches the X-Tenant-ID header to the request context so paid
+// upstream usage is charged to that tenant's budget.
+func withTenant(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant := strings.TrimSpace(r.Header.Get("X-Tenant-ID"))
+		if tenant == "" {
+			tenant = budget.DefaultTenant
+		}
+		if !budget.ValidTenant(tenant) {
+			writeErr(w, http.StatusBadRequest, "bad_request", "Invalid X-Tenant-ID header")
+			return
+		}
+		next(w, r.WithContext(budget.WithTenant(r.Context(), tenant)))
+	}
+}
+
 func withConcurrencyLimit(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if err := requestSem.Acquire(r.Context(), 1); err != nil {
@@ -553,6 +1194,8 @@ func withLogging(next http.Handler) http.Handler {
 		start := time.Now()
 		ww := &wrapWriter{ResponseWriter: w, status: 200}
 		next.ServeHTTP(ww, r)
+		// ServeMux records the matched route on r, which keeps the label set bounded.
+		appmetrics.ObserveHTTP(r.Pattern, ww.status, time.Since(start))
 
 		fmt.Printf("%s %s -> %d (%s)\n",
 			r.Method, sanitizeLogString(r.URL.Path), ww.status, time.Since(start))
@@ -569,6 +1212,12 @@ func (w *wrapWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer for Flush
+// and per-request deadlines.
+func (w *wrapWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // ---------- Helpers ----------
 
 func getRateLimiter(ip string) *rate.Limiter {
@@ -667,6 +1316,50 @@ func validatePresignedURL(raw string, allowedHostSuffixes []string, allowPrivate
 	return nil
 }
 
+// validateWebhookURL requires an https callback on a public host. When
+// allowedHostSuffixes is non-empty the host must also match one of them.
+func validateWebhookURL(raw string, allowedHostSuffixes []string, allowPrivate bool) error {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed == nil {
+		return fmt.Errorf("invalid webhookUrl")
+	}
+	host := strings.ToLower(strings.TrimSpace(parsed.Hostname()))
+	if host == "" {
+		return fmt.Errorf("webhookUrl host is required")
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("webhookUrl must not contain credentials")
+	}
+
+	isLocalName := host == "localhost" || strings.HasSuffix(host, ".localhost")
+	isPrivateIP := false
+	if ip := net.ParseIP(host); ip != nil {
+		isPrivateIP = isPrivateOrLocalIP(ip)
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "https":
+	case "http":
+		if !(allowPrivate && (isLocalName || isPrivateIP)) {
+			return fmt.Errorf("webhookUrl must use https")
+		}
+	default:
+		return fmt.Errorf("webhookUrl must use https")
+	}
+
+	if isLocalName || isPrivateIP {
+		if allowPrivate {
+			return nil
+		}
+		return fmt.Errorf("webhookUrl host is not allowed")
+	}
+
+	if len(allowedHostSuffixes) > 0 && !isAllowedPresignedHost(host, allowedHostSuffixes) {
+		return fmt.Errorf("webhookUrl host is not in allowlist")
+	}
+	return nil
+}
+
 func isAllowedPresignedHost(host string, suffixes []string) bool {
 	for _, suffix := range suffixes {
 		s := strings.ToLower(strings.TrimSpace(suffix))
@@ -748,6 +1441,23 @@ func intOption(options map[string]any, key string, fallback int) int {
 	return fallback
 }
 
+// parseOptionsHeader decodes the JSON options object the Worker forwards in
+// X-Extract-Options on the binary stream path (the body carries file bytes).
+func parseOptionsHeader(r *http.Request) (map[string]any, error) {
+	raw := strings.TrimSpace(r.Header.Get("X-Extract-Options"))
+	if raw == "" {
+		return nil, nil
+	}
+	if len(raw) > maxOptionsHeaderBytes {
+		return nil, fmt.Errorf("X-Extract-Options exceeds %d bytes", maxOptionsHeaderBytes)
+	}
+	var options map[string]any
+	if err := json.Unmarshal([]byte(raw), &options); err != nil {
+		return nil, fmt.Errorf("X-Extract-Options must be a JSON object")
+	}
+	return options, nil
+}
+
 func parseJSON[T any](r *http.Request, limit int64) (T, error) {
 	var out T
 	dec := json.NewDecoder(io.LimitReader(r.Body, limit))
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleMetricsNegotiatesFormat(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleMetrics(rec, httptest.NewRequest("GET", "/metrics", nil))
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("default response should stay JSON: %v", err)
+	}
+	if _, ok := body["totalRequests"]; !ok {
+		t.Fatalf("missing totalRequests in %v", body)
+	}
+
+	scrape := httptest.NewRequest("GET", "/metrics", nil)
+	scrape.Header.Set("Accept", "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5")
+	rec = httptest.NewRecorder()
+	handleMetrics(rec, scrape)
+	if !strings.Contains(rec.Body.String(), "# TYPE go_goroutines gauge") {
+		t.Fatalf("expected Prometheus exposition, got %q", rec.Body.String()[:min(200, rec.Body.Len())])
+	}
+
+	rec = httptest.NewRecorder()
+	handleMetrics(rec, httptest.NewRequest("GET", "/metrics?format=prometheus", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("?format=prometheus should serve text, got %q", rec.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/config"
+	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+// pagedExtractor reports a two-page document the way the PDF extractor does.
+type pagedExtractor struct{}
+
+func (pagedExtractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
+	job.Progress.Report(types.ProgressEvent{Stage: types.ProgressPageCount, TotalPages: 2, Pages: 2})
+	for i := 1; i <= 2; i++ {
+		job.Progress.Report(types.ProgressEvent{Stage: types.ProgressPage, Page: i, Method: "text-layer"})
+	}
+	return extract.Result{Success: true, Text: "two pages", Method: "paged"}, nil
+}
+func (pagedExtractor) SupportedTypes() []string      { return nil }
+func (pagedExtractor) SupportedExtensions() []string { return []string{".paged"} }
+func (pagedExtractor) Name() string                  { return "test/paged" }
+func (pagedExtractor) MaxFileSize() int64            { return 0 }
+
+func TestHandleExtractStreamSendsProgressThenResult(t *testing.T) {
+	prevCfg, prevRt := cfg, extractRt
+	defer func() { cfg, extractRt = prevCfg, prevRt }()
+	cfg = config.Config{MaxFileBytes: 1 << 20, UniversalExtractTimeout: 5 * time.Second}
+	reg := extract.NewRegistry()
+	reg.Register(pagedExtractor{})
+	extractRt = extract.NewRouter(reg, cfg.MaxFileBytes, time.Second)
+
+	req := httptest.NewRequest("POST", "/extract/stream", strings.NewReader("page one\npage two\n"))
+	req.Header.Set("X-File-Name", "doc.paged")
+	rec := httptest.NewRecorder()
+	handleExtractStream(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	var stages []string
+	var result extract.Result
+	for _, block := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n") {
+		event, data, _ := strings.Cut(block, "\n")
+		data = strings.TrimPrefix(data, "data: ")
+		switch event {
+		case "event: progress":
+			var ev types.ProgressEvent
+			if err := json.Unmarshal([]byte(data), &ev); err != nil {
+				t.Fatalf("bad progress event %q: %v", data, err)
+			}
+			stages = append(stages, ev.Stage)
+		case "event: result":
+			if err := json.Unmarshal([]byte(data), &result); err != nil {
+				t.Fatalf("bad result event %q: %v", data, err)
+			}
+		default:
+			t.Fatalf("unexpected block %q", block)
+		}
+	}
+
+	want := []string{types.ProgressDownloaded, types.ProgressPageCount, types.ProgressPage, types.ProgressPage}
+	if strings.Join(stages, ",") != strings.Join(want, ",") {
+		t.Fatalf("stages = %v, want %v", stages, want)
+	}
+	if !result.Success || result.Text != "two pages" || result.FileType != "test/paged" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestHandleExtractStreamRejectsBadInputBeforeStreaming(t *testing.T) {
+	prevCfg := cfg
+	defer func() { cfg = prevCfg }()
+	cfg = config.Config{MaxJSONBodyBytes: 1 << 10}
+
+	rec := httptest.NewRecorder()
+	handleExtractStream(rec, httptest.NewRequest("POST", "/extract/stream", strings.NewReader(`{"fileName":"a.pdf"}`)))
+	if rec.Code != 400 || strings.HasPrefix(rec.Header().Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("expected a JSON 400, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+// pageStreamingExtractor hands out pages itself, as the PDF extractor does.
+type pageStreamingExtractor struct{ pagedExtractor }
+
+func (pageStreamingExtractor) ExtractPages(ctx context.Context, job extract.Job, emit func(extract.PageResult)) (extract.Result, error) {
+	emit(extract.PageResult{PageNumber: 2, Text: "page two", Method: "text-layer", WordCount: 2})
+	emit(extract.PageResult{PageNumber: 1, Text: "page one", Method: "ocr:test", WordCount: 2})
+	return extract.Result{Method: "hybrid", WordCount: 4, Metadata: map[string]string{"totalPages": "2"}}, nil
+}
+func (pageStreamingExtractor) SupportedExtensions() []string { return []string{".pages"} }
+func (pageStreamingExtractor) Name() string                  { return "test/pages" }
+
+func TestHandleUniversalExtractStreamsPagesAsNDJSON(t *testing.T) {
+	prevCfg, prevRt := cfg, extractRt
+	defer func() { cfg, extractRt = prevCfg, prevRt }()
+	cfg = config.Config{MaxFileBytes: 1 << 20, UniversalExtractTimeout: 5 * time.Second}
+	reg := extract.NewRegistry()
+	reg.Register(pageStreamingExtractor{})
+	extractRt = extract.NewRouter(reg, cfg.MaxFileBytes, time.Second)
+
+	req := httptest.NewRequest("POST", "/extract", strings.NewReader("page one\npage two\n"))
+	req.Header.Set("X-File-Name", "doc.pages")
+	req.Header.Set("Accept", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	handleUniversalExtract(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("content type = %q", ct)
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 2 pages and a summary:\n%s", len(lines), rec.Body.String())
+	}
+	var pages []int
+	for _, line := range lines[:2] {
+		var p extract.PageResult
+		if err := json.Unmarshal([]byte(line), &p); err != nil {
+			t.Fatalf("bad page line %q: %v", line, err)
+		}
+		pages = append(pages, p.PageNumber)
+	}
+	if pages[0] != 2 || pages[1] != 1 {
+		t.Fatalf("pages = %v, want them in emit order", pages)
+	}
+
+	var sum pageStreamSummary
+	if err := json.Unmarshal([]byte(lines[2]), &sum); err != nil {
+		t.Fatalf("bad summary line %q: %v", lines[2], err)
+	}
+	if !sum.Done || !sum.Success || sum.FileType != "test/pages" || sum.WordCount != 4 || sum.Text != "" {
+		t.Fatalf("unexpected summary %+v", sum)
+	}
+}
+
+func TestHandleUniversalExtractPageStreamWrapsBufferedExtractors(t *testing.T) {
+	prevCfg, prevRt := cfg, extractRt
+	defer func() { cfg, extractRt = prevCfg, prevRt }()
+	cfg = config.Config{MaxFileBytes: 1 << 20, UniversalExtractTimeout: 5 * time.Second}
+	reg := extract.NewRegistry()
+	reg.Register(pagedExtractor{})
+	extractRt = extract.NewRouter(reg, cfg.MaxFileBytes, time.Second)
+
+	req := httptest.NewRequest("POST", "/extract", strings.NewReader("anything"))
+	req.Header.Set("X-File-Name", "doc.paged")
+	req.Header.Set("Accept", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	handleUniversalExtract(rec, req)
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 1 page and a summary:\n%s", len(lines), rec.Body.String())
+	}
+	var p extract.PageResult
+	if err := json.Unmarshal([]byte(lines[0]), &p); err != nil || p.PageNumber != 1 || p.Text != "two pages" {
+		t.Fatalf("unexpected page line %q (%v)", lines[0], err)
+	}
+}
@@ -0,0 +1,146 @@
+// Package budget caps what each tenant may spend on paid upstream APIs (OCR,
+// vision, transcription) per UTC day and month. Once a cap is reached,
+// extractors skip paid calls and fall back to free paths instead.
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/usage"
+)
+
+// DefaultTenant is charged for requests that carry no tenant ID.
+const DefaultTenant = "default"
+
+// ErrExceeded is returned by paid paths that were skipped for budget reasons.
+var ErrExceeded = errors.New("tenant budget exceeded")
+
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// ValidTenant reports whether id is usable as a tenant ID.
+func ValidTenant(id string) bool { return tenantPattern.MatchString(id) }
+
+// Limits are spend caps in USD; zero means unlimited.
+type Limits struct {
+	DailyUSD   float64
+	MonthlyUSD float64
+}
+
+// Status is a tenant's spend in the current periods.
+type Status struct {
+	Tenant          string  `json:"tenant"`
+	DailySpentUSD   float64 `json:"dailySpentUsd"`
+	MonthlySpentUSD float64 `json:"monthlySpentUsd"`
+	DailyLimitUSD   float64 `json:"dailyLimitUsd,omitempty"`
+	MonthlyLimitUSD float64 `json:"monthlyLimitUsd,omitempty"`
+	Exceeded        bool    `json:"exceeded"`
+}
+
+// Guard checks and records tenant spend against Limits.
+type Guard struct {
+	store  Store
+	limits Limits
+	now    func() time.Time
+}
+
+func NewGuard(store Store, limits Limits) *Guard {
+	return &Guard{store: store, limits: limits, now: time.Now}
+}
+
+func (g *Guard) periods() (day, month string) {
+	t := g.now().UTC()
+	return t.Format("2006-01-02"), t.Format("2006-01")
+}
+
+// Status reports the tenant's spend plus pending, the cost already incurred
+// by the request in flight but not yet charged.
+func (g *Guard) Status(ctx context.Context, tenant string, pending float64) (Status, error) {
+	day, month := g.periods()
+	daily, err := g.store.Spent(ctx, tenant, day)
+	if err != nil {
+		return Status{}, err
+	}
+	monthly, err := g.store.Spent(ctx, tenant, month)
+	if err != nil {
+		return Status{}, err
+	}
+	st := Status{
+		Tenant:          tenant,
+		DailySpentUSD:   daily + pending,
+		MonthlySpentUSD: monthly + pending,
+		DailyLimitUSD:   g.limits.DailyUSD,
+		MonthlyLimitUSD: g.limits.MonthlyUSD,
+	}
+	st.Exceeded = (g.limits.DailyUSD > 0 && st.DailySpentUSD >= g.limits.DailyUSD) ||
+		(g.limits.MonthlyUSD > 0 && st.MonthlySpentUSD >= g.limits.MonthlyUSD)
+	return st, nil
+}
+
+// Charge adds usd to the tenant's day and month.
+func (g *Guard) Charge(ctx context.Context, tenant string, usd float64) error {
+	if usd <= 0 {
+		return nil
+	}
+	day, month := g.periods()
+	if err := g.store.Add(ctx, tenant, day, usd); err != nil {
+		return err
+	}
+	return g.store.Add(ctx, tenant, month, usd)
+}
+
+// ---------- Request context ----------
+
+var active atomic.Pointer[Guard]
+
+// SetGuard enables budget enforcement; nil disables it.
+func SetGuard(g *Guard) {
+	active.Store(g)
+}
+
+type tenantKey struct{}
+
+// WithTenant attaches the tenant that pays for the request to ctx.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// Tenant returns the tenant attached to ctx, or DefaultTenant.
+func Tenant(ctx context.Context) string {
+	if t, ok := ctx.Value(tenantKey{}).(string); ok && t != "" {
+		return t
+	}
+	return DefaultTenant
+}
+
+// Allowed reports whether the request's tenant may still call paid APIs.
+// Spend already recorded by this request counts. Store errors allow the
+// call: budgets are a cost guard, not an access control.
+func Allowed(ctx context.Context) bool {
+	g := active.Load()
+	if g == nil {
+		return true
+	}
+	st, err := g.Status(ctx, Tenant(ctx), usage.Pending(ctx))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[budget] status check failed for tenant %s: %v\n", Tenant(ctx), err)
+		return true
+	}
+	return !st.Exceeded
+}
+
+// Charge records usd against the request's tenant.
+func Charge(ctx context.Context, usd float64) {
+	g := active.Load()
+	if g == nil {
+		return
+	}
+	if err := g.Charge(ctx, Tenant(ctx), usd); err != nil {
+		fmt.Fprintf(os.Stderr, "[budget] charge failed for tenant %s: %v\n", Tenant(ctx), err)
+	}
+}
@@ -0,0 +1,106 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/usage"
+)
+
+func TestGuardEnforcesDailyAndMonthlyLimits(t *testing.T) {
+	ctx := context.Background()
+	g := NewGuard(NewMemoryStore(), Limits{DailyUSD: 1, MonthlyUSD: 1.5})
+	day := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return day }
+
+	if err := g.Charge(ctx, "acme", 0.6); err != nil {
+		t.Fatalf("charge: %v", err)
+	}
+	st, _ := g.Status(ctx, "acme", 0)
+	if st.Exceeded || st.DailySpentUSD != 0.6 {
+		t.Fatalf("unexpected status after first charge: %+v", st)
+	}
+	if st, _ := g.Status(ctx, "acme", 0.4); !st.Exceeded {
+		t.Fatalf("pending spend should count toward the daily cap: %+v", st)
+	}
+	if st, _ := g.Status(ctx, "other", 0); st.Exceeded || st.MonthlySpentUSD != 0 {
+		t.Fatalf("tenants must not share spend: %+v", st)
+	}
+
+	// Next day: the daily cap resets but the month keeps accumulating.
+	g.now = func() time.Time { return day.Add(24 * time.Hour) }
+	_ = g.Charge(ctx, "acme", 0.9)
+	st, _ = g.Status(ctx, "acme", 0)
+	if !st.Exceeded || st.DailySpentUSD != 0.9 || st.MonthlySpentUSD != 1.5 {
+		t.Fatalf("expected monthly cap reached: %+v", st)
+	}
+
+	// Next month: both reset.
+	g.now = func() time.Time { return day.AddDate(0, 1, 0) }
+	if st, _ := g.Status(ctx, "acme", 0); st.Exceeded || st.MonthlySpentUSD != 0 {
+		t.Fatalf("expected a fresh month: %+v", st)
+	}
+}
+
+func TestFileStorePersistsAndPrunesOldMonths(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	_ = s.Add(ctx, "acme", "2026-03-31", 2)
+	_ = s.Add(ctx, "acme", "2026-03", 2)
+
+	reopened, _ := NewFileStore(dir)
+	if got, _ := reopened.Spent(ctx, "acme", "2026-03"); got != 2 {
+		t.Fatalf("expected spend to survive reopen, got %v", got)
+	}
+
+	_ = reopened.Add(ctx, "acme", "2026-04-01", 1)
+	if got, _ := reopened.Spent(ctx, "acme", "2026-03-31"); got != 0 {
+		t.Fatalf("earlier months should be pruned, got %v", got)
+	}
+	if got, _ := reopened.Spent(ctx, "acme", "2026-04-01"); got != 1 {
+		t.Fatalf("expected 1, got %v", got)
+	}
+}
+
+func TestAllowedUsesRequestTenantAndPendingUsage(t *testing.T) {
+	usage.SetPrices(usage.Prices{usage.PriceKey("paid", usage.UnitPages): 0.5})
+	defer usage.SetPrices(nil)
+	g := NewGuard(NewMemoryStore(), Limits{DailyUSD: 1})
+	SetGuard(g)
+	defer SetGuard(nil)
+
+	ctx := WithTenant(context.Background(), "acme")
+	if Tenant(context.Background()) != DefaultTenant || Tenant(ctx) != "acme" {
+		t.Fatalf("unexpected tenant resolution")
+	}
+	meter := usage.NewMeter()
+	ctx = usage.WithMeter(ctx, meter)
+	if !Allowed(ctx) {
+		t.Fatalf("fresh tenant should be allowed")
+	}
+	usage.Record(ctx, "paid", usage.UnitPages, 2)
+	if Allowed(ctx) {
+		t.Fatalf("spend recorded by the request in flight should count")
+	}
+
+	Charge(ctx, meter.Summary().EstimatedCostUSD)
+	if Allowed(WithTenant(context.Background(), "acme")) {
+		t.Fatalf("charged spend should persist across requests")
+	}
+	if !Allowed(context.Background()) {
+		t.Fatalf("other tenants are unaffected")
+	}
+}
+
+func TestValidTenant(t *testing.T) {
+	for id, want := range map[string]bool{"acme": true, "org_1.team-2:x": true, "": false, "a b": false, "../x": false} {
+		if got := ValidTenant(id); got != want {
+			t.Fatalf("ValidTenant(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package budget
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Store persists spend per tenant and period. Periods are UTC dates
+// ("2006-01-02") or months ("2006-01"); a write drops the tenant's periods
+// from earlier months. Implementations must be safe for concurrent use.
+type Store interface {
+	Spent(ctx context.Context, tenant, period string) (float64, error)
+	Add(ctx context.Context, tenant, period string, usd float64) error
+}
+
+// pruneBefore removes periods from months other than period's.
+func pruneBefore(spend map[string]float64, period string) {
+	month := period
+	if len(month) > 7 {
+		month = month[:7]
+	}
+	for k := range spend {
+		if !strings.HasPrefix(k, month) {
+			delete(spend, k)
+		}
+	}
+}
+
+// ---------- In-memory ----------
+
+// MemoryStore keeps spend in process memory. It resets on restart.
+type MemoryStore struct {
+	mu    sync.Mutex
+	spend map[string]map[string]float64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{spend: make(map[string]map[string]float64)}
+}
+
+func (s *MemoryStore) Spent(_ context.Context, tenant, period string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.spend[tenant][period], nil
+}
+
+func (s *MemoryStore) Add(_ context.Context, tenant, period string, usd float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.spend[tenant]
+	if !ok {
+		t = make(map[string]float64)
+		s.spend[tenant] = t
+	}
+	pruneBefore(t, period)
+	t[period] += usd
+	return nil
+}
+
+// ---------- File-backed ----------
+
+// FileStore writes one JSON document per tenant so spend survives restarts.
+// File names are hashes of the tenant ID; writes go through a temp file +
+// rename to stay atomic.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, fmt.Errorf("budget store dir required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("budget store dir: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(tenant string) string {
+	sum := sha256.Sum256([]byte(tenant))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+func (s *FileStore) Spent(_ context.Context, tenant, period string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	spend, err := s.read(tenant)
+	if err != nil {
+		return 0, err
+	}
+	return spend[period], nil
+}
+
+func (s *FileStore) Add(_ context.Context, tenant, period string, usd float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	spend, err := s.read(tenant)
+	if err != nil {
+		return err
+	}
+	pruneBefore(spend, period)
+	spend[period] += usd
+
+	b, err := json.Marshal(spend)
+	if err != nil {
+		return fmt.Errorf("marshal spend: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, "spend-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("close: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(tenant)); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("rename: %w", err)
+	}
+	return nil
+}
+
+// read loads a tenant's spend; callers hold s.mu.
+func (s *FileStore) read(tenant string) (map[string]float64, error) {
+	spend := make(map[string]float64)
+	b, err := os.ReadFile(s.path(tenant))
+	if errors.Is(err, os.ErrNotExist) {
+		return spend, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read spend: %w", err)
+	}
+	if err := json.Unmarshal(b, &spend); err != nil {
+		return nil, fmt.Errorf("decode spend: %w", err)
+	}
+	return spend, nil
+}
@@ -27,10 +27,29 @@ type Config struct {
 	MaxCodeFileBytes int64
 	MaxImageBytes    int64
 
+	// Local PDFs up to this size are sent to OCR inline as a data URI;
+	// larger ones are uploaded through the Mistral files API.
+	OCRInlineMaxBytes int64
+
+	// OCR backend: "mistral" (hosted) or "tesseract" (local pdftoppm + tesseract)
+	OCRProvider       string
+	TesseractBinary   string
+	PDFToPPMBinary    string
+	TesseractLanguage string
+	TesseractDPI      int
+	TesseractTimeout  time.Duration // per page, for rasterizing and for recognition
+
+	// OCR failover: providers tried in order after the requested one fails,
+	// and a per-provider circuit breaker.
+	OCRFallbackProviders []string
+	OCRBreakerThreshold  int // consecutive failures before the circuit opens
+	OCRBreakerCooldown   time.Duration
+
 	// Concurrency
 	MaxConcurrentRequests int64
 	MaxOCRConcurrent      int64
 	MaxPageWorkers        int // per-document page extraction workers cap
+	PageStreamWindow      int // pages read and OCR'd together when streaming pages
 
 	// Server timeouts
 	ReadHeaderTimeout time.Duration
@@ -80,12 +99,72 @@ type Config struct {
 	GroqAPIURL string
 	GroqModel  string
 
+	// Upstream APIs (Mistral, OpenRouter, Groq): retries and per-provider
+	// request budgets in requests per minute
+	UpstreamMaxRetries int
+	UpstreamBaseDelay  time.Duration
+	UpstreamMaxDelay   time.Duration
+	MistralRPM         int
+	OpenRouterRPM      int
+	GroqRPM            int
+
+	// Usage cost estimates (USD). Local Tesseract OCR is free.
+	MistralPricePerPage             float64
+	OpenRouterPricePerMInputTokens  float64 // per million prompt tokens
+	OpenRouterPricePerMOutputTokens float64 // per million completion tokens
+	GroqPricePerAudioHour           float64
+
 	// Conversion binaries
 	LibreOfficeTimeout time.Duration
 	LibreOfficeBinary  string
 	FFmpegTimeout      time.Duration
 	FFmpegBinary       string
 
+	// Archives (zip/tar/gzip)
+	ArchiveMaxEntries    int
+	ArchiveMaxTotalBytes int64
+	ArchiveMaxDepth      int
+	ArchiveMaxRatio      float64
+
+	// Batch extraction
+	BatchMaxItems    int
+	BatchParallelism int // upper bound; requests may ask for less
+	BatchTimeout     time.Duration
+
+	// Result cache
+	ResultCache           string // "memory", "file" or "off"
+	ResultCacheDir        string
+	ResultCacheMaxEntries int
+	ResultCacheTTL        time.Duration
+
+	// Per-page OCR cache
+	OCRPageCache           string // "memory", "file" or "off"
+	OCRPageCacheDir        string
+	OCRPageCacheMaxEntries int
+	OCRPageCacheTTL        time.Duration
+
+	// Async jobs
+	JobWorkers                    int
+	JobQueueSize                  int
+	JobTimeout                    time.Duration
+	JobStore                      string // "memory" or "file"
+	JobStoreDir                   string
+	JobRetention                  time.Duration
+	JobWebhookSecret              string
+	JobWebhookTimeout             time.Duration
+	JobWebhookAllowedHostSuffixes []string // empty = any public https host
+
+	// Per-tenant spend caps on paid upstreams (USD, UTC periods; 0 = unlimited)
+	BudgetDailyUSD   float64
+	BudgetMonthlyUSD float64
+	BudgetStore      string // "memory" or "file"
+	BudgetStoreDir   string
+
+	// OpenTelemetry tracing (empty endpoint = spans are not exported)
+	OTelEndpoint    string // OTLP/HTTP collector base URL
+	OTelServiceName string
+	OTelSampleRatio float64 // share of new traces sampled, (0, 1]
+
 	// Security
 	AllowedPresignedHostSuffixes []string
 	AllowPrivateDownloadURLs     bool
@@ -108,9 +187,23 @@ func Load() Config {
 		MaxCodeFileBytes: int64(envInt("MAX_CODE_FILE_BYTES", int(10<<20))),
 		MaxImageBytes:    int64(envInt("MAX_IMAGE_BYTES", int(40<<20))),
 
+		OCRInlineMaxBytes: int64(envInt("OCR_INLINE_MAX_BYTES", int(10<<20))),
+
+		OCRProvider:       strings.ToLower(envStr("OCR_PROVIDER", "mistral")),
+		TesseractBinary:   envStr("TESSERACT_BINARY", "tesseract"),
+		PDFToPPMBinary:    envStr("PDFTOPPM_BINARY", "pdftoppm"),
+		TesseractLanguage: envStr("TESSERACT_LANG", "eng"),
+		TesseractDPI:      envInt("TESSERACT_DPI", 300),
+		TesseractTimeout:  envDur("TESSERACT_TIMEOUT", 60*time.Second),
+
+		OCRFallbackProviders: envCSV("OCR_FALLBACK_PROVIDERS", nil),
+		OCRBreakerThreshold:  envInt("OCR_BREAKER_THRESHOLD", 5),
+		OCRBreakerCooldown:   envDur("OCR_BREAKER_COOLDOWN", 30*time.Second),
+
 		MaxConcurrentRequests: int64(envInt("MAX_CONCURRENT_REQUESTS", 15)),
 		MaxOCRConcurrent:      int64(envInt("MAX_OCR_CONCURRENT", 3)),
 		MaxPageWorkers:        envInt("MAX_PAGE_WORKERS", 8),
+		PageStreamWindow:      envInt("PAGE_STREAM_WINDOW", 32),
 
 		ReadHeaderTimeout: envDur("READ_HEADER_TIMEOUT", 10*time.Second),
 		ReadTimeout:       envDur("READ_TIMEOUT", 30*time.Second),
@@ -149,11 +242,61 @@ func Load() Config {
 		GroqAPIURL: envStr("GROQ_API_URL", "https://api.groq.com/openai/v1/audio/transcriptions"),
 		GroqModel:  envStr("GROQ_MODEL", "whisper-large-v3-turbo"),
 
+		UpstreamMaxRetries: envInt("UPSTREAM_MAX_RETRIES", 2),
+		UpstreamBaseDelay:  envDur("UPSTREAM_BASE_DELAY", time.Second),
+		UpstreamMaxDelay:   envDur("UPSTREAM_MAX_DELAY", 60*time.Second),
+		MistralRPM:         envInt("MISTRAL_RPM", 360),
+		OpenRouterRPM:      envInt("OPENROUTER_RPM", 600),
+		GroqRPM:            envInt("GROQ_RPM", 20),
+
+		MistralPricePerPage:             envFloat("MISTRAL_PRICE_PER_PAGE", 0.001),
+		OpenRouterPricePerMInputTokens:  envFloat("OPENROUTER_PRICE_PER_M_INPUT_TOKENS", 0.10),
+		OpenRouterPricePerMOutputTokens: envFloat("OPENROUTER_PRICE_PER_M_OUTPUT_TOKENS", 0.20),
+		GroqPricePerAudioHour:           envFloat("GROQ_PRICE_PER_AUDIO_HOUR", 0.04),
+
 		LibreOfficeTimeout: envDur("LIBREOFFICE_TIMEOUT", 60*time.Second),
 		LibreOfficeBinary:  envStr("LIBREOFFICE_BINARY", "soffice"),
 		FFmpegTimeout:      envDur("FFMPEG_TIMEOUT", 120*time.Second),
 		FFmpegBinary:       envStr("FFMPEG_BINARY", "ffmpeg"),
 
+		ArchiveMaxEntries:    envInt("ARCHIVE_MAX_ENTRIES", 1000),
+		ArchiveMaxTotalBytes: int64(envInt("ARCHIVE_MAX_TOTAL_BYTES", int(512<<20))),
+		ArchiveMaxDepth:      envInt("ARCHIVE_MAX_DEPTH", 3),
+		ArchiveMaxRatio:      envFloat("ARCHIVE_MAX_RATIO", 100),
+
+		BatchMaxItems:    envInt("BATCH_MAX_ITEMS", 200),
+		BatchParallelism: envInt("BATCH_PARALLELISM", 4),
+		BatchTimeout:     envDur("BATCH_TIMEOUT", 15*time.Minute),
+
+		ResultCache:           strings.ToLower(envStr("RESULT_CACHE", "memory")),
+		ResultCacheDir:        envStr("RESULT_CACHE_DIR", "/tmp/fileproc-cache"),
+		ResultCacheMaxEntries: envInt("RESULT_CACHE_MAX_ENTRIES", 500),
+		ResultCacheTTL:        envDur("RESULT_CACHE_TTL", 24*time.Hour),
+
+		OCRPageCache:           strings.ToLower(envStr("OCR_PAGE_CACHE", "memory")),
+		OCRPageCacheDir:        envStr("OCR_PAGE_CACHE_DIR", "/tmp/fileproc-ocr-cache"),
+		OCRPageCacheMaxEntries: envInt("OCR_PAGE_CACHE_MAX_ENTRIES", 5000),
+		OCRPageCacheTTL:        envDur("OCR_PAGE_CACHE_TTL", 7*24*time.Hour),
+
+		JobWorkers:                    envInt("JOB_WORKERS", 4),
+		JobQueueSize:                  envInt("JOB_QUEUE_SIZE", 100),
+		JobTimeout:                    envDur("JOB_TIMEOUT", 30*time.Minute),
+		JobStore:                      strings.ToLower(envStr("JOB_STORE", "memory")),
+		JobStoreDir:                   envStr("JOB_STORE_DIR", "/tmp/fileproc-jobs"),
+		JobRetention:                  envDur("JOB_RETENTION", 24*time.Hour),
+		JobWebhookSecret:              envStr("JOB_WEBHOOK_SECRET", ""),
+		JobWebhookTimeout:             envDur("JOB_WEBHOOK_TIMEOUT", 10*time.Second),
+		JobWebhookAllowedHostSuffixes: envCSV("JOB_WEBHOOK_ALLOWED_HOST_SUFFIXES", nil),
+
+		BudgetDailyUSD:   envFloat("BUDGET_DAILY_USD", 0),
+		BudgetMonthlyUSD: envFloat("BUDGET_MONTHLY_USD", 0),
+		BudgetStore:      strings.ToLower(envStr("BUDGET_STORE", "memory")),
+		BudgetStoreDir:   envStr("BUDGET_STORE_DIR", "/tmp/fileproc-budgets"),
+
+		OTelEndpoint:    envStr("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		OTelServiceName: envStr("OTEL_SERVICE_NAME", "file-processing-service"),
+		OTelSampleRatio: envFloat("OTEL_TRACES_SAMPLER_ARG", 1),
+
 		AllowedPresignedHostSuffixes: envCSV("ALLOWED_PRESIGNED_HOST_SUFFIXES", []string{
 			".r2.cloudflarestorage.com",
 			".r2.dev",
@@ -166,6 +309,39 @@ func (c Config) Validate() error {
 	if len(strings.TrimSpace(c.InternalSharedSecret)) < 32 {
 		return fmt.Errorf("INTERNAL_SHARED_SECRET must be at least 32 characters")
 	}
+	switch c.JobStore {
+	case "memory", "file":
+	default:
+		return fmt.Errorf("JOB_STORE must be \"memory\" or \"file\"")
+	}
+	switch c.OCRProvider {
+	case "mistral", "tesseract":
+	default:
+		return fmt.Errorf("OCR_PROVIDER must be \"mistral\" or \"tesseract\"")
+	}
+	for _, p := range c.OCRFallbackProviders {
+		if p != "mistral" && p != "tesseract" {
+			return fmt.Errorf("OCR_FALLBACK_PROVIDERS entries must be \"mistral\" or \"tesseract\", got %q", p)
+		}
+	}
+	switch c.ResultCache {
+	case "memory", "file", "off":
+	default:
+		return fmt.Errorf("RESULT_CACHE must be \"memory\", \"file\" or \"off\"")
+	}
+	switch c.OCRPageCache {
+	case "memory", "file", "off":
+	default:
+		return fmt.Errorf("OCR_PAGE_CACHE must be \"memory\", \"file\" or \"off\"")
+	}
+	switch c.BudgetStore {
+	case "memory", "file":
+	default:
+		return fmt.Errorf("BUDGET_STORE must be \"memory\" or \"file\"")
+	}
+	if c.OTelSampleRatio > 1 {
+		return fmt.Errorf("OTEL_TRACES_SAMPLER_ARG must be between 0 and 1")
+	}
 	return nil
 }
 
@@ -0,0 +1,100 @@
+package extract
+
+import (
+	"context"
+	"sync"
+)
+
+// Semaphore is the part of semaphore.Weighted that batches use.
+type Semaphore interface {
+	Acquire(ctx context.Context, n int64) error
+	Release(n int64)
+}
+
+// BatchItem is one per-file outcome of ExtractBatch. Index is the item's
+// position in the submitted batch; items are emitted in completion order.
+type BatchItem struct {
+	Index    int    `json:"index"`
+	FileName string `json:"fileName"`
+	Result
+}
+
+type BatchOptions struct {
+	// Parallelism caps how many items are extracted at once (default 1).
+	Parallelism int
+	// Sem, if set, is acquired per item so batch work shares the server-wide
+	// concurrency budget with single-file requests.
+	Sem Semaphore
+	// Validate, if set, rejects an item before any download is attempted.
+	Validate func(UniversalExtractRequest) error
+}
+
+// ExtractBatch runs every request through Extract and calls emit once per item
+// as it finishes. A failing item never aborts the rest of the batch; items not
+// started before ctx is done are reported with the context error. emit is
+// always called from the caller's goroutine.
+func (r *Router) ExtractBatch(ctx context.Context, reqs []UniversalExtractRequest, opts BatchOptions, emit func(BatchItem)) {
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	if parallelism > len(reqs) {
+		parallelism = len(reqs)
+	}
+
+	indices := make(chan int)
+	done := make(chan BatchItem)
+
+	var wg sync.WaitGroup
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				done <- r.extractBatchItem(ctx, i, reqs[i], opts)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(indices)
+		for i := range reqs {
+			select {
+			case indices <- i:
+			case <-ctx.Done():
+				for j := i; j < len(reqs); j++ {
+					done <- batchError(j, reqs[j], ctx.Err())
+				}
+				return
+			}
+		}
+	}()
+
+	// Exactly one item is sent per request, either by a worker or by the
+	// dispatcher for items skipped after cancellation.
+	for emitted := 0; emitted < len(reqs); emitted++ {
+		emit(<-done)
+	}
+	wg.Wait()
+}
+
+func (r *Router) extractBatchItem(ctx context.Context, index int, req UniversalExtractRequest, opts BatchOptions) BatchItem {
+	if opts.Validate != nil {
+		if err := opts.Validate(req); err != nil {
+			return batchError(index, req, err)
+		}
+	}
+	if opts.Sem != nil {
+		if err := opts.Sem.Acquire(ctx, 1); err != nil {
+			return batchError(index, req, err)
+		}
+		defer opts.Sem.Release(1)
+	}
+
+	res, _ := r.Extract(ctx, req)
+	return BatchItem{Index: index, FileName: req.FileName, Result: res}
+}
+
+func batchError(index int, req UniversalExtractRequest, err error) BatchItem {
+	return BatchItem{Index: index, FileName: req.FileName, Result: errResult(err.Error())}
+}
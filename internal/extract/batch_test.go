@@ -0,0 +1,94 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractBatchReportsPerItemResults(t *testing.T) {
+	t.Setenv("ALLOW_PRIVATE_DOWNLOAD_URLS", "1")
+
+	reg := NewRegistry()
+	reg.Register(&stubExtractor{
+		name: "text/plain",
+		mts:  []string{"text/plain"},
+		exts: []string{".txt"},
+	})
+	router := NewRouter(reg, 1<<20, 5*time.Second)
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	origTransport := http.DefaultTransport
+	http.DefaultTransport = srv.Client().Transport
+	defer func() { http.DefaultTransport = origTransport }()
+
+	reqs := []UniversalExtractRequest{
+		{PresignedURL: srv.URL + "/a", FileName: "a.txt"},
+		{PresignedURL: srv.URL + "/missing", FileName: "b.txt"},
+		{PresignedURL: "", FileName: "c.txt"},
+		{PresignedURL: srv.URL + "/rejected", FileName: "d.txt"},
+	}
+
+	var items []BatchItem
+	router.ExtractBatch(context.Background(), reqs, BatchOptions{
+		Parallelism: 2,
+		Validate: func(req UniversalExtractRequest) error {
+			if strings.HasSuffix(req.PresignedURL, "/rejected") {
+				return errors.New("host not allowed")
+			}
+			return nil
+		},
+	}, func(item BatchItem) {
+		items = append(items, item)
+	})
+
+	if len(items) != len(reqs) {
+		t.Fatalf("expected %d items, got %d", len(reqs), len(items))
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Index < items[j].Index })
+
+	if !items[0].Success || items[0].FileName != "a.txt" {
+		t.Fatalf("expected first item to succeed, got %+v", items[0])
+	}
+	for _, item := range items[1:] {
+		if item.Success || item.Error == nil {
+			t.Fatalf("expected item %d to fail with an error, got %+v", item.Index, item)
+		}
+	}
+	if *items[3].Error != "host not allowed" {
+		t.Fatalf("expected validation error, got %q", *items[3].Error)
+	}
+}
+
+func TestExtractBatchReportsSkippedItemsOnCancel(t *testing.T) {
+	router := NewRouter(NewRegistry(), 1<<20, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	reqs := make([]UniversalExtractRequest, 5)
+	count := 0
+	router.ExtractBatch(ctx, reqs, BatchOptions{Parallelism: 2}, func(item BatchItem) {
+		count++
+		if item.Success {
+			t.Fatalf("expected cancelled item %d to fail", item.Index)
+		}
+	})
+	if count != len(reqs) {
+		t.Fatalf("expected %d items, got %d", len(reqs), count)
+	}
+}
@@ -0,0 +1,36 @@
+package extract
+
+import (
+	"github.com/toricodesthings/file-processing-service/internal/format"
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+// ParseBlocksOption reads the "blocks" option, which asks for the document
+// structure alongside the text.
+func ParseBlocksOption(options map[string]any) (bool, error) {
+	b, _, err := BoolOption(options, "blocks")
+	return b, err
+}
+
+// ResultBlocks returns res.Blocks, or blocks parsed from the markdown of
+// extractors that don't build their own, such as OCR, transcripts and PDF
+// text layers. Pages are parsed one by one and separated by page breaks.
+func ResultBlocks(res Result) []types.Block {
+	if res.Blocks != nil {
+		return res.Blocks
+	}
+	if len(res.Pages) == 0 {
+		return format.ParseMarkdown(res.Text)
+	}
+	var blocks []types.Block
+	for i, p := range res.Pages {
+		if i > 0 {
+			blocks = append(blocks, types.Block{Type: types.BlockPageBreak, Source: types.Source{Page: res.Pages[i-1].PageNumber}})
+		}
+		for _, b := range format.ParseMarkdown(p.Text) {
+			b.Source.Page = p.PageNumber
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
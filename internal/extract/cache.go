@@ -0,0 +1,264 @@
+package extract
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+// ResultCache stores successful extraction results by content key.
+// Implementations must be safe for concurrent use. Caching is best effort:
+// a failed lookup is a miss and a failed write is dropped.
+type ResultCache interface {
+	Get(ctx context.Context, key string) (Result, bool)
+	Set(ctx context.Context, key string, res Result)
+}
+
+// Versioned is implemented by extractors whose output changes between
+// releases. Bumping the version invalidates that extractor's cached results.
+type Versioned interface {
+	Version() string
+}
+
+const defaultExtractorVersion = "1"
+
+// cacheExcludedOptions do not change extractor output: chunking and the
+// output format are applied to the cached result afterwards, blocks are
+// always cached and "cache" only controls the lookup.
+var cacheExcludedOptions = map[string]bool{"chunking": true, "blocks": true, "outputFormat": true, "cache": true}
+
+// CacheKey derives the cache key for a file's SHA-256, the extractor that
+// will handle it and the request options. Options are normalized by JSON
+// encoding, which sorts map keys.
+func CacheKey(fileSHA256 string, ex Extractor, options map[string]any) (string, error) {
+	version := defaultExtractorVersion
+	if v, ok := ex.(Versioned); ok {
+		version = v.Version()
+	}
+
+	relevant := make(map[string]any, len(options))
+	for k, v := range options {
+		if v != nil && !cacheExcludedOptions[k] {
+			relevant[k] = v
+		}
+	}
+	opts, err := json.Marshal(relevant)
+	if err != nil {
+		return "", fmt.Errorf("normalize options: %w", err)
+	}
+
+	h := sha256.New()
+	for _, part := range []string{fileSHA256, ex.Name(), version, string(opts)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cloneResult copies the slices and map a caller might mutate so cached
+// entries stay unchanged.
+func cloneResult(res Result) Result {
+	if res.Metadata != nil {
+		meta := make(map[string]string, len(res.Metadata))
+		for k, v := range res.Metadata {
+			meta[k] = v
+		}
+		res.Metadata = meta
+	}
+	res.Pages = append([]PageResult(nil), res.Pages...)
+	res.Blocks = append([]types.Block(nil), res.Blocks...)
+	res.Tables = append([]types.Table(nil), res.Tables...)
+	res.Chunks = nil
+	// A cache hit spends nothing upstream and runs no extractor chain.
+	res.Usage = nil
+	res.Attempts = nil
+	return res
+}
+
+// ---------- In-memory LRU ----------
+
+type memoryEntry struct {
+	key      string
+	res      Result
+	storedAt time.Time
+}
+
+// MemoryCache keeps the most recently used results in process memory.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	ll         *list.List
+	items      map[string]*list.Element
+	now        func() time.Time
+}
+
+func NewMemoryCache(maxEntries int, ttl time.Duration) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = 500
+	}
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string) (Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return Result{}, false
+	}
+	ent := el.Value.(*memoryEntry)
+	if c.ttl > 0 && c.now().Sub(ent.storedAt) > c.ttl {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return Result{}, false
+	}
+	c.ll.MoveToFront(el)
+	return cloneResult(ent.res), true
+}
+
+func (c *MemoryCache) Set(_ context.Context, key string, res Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value = &memoryEntry{key: key, res: cloneResult(res), storedAt: c.now()}
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&memoryEntry{key: key, res: cloneResult(res), storedAt: c.now()})
+	for c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoryEntry).key)
+	}
+}
+
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// ---------- File-backed ----------
+
+var validCacheKeyPattern = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+type fileEntry struct {
+	StoredAt time.Time `json:"storedAt"`
+	Result   Result    `json:"result"`
+}
+
+// FileCache writes one JSON document per key so results survive restarts and
+// can be shared by replicas on the same volume. Expired entries are removed
+// lazily on Get and in bulk by Prune.
+type FileCache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+func NewFileCache(dir string, ttl time.Duration) (*FileCache, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, fmt.Errorf("result cache dir required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("result cache dir: %w", err)
+	}
+	return &FileCache{dir: dir, ttl: ttl, now: time.Now}, nil
+}
+
+func (c *FileCache) path(key string) (string, bool) {
+	if !validCacheKeyPattern.MatchString(key) {
+		return "", false
+	}
+	return filepath.Join(c.dir, key+".json"), true
+}
+
+func (c *FileCache) Get(_ context.Context, key string) (Result, bool) {
+	p, ok := c.path(key)
+	if !ok {
+		return Result{}, false
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return Result{}, false
+	}
+	var ent fileEntry
+	if err := json.Unmarshal(b, &ent); err != nil {
+		_ = os.Remove(p)
+		return Result{}, false
+	}
+	if c.expired(ent.StoredAt) {
+		_ = os.Remove(p)
+		return Result{}, false
+	}
+	return ent.Result, true
+}
+
+func (c *FileCache) Set(_ context.Context, key string, res Result) {
+	p, ok := c.path(key)
+	if !ok {
+		return
+	}
+	b, err := json.Marshal(fileEntry{StoredAt: c.now(), Result: cloneResult(res)})
+	if err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(c.dir, key+"-*.tmp")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(b)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil || os.Rename(tmp.Name(), p) != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// Prune deletes expired entries and returns how many were removed.
+func (c *FileCache) Prune(_ context.Context) int {
+	if c.ttl <= 0 {
+		return 0
+	}
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, de := range entries {
+		if de.IsDir() || !(strings.HasSuffix(de.Name(), ".json") || strings.HasSuffix(de.Name(), ".tmp")) {
+			continue
+		}
+		// The write time is close enough to StoredAt and avoids parsing every entry.
+		info, err := de.Info()
+		if err != nil || !c.expired(info.ModTime()) {
+			continue
+		}
+		if os.Remove(filepath.Join(c.dir, de.Name())) == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+func (c *FileCache) expired(storedAt time.Time) bool {
+	return c.ttl > 0 && c.now().Sub(storedAt) > c.ttl
+}
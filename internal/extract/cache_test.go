@@ -0,0 +1,213 @@
+package extract
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/usage"
+)
+
+type countingExtractor struct {
+	stubExtractor
+	calls atomic.Int32
+}
+
+func (c *countingExtractor) Extract(ctx context.Context, job Job) (Result, error) {
+	c.calls.Add(1)
+	usage.Record(ctx, "stub", usage.UnitPages, 1)
+	return Result{Success: true, Text: "## Notes\n\nhello world", Metadata: map[string]string{"title": "Notes"}}, nil
+}
+
+func TestCacheKeyNormalizesOptions(t *testing.T) {
+	ex := &stubExtractor{name: "document/pdf"}
+	hash := strings.Repeat("a", 64)
+
+	base, err := CacheKey(hash, ex, map[string]any{"pages": "1-3", "ocrModel": "m"})
+	if err != nil {
+		t.Fatalf("key: %v", err)
+	}
+	same, _ := CacheKey(hash, ex, map[string]any{"ocrModel": "m", "pages": "1-3", "chunking": true, "cache": true})
+	if base != same {
+		t.Fatalf("key should ignore option order, chunking and cache")
+	}
+	other, _ := CacheKey(hash, ex, map[string]any{"pages": "1-4", "ocrModel": "m"})
+	if base == other {
+		t.Fatalf("key should change with extractor options")
+	}
+	otherFile, _ := CacheKey(strings.Repeat("b", 64), ex, map[string]any{"pages": "1-3", "ocrModel": "m"})
+	if base == otherFile {
+		t.Fatalf("key should change with file hash")
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsedAndExpires(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(2, time.Hour)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "a", Result{Text: "a"})
+	c.Set(ctx, "b", Result{Text: "b"})
+	if _, ok := c.Get(ctx, "a"); !ok {
+		t.Fatalf("expected a to be cached")
+	}
+	c.Set(ctx, "c", Result{Text: "c"})
+	if _, ok := c.Get(ctx, "b"); ok {
+		t.Fatalf("expected b to be evicted as least recently used")
+	}
+
+	res, _ := c.Get(ctx, "a")
+	res.Metadata = map[string]string{"cached": "true"}
+	if again, _ := c.Get(ctx, "a"); again.Metadata != nil {
+		t.Fatalf("callers must not be able to mutate cached entries")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := c.Get(ctx, "c"); ok {
+		t.Fatalf("expected c to expire")
+	}
+}
+
+func TestFileCacheRoundTripAndPrune(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewFileCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	key := strings.Repeat("c", 64)
+
+	c.Set(ctx, key, Result{Success: true, Text: "cached text", Pages: []PageResult{{PageNumber: 1, Text: "p1"}}})
+	res, ok := c.Get(ctx, key)
+	if !ok || res.Text != "cached text" || len(res.Pages) != 1 {
+		t.Fatalf("round trip failed: %+v, %v", res, ok)
+	}
+	if _, ok := c.Get(ctx, "../../etc/passwd"); ok {
+		t.Fatalf("invalid keys must miss")
+	}
+
+	c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if n := c.Prune(ctx); n != 1 {
+		t.Fatalf("expected 1 pruned entry, got %d", n)
+	}
+	if _, ok := c.Get(ctx, key); ok {
+		t.Fatalf("expected pruned entry to miss")
+	}
+}
+
+func TestCachesDropPerRequestFields(t *testing.T) {
+	ctx := context.Background()
+	fileCache, err := NewFileCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	key := strings.Repeat("d", 64)
+	res := Result{
+		Success:  true,
+		Text:     "converted",
+		Attempts: []Attempt{{Extractor: "native", Error: "corrupt file"}, {Extractor: "converter"}},
+		Usage:    &usage.Summary{EstimatedCostUSD: 1},
+	}
+	for name, c := range map[string]ResultCache{"memory": NewMemoryCache(2, time.Hour), "file": fileCache} {
+		c.Set(ctx, key, res)
+		got, ok := c.Get(ctx, key)
+		if !ok || got.Text != "converted" {
+			t.Fatalf("%s: round trip failed: %+v, %v", name, got, ok)
+		}
+		if got.Attempts != nil || got.Usage != nil {
+			t.Fatalf("%s: cache hits must not report attempts or usage, got %+v", name, got)
+		}
+	}
+}
+
+func TestRouterServesRepeatExtractionsFromCache(t *testing.T) {
+	ex := &countingExtractor{stubExtractor: stubExtractor{name: "text/markdown", exts: []string{".md"}}}
+	reg := NewRegistry()
+	reg.Register(ex)
+	router := NewRouter(reg, 1<<20, 5*time.Second)
+	router.SetCache(NewMemoryCache(10, time.Hour))
+
+	extractBody := func(options map[string]any) Result {
+		t.Helper()
+		dl, err := SaveBodyToTemp(strings.NewReader("# same bytes"), "notes.md", 1<<20)
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		defer dl.Cleanup()
+		res, err := router.ExtractFile(context.Background(), dl, "notes.md", options)
+		if err != nil {
+			t.Fatalf("extract: %v", err)
+		}
+		return res
+	}
+
+	first := extractBody(nil)
+	if first.Metadata["cached"] != "" {
+		t.Fatalf("first extraction should not be cached")
+	}
+	if first.Usage == nil || first.Usage.Items[0].Quantity != 1 {
+		t.Fatalf("expected usage on a fresh extraction, got %+v", first.Usage)
+	}
+	second := extractBody(map[string]any{"chunking": true})
+	if second.Metadata["cached"] != "true" || second.Text != first.Text {
+		t.Fatalf("expected cached result, got %+v", second)
+	}
+	if second.Usage != nil {
+		t.Fatalf("cache hits should not report usage, got %+v", second.Usage)
+	}
+	if len(second.Chunks) == 0 {
+		t.Fatalf("chunking should apply to cached results")
+	}
+	if ex.calls.Load() != 1 {
+		t.Fatalf("expected 1 extractor call, got %d", ex.calls.Load())
+	}
+
+	extractBody(map[string]any{"cache": false})
+	if ex.calls.Load() != 2 {
+		t.Fatalf("cache=false should bypass the cache")
+	}
+}
+
+func TestRouterDoesNotCacheFallbackResults(t *testing.T) {
+	converter := &countingExtractor{stubExtractor: stubExtractor{name: "converter", exts: []string{".doc"}}}
+	reg := NewRegistry()
+	reg.RegisterPriority(converter, PriorityFallback)
+	reg.Register(&failingExtractor{stubExtractor{name: "native", exts: []string{".doc"}}, errors.New("corrupt file")})
+	router := NewRouter(reg, 1<<20, 5*time.Second)
+	router.SetCache(NewMemoryCache(10, time.Hour))
+
+	for i := range 2 {
+		dl, err := SaveBodyToTemp(strings.NewReader("same bytes"), "a.doc", 1<<20)
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		defer dl.Cleanup()
+		res, err := router.ExtractFile(context.Background(), dl, "a.doc", nil)
+		if err != nil {
+			t.Fatalf("extract %d: %v", i, err)
+		}
+		if res.Metadata["cached"] != "" || len(res.Attempts) != 2 {
+			t.Fatalf("extract %d: fallback results must not come from the cache, got %+v", i, res)
+		}
+	}
+	if converter.calls.Load() != 2 {
+		t.Fatalf("expected the fallback to run for each request, got %d calls", converter.calls.Load())
+	}
+}
+
+func TestSaveBodyToTempHashesContent(t *testing.T) {
+	dl, err := SaveBodyToTemp(strings.NewReader("hello"), "a.txt", 1<<20)
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	defer dl.Cleanup()
+	sum := sha256.Sum256([]byte("hello"))
+	if dl.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected hash %q", dl.SHA256)
+	}
+}
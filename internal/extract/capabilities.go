@@ -0,0 +1,90 @@
+package extract
+
+import "os/exec"
+
+// Capabilities is what an extractor declares beyond the Extractor interface
+// so clients can discover it instead of hard-coding it.
+type Capabilities struct {
+	PaidAPIs []string // upstream APIs that may bill for a call, e.g. "groq"
+	Options  []string // request option keys the extractor reads
+	Binaries []string // external programs it runs, as configured
+}
+
+// Describer is implemented by extractors that call paid APIs, read request
+// options or run external programs. Extractors without it need none of these.
+type Describer interface {
+	Capabilities() Capabilities
+}
+
+// Binary reports whether an external program is installed.
+type Binary struct {
+	Name    string `json:"name"`
+	Present bool   `json:"present"`
+	Path    string `json:"path,omitempty"`
+}
+
+// ExtractorInfo is one extractor's entry in GET /capabilities.
+type ExtractorInfo struct {
+	Name        string   `json:"name"`
+	MIMETypes   []string `json:"mimeTypes"`
+	Extensions  []string `json:"extensions"`
+	MaxFileSize int64    `json:"maxFileSize"`
+	Priority    int      `json:"priority"` // PriorityNative, or lower for fallbacks
+	PaidAPIs    []string `json:"paidApis"`
+	Preview     bool     `json:"preview"`
+	Options     []string `json:"options"`
+	Binaries    []Binary `json:"binaries"`
+	// Available is false when one of Binaries is missing, since every
+	// extraction would then fail.
+	Available bool `json:"available"`
+}
+
+// Describe reports every registered extractor, in registration order.
+func (r *Registry) Describe() []ExtractorInfo {
+	out := make([]ExtractorInfo, 0, len(r.extractors))
+	for _, c := range r.extractors {
+		info := Describe(c.extractor)
+		info.Priority = c.priority
+		out = append(out, info)
+	}
+	return out
+}
+
+// Describe reports e's capabilities, looking its binaries up on PATH.
+// Preview is left for the caller, which owns the preview policy.
+func Describe(e Extractor) ExtractorInfo {
+	info := ExtractorInfo{
+		Name:        e.Name(),
+		MIMETypes:   nonNil(e.SupportedTypes()),
+		Extensions:  nonNil(e.SupportedExtensions()),
+		MaxFileSize: e.MaxFileSize(),
+		PaidAPIs:    []string{},
+		Options:     []string{},
+		Binaries:    []Binary{},
+		Available:   true,
+	}
+	d, ok := e.(Describer)
+	if !ok {
+		return info
+	}
+	caps := d.Capabilities()
+	info.PaidAPIs = nonNil(caps.PaidAPIs)
+	info.Options = nonNil(caps.Options)
+	for _, name := range caps.Binaries {
+		b := Binary{Name: name}
+		if path, err := exec.LookPath(name); err == nil {
+			b.Present, b.Path = true, path
+		} else {
+			info.Available = false
+		}
+		info.Binaries = append(info.Binaries, b)
+	}
+	return info
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
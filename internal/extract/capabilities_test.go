@@ -0,0 +1,44 @@
+package extract
+
+import (
+	"os/exec"
+	"testing"
+)
+
+type describedExtractor struct {
+	stubExtractor
+	caps Capabilities
+}
+
+func (d *describedExtractor) Capabilities() Capabilities { return d.caps }
+
+func TestDescribeReportsMissingBinaries(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not on PATH")
+	}
+	e := &describedExtractor{
+		stubExtractor: stubExtractor{name: "tool", exts: []string{".tool"}},
+		caps: Capabilities{
+			PaidAPIs: []string{"groq"},
+			Options:  []string{"model"},
+			Binaries: []string{"sh", "definitely-not-installed-binary"},
+		},
+	}
+	info := Describe(e)
+	if info.Name != "tool" || len(info.PaidAPIs) != 1 || len(info.Options) != 1 {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	if len(info.Binaries) != 2 || !info.Binaries[0].Present || info.Binaries[1].Present {
+		t.Fatalf("binaries = %+v", info.Binaries)
+	}
+	if info.Available {
+		t.Fatal("extractor with a missing binary reported as available")
+	}
+}
+
+func TestDescribeWithoutDescriber(t *testing.T) {
+	info := Describe(&stubExtractor{name: "plain", exts: []string{".txt"}})
+	if !info.Available || info.PaidAPIs == nil || info.Options == nil || info.Binaries == nil || info.MIMETypes == nil {
+		t.Fatalf("expected an available extractor with empty lists, got %+v", info)
+	}
+}
@@ -0,0 +1,485 @@
+package extract
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	ChunkByHeading   = "heading"
+	ChunkByParagraph = "paragraph"
+	ChunkFixed       = "fixed"
+
+	ChunkUnitTokens = "tokens"
+	ChunkUnitChars  = "chars"
+
+	defaultChunkTokens = 512
+	defaultChunkChars  = 2000
+	maxChunks          = 20000
+
+	// runesPerToken approximates a BPE tokenizer on English prose. Callers
+	// that need exact budgets should chunk by chars.
+	runesPerToken = 4
+)
+
+var (
+	chunkSizeBounds  = map[string][2]int{ChunkUnitTokens: {32, 8192}, ChunkUnitChars: {128, 32768}}
+	pageMarkerRe     = regexp.MustCompile(`^\[Page (\d+)\]$`)
+	pageMarkerLineRe = regexp.MustCompile(`(?m)^\[Page \d+\]\s*$`)
+	markdownHeaderRe = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*$`)
+)
+
+// ChunkOptions controls how Result.Text is split into Result.Chunks.
+type ChunkOptions struct {
+	Strategy   string
+	TargetSize int
+	Unit       string
+	Overlap    int
+}
+
+// Chunk is a contiguous slice of Result.Text. StartChar/EndChar are rune
+// offsets (the same unit as CharCount), so Text == []rune(Result.Text)[StartChar:EndChar].
+type Chunk struct {
+	Index       int      `json:"index"`
+	Text        string   `json:"text"`
+	StartChar   int      `json:"startChar"`
+	EndChar     int      `json:"endChar"`
+	PageNumber  int      `json:"pageNumber,omitempty"`
+	HeadingPath []string `json:"headingPath,omitempty"`
+	WordCount   int      `json:"wordCount"`
+}
+
+// ParseChunkOptions reads the "chunking" option. It returns nil when chunking
+// was not requested. `true` selects the defaults; an object may override
+// strategy, targetSize, unit and overlap.
+func ParseChunkOptions(options map[string]any) (*ChunkOptions, error) {
+	raw, ok := lookupOption(options, "chunking")
+	if !ok {
+		return nil, nil
+	}
+
+	var obj map[string]any
+	switch v := raw.(type) {
+	case bool:
+		if !v {
+			return nil, nil
+		}
+	case map[string]any:
+		obj = v
+	default:
+		return nil, &OptionError{Key: "chunking", Reason: "must be a boolean or an object"}
+	}
+
+	opts := &ChunkOptions{Strategy: ChunkByHeading, Unit: ChunkUnitTokens}
+
+	if s, ok, err := StringOption(obj, "strategy"); err != nil {
+		return nil, prefixOptionError(err)
+	} else if ok {
+		switch s = strings.ToLower(strings.TrimSpace(s)); s {
+		case ChunkByHeading, ChunkByParagraph, ChunkFixed:
+			opts.Strategy = s
+		default:
+			return nil, &OptionError{Key: "chunking.strategy", Reason: "must be one of heading, paragraph, fixed"}
+		}
+	}
+
+	if s, ok, err := StringOption(obj, "unit"); err != nil {
+		return nil, prefixOptionError(err)
+	} else if ok {
+		switch s = strings.ToLower(strings.TrimSpace(s)); s {
+		case ChunkUnitTokens, ChunkUnitChars:
+			opts.Unit = s
+		default:
+			return nil, &OptionError{Key: "chunking.unit", Reason: "must be tokens or chars"}
+		}
+	}
+
+	bounds := chunkSizeBounds[opts.Unit]
+	opts.TargetSize = defaultChunkTokens
+	if opts.Unit == ChunkUnitChars {
+		opts.TargetSize = defaultChunkChars
+	}
+	if n, ok, err := IntOption(obj, "targetSize"); err != nil {
+		return nil, prefixOptionError(err)
+	} else if ok {
+		if n < bounds[0] || n > bounds[1] {
+			return nil, &OptionError{Key: "chunking.targetSize", Reason: fmt.Sprintf("must be between %d and %d %s", bounds[0], bounds[1], opts.Unit)}
+		}
+		opts.TargetSize = n
+	}
+
+	if n, ok, err := IntOption(obj, "overlap"); err != nil {
+		return nil, prefixOptionError(err)
+	} else if ok {
+		if n < 0 || n > opts.TargetSize/2 {
+			return nil, &OptionError{Key: "chunking.overlap", Reason: fmt.Sprintf("must be between 0 and %d %s", opts.TargetSize/2, opts.Unit)}
+		}
+		opts.Overlap = n
+	}
+
+	return opts, nil
+}
+
+func prefixOptionError(err error) error {
+	if oe, ok := err.(*OptionError); ok {
+		return &OptionError{Key: "chunking." + oe.Key, Reason: oe.Reason}
+	}
+	return err
+}
+
+// chunkBlock is a run of lines that must not be split unless it alone
+// exceeds the target: a paragraph, list, table, code fence or heading line.
+type chunkBlock struct {
+	start, end int // byte offsets into the text
+	heading    bool
+	skip       bool // page markers and "---" separators carry no content
+	page       int
+	path       []string
+}
+
+// BuildChunks splits text into chunks that follow the markdown structure the
+// extractors emit: headings ("## Slide 3", "## Sheet: Q1", ...), "[Page N]"
+// markers, blank-line paragraphs, tables and code fences. pages, when present,
+// attributes chunks to PDF pages even if page markers were not requested.
+func BuildChunks(text string, pages []PageResult, opts ChunkOptions) []Chunk {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	c := chunker{text: text, opts: opts}
+	blocks := c.blocks(pages)
+
+	var spans [][3]int // start, end, segment start
+	switch opts.Strategy {
+	case ChunkFixed:
+		for _, s := range c.split(0, len(text)) {
+			spans = append(spans, [3]int{s[0], s[1], 0})
+		}
+	default:
+		spans = c.pack(blocks)
+	}
+
+	out := make([]Chunk, 0, len(spans))
+	cur := runeCursor{text: text}
+	for _, s := range spans {
+		start, end := s[0], s[1]
+		if opts.Overlap > 0 && len(out) > 0 {
+			start = c.extendBack(start, s[2])
+		}
+		start, end = trimSpan(text, start, end)
+		if start >= end {
+			continue
+		}
+		b := labelBlock(blocks, start, end)
+		chunkText := text[start:end]
+		words, _ := BuildCounts(chunkText)
+		out = append(out, Chunk{
+			Index:       len(out),
+			Text:        chunkText,
+			StartChar:   cur.at(start),
+			EndChar:     cur.at(end),
+			PageNumber:  b.page,
+			HeadingPath: b.path,
+			WordCount:   words,
+		})
+		if len(out) >= maxChunks {
+			break
+		}
+	}
+	return out
+}
+
+type chunker struct {
+	text string
+	opts ChunkOptions
+}
+
+// size measures text[start:end] in the configured unit.
+func (c chunker) size(start, end int) int {
+	n := utf8.RuneCountInString(c.text[start:end])
+	if c.opts.Unit == ChunkUnitTokens {
+		return (n + runesPerToken - 1) / runesPerToken
+	}
+	return n
+}
+
+func (c chunker) runeBudget(units int) int {
+	if c.opts.Unit == ChunkUnitTokens {
+		return units * runesPerToken
+	}
+	return units
+}
+
+func (c chunker) blocks(pages []PageResult) []chunkBlock {
+	var (
+		out     []chunkBlock
+		path    []string
+		levels  []int
+		page    int
+		inFence bool
+		cur     = -1
+	)
+	// Explicit "[Page N]" markers win over locating page text.
+	var starts [][2]int
+	if !pageMarkerLineRe.MatchString(c.text) {
+		starts = pageStarts(c.text, pages)
+	}
+
+	closeBlock := func(end int) {
+		if cur >= 0 {
+			out = append(out, chunkBlock{start: cur, end: end, page: page, path: path})
+			cur = -1
+		}
+	}
+
+	for off := 0; off < len(c.text); {
+		nl := strings.IndexByte(c.text[off:], '\n')
+		lineEnd := len(c.text)
+		if nl >= 0 {
+			lineEnd = off + nl
+		}
+		next := lineEnd + 1
+		line := strings.TrimSpace(c.text[off:lineEnd])
+
+		for len(starts) > 0 && starts[0][0] <= off {
+			closeBlock(off)
+			page = starts[0][1]
+			starts = starts[1:]
+		}
+
+		switch {
+		case strings.HasPrefix(line, "```"):
+			if cur < 0 {
+				cur = off
+			}
+			inFence = !inFence
+		case inFence:
+		case line == "":
+			closeBlock(off)
+		case pageMarkerRe.MatchString(line):
+			closeBlock(off)
+			page, _ = strconv.Atoi(pageMarkerRe.FindStringSubmatch(line)[1])
+			out = append(out, chunkBlock{start: off, end: lineEnd, skip: true, page: page, path: path})
+		case line == "---" && cur < 0:
+			out = append(out, chunkBlock{start: off, end: lineEnd, skip: true, page: page, path: path})
+		case markdownHeaderRe.MatchString(line):
+			closeBlock(off)
+			m := markdownHeaderRe.FindStringSubmatch(line)
+			level := len(m[1])
+			for len(levels) > 0 && levels[len(levels)-1] >= level {
+				levels = levels[:len(levels)-1]
+				path = path[:len(path)-1]
+			}
+			levels = append(levels, level)
+			path = append(path[:len(path):len(path)], m[2])
+			out = append(out, chunkBlock{start: off, end: lineEnd, heading: true, page: page, path: path})
+		default:
+			if cur < 0 {
+				cur = off
+			}
+		}
+		off = next
+	}
+	closeBlock(len(c.text))
+	return out
+}
+
+// pack groups consecutive blocks into chunks no larger than the target.
+// Headings always start a new chunk under the heading strategy; page changes
+// start one under both structural strategies.
+func (c chunker) pack(blocks []chunkBlock) [][3]int {
+	var spans [][3]int
+	start, end, segment := -1, -1, 0
+	onlyHeadings := false
+	lastPage := 0
+	flush := func() {
+		if start >= 0 {
+			spans = append(spans, [3]int{start, end, segment})
+		}
+		start, end = -1, -1
+	}
+
+	for _, b := range blocks {
+		if b.page != lastPage {
+			flush()
+			segment = b.start
+			lastPage = b.page
+		}
+		if b.skip {
+			continue
+		}
+		if b.heading && c.opts.Strategy == ChunkByHeading && !onlyHeadings {
+			flush()
+			segment = b.start
+		}
+		// A heading stays with the content below it rather than ending a chunk.
+		if start >= 0 && !onlyHeadings && c.size(start, b.end) > c.opts.TargetSize {
+			flush()
+		}
+		if start < 0 {
+			start = b.start
+			onlyHeadings = true
+		}
+		end = b.end
+		onlyHeadings = onlyHeadings && b.heading
+		if c.size(start, end) > c.opts.TargetSize {
+			parts := c.split(start, end)
+			for _, p := range parts[:len(parts)-1] {
+				spans = append(spans, [3]int{p[0], p[1], segment})
+			}
+			start, end = parts[len(parts)-1][0], parts[len(parts)-1][1]
+			onlyHeadings = false
+		}
+	}
+	flush()
+	return spans
+}
+
+// split cuts text[start:end] into pieces of at most the target size,
+// preferring line breaks, then spaces.
+func (c chunker) split(start, end int) [][2]int {
+	var out [][2]int
+	budget := c.runeBudget(c.opts.TargetSize)
+	for start < end {
+		cut := start
+		for n := 0; n < budget && cut < end; n++ {
+			_, w := utf8.DecodeRuneInString(c.text[cut:])
+			cut += w
+		}
+		if cut < end {
+			window := c.text[start:cut]
+			if i := strings.LastIndex(window, "\n"); i > len(window)/2 {
+				cut = start + i + 1
+			} else if i := strings.LastIndexFunc(window, unicode.IsSpace); i > 0 {
+				// The space may be several bytes, like U+3000 or NBSP.
+				_, w := utf8.DecodeRuneInString(window[i:])
+				cut = start + i + w
+			}
+		}
+		out = append(out, [2]int{start, cut})
+		start = cut
+	}
+	return out
+}
+
+// extendBack moves start earlier by the overlap, snapping forward to a word
+// boundary and never crossing floor.
+func (c chunker) extendBack(start, floor int) int {
+	budget := c.runeBudget(c.opts.Overlap)
+	s := start
+	for n := 0; n < budget && s > floor; n++ {
+		_, w := utf8.DecodeLastRuneInString(c.text[:s])
+		s -= w
+	}
+	if s == floor {
+		return s
+	}
+	if i := strings.IndexFunc(c.text[s:start], unicode.IsSpace); i >= 0 {
+		return s + i
+	}
+	return start
+}
+
+// pageStarts locates where each page's text begins in the combined output by
+// searching for its first line in order. Pages that cannot be found are
+// folded into the previous page.
+func pageStarts(text string, pages []PageResult) [][2]int {
+	var out [][2]int
+	from := 0
+	for _, p := range pages {
+		probe := firstLine(p.Text)
+		if probe == "" {
+			continue
+		}
+		i := strings.Index(text[from:], probe)
+		if i < 0 {
+			continue
+		}
+		// Attribute the line containing the probe to this page.
+		at := from + i
+		if nl := strings.LastIndexByte(text[:at], '\n'); nl >= 0 {
+			at = nl + 1
+		} else {
+			at = 0
+		}
+		out = append(out, [2]int{at, p.PageNumber})
+		from = from + i + len(probe)
+	}
+	return out
+}
+
+// firstLine returns a short prefix of the first substantial line of s.
+// Markdown decoration is trimmed since format.Combine normalizes it.
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#*-+>|"))
+		if utf8.RuneCountInString(line) < 8 {
+			continue
+		}
+		if len(line) > 48 {
+			cut := 48
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			line = line[:cut]
+		}
+		return line
+	}
+	return ""
+}
+
+func trimSpan(text string, start, end int) (int, int) {
+	for start < end {
+		r, w := utf8.DecodeRuneInString(text[start:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += w
+	}
+	for end > start {
+		r, w := utf8.DecodeLastRuneInString(text[:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		end -= w
+	}
+	return start, end
+}
+
+// labelBlock picks the block whose page and heading path describe the chunk
+// text[start:end]: the first content block, so a chunk opening with
+// "# Deck\n\n## Slide 1" is labelled with both headings.
+func labelBlock(blocks []chunkBlock, start, end int) chunkBlock {
+	i := sort.Search(len(blocks), func(i int) bool { return blocks[i].start > start })
+	if i == 0 {
+		return chunkBlock{}
+	}
+	for j := i - 1; j < len(blocks) && blocks[j].start < end; j++ {
+		if !blocks[j].heading && !blocks[j].skip {
+			return blocks[j]
+		}
+	}
+	return blocks[i-1]
+}
+
+// runeCursor converts increasing-ish byte offsets to rune offsets without
+// rescanning the text from the start each time.
+type runeCursor struct {
+	text  string
+	bytes int
+	runes int
+}
+
+func (c *runeCursor) at(offset int) int {
+	if offset >= c.bytes {
+		c.runes += utf8.RuneCountInString(c.text[c.bytes:offset])
+	} else {
+		c.runes -= utf8.RuneCountInString(c.text[offset:c.bytes])
+	}
+	c.bytes = offset
+	return c.runes
+}
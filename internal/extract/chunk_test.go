@@ -0,0 +1,161 @@
+package extract
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func checkChunkOffsets(t *testing.T, text string, chunks []Chunk) {
+	t.Helper()
+	runes := []rune(text)
+	for i, c := range chunks {
+		if c.Index != i {
+			t.Fatalf("chunk %d has index %d", i, c.Index)
+		}
+		if got := string(runes[c.StartChar:c.EndChar]); got != c.Text {
+			t.Fatalf("chunk %d offsets [%d,%d) give %q, text is %q", i, c.StartChar, c.EndChar, got, c.Text)
+		}
+		if words, _ := BuildCounts(c.Text); words != c.WordCount {
+			t.Fatalf("chunk %d wordCount = %d, want %d", i, c.WordCount, words)
+		}
+	}
+}
+
+func TestParseChunkOptions(t *testing.T) {
+	opts, err := ParseChunkOptions(map[string]any{})
+	if err != nil || opts != nil {
+		t.Fatalf("absent: got %+v, %v", opts, err)
+	}
+
+	opts, err = ParseChunkOptions(map[string]any{"chunking": true})
+	if err != nil || opts == nil {
+		t.Fatalf("true: got %+v, %v", opts, err)
+	}
+	if opts.Strategy != ChunkByHeading || opts.Unit != ChunkUnitTokens || opts.TargetSize != defaultChunkTokens {
+		t.Fatalf("unexpected defaults: %+v", opts)
+	}
+
+	opts, err = ParseChunkOptions(map[string]any{"chunking": map[string]any{
+		"strategy": "Paragraph", "unit": "chars", "targetSize": float64(500), "overlap": float64(50),
+	}})
+	if err != nil {
+		t.Fatalf("object: %v", err)
+	}
+	if *opts != (ChunkOptions{Strategy: ChunkByParagraph, Unit: ChunkUnitChars, TargetSize: 500, Overlap: 50}) {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+
+	for _, bad := range []map[string]any{
+		{"chunking": "yes"},
+		{"chunking": map[string]any{"strategy": "sentences"}},
+		{"chunking": map[string]any{"targetSize": float64(4)}},
+		{"chunking": map[string]any{"targetSize": float64(100), "overlap": float64(60)}},
+		{"chunking": map[string]any{"overlap": 1.5}},
+	} {
+		_, err := ParseChunkOptions(bad)
+		var oe *OptionError
+		if !errors.As(err, &oe) || !strings.HasPrefix(oe.Key, "chunking") {
+			t.Fatalf("%v: expected chunking OptionError, got %v", bad, err)
+		}
+	}
+}
+
+func TestBuildChunksByHeading(t *testing.T) {
+	text := "# Deck\n\n## Slide 1\n\nWelcome to the café.\n\n## Slide 2\n\nRevenue grew.\n\n| Q | Rev |\n| --- | --- |\n| 1 | 10 |"
+	chunks := BuildChunks(text, nil, ChunkOptions{Strategy: ChunkByHeading, Unit: ChunkUnitTokens, TargetSize: 512})
+	checkChunkOffsets(t, text, chunks)
+
+	// The document title has no body of its own, so it stays with Slide 1.
+	if len(chunks) != 2 {
+		t.Fatalf("expected 2 chunks, got %d: %+v", len(chunks), chunks)
+	}
+	if got := strings.Join(chunks[0].HeadingPath, " > "); got != "Deck > Slide 1" {
+		t.Fatalf("chunk 0 heading path = %q", got)
+	}
+	if got := strings.Join(chunks[1].HeadingPath, " > "); got != "Deck > Slide 2" {
+		t.Fatalf("chunk 1 heading path = %q", got)
+	}
+	if !strings.HasPrefix(chunks[1].Text, "## Slide 2") || !strings.HasSuffix(chunks[1].Text, "| 1 | 10 |") {
+		t.Fatalf("slide 2 chunk should hold heading through table: %q", chunks[1].Text)
+	}
+}
+
+func TestBuildChunksParagraphPacksAndSplits(t *testing.T) {
+	long := strings.Repeat("lorem ipsum dolor ", 40)
+	text := "## Intro\n\nShort one.\n\nShort two.\n\n" + long
+	opts := ChunkOptions{Strategy: ChunkByParagraph, Unit: ChunkUnitChars, TargetSize: 200}
+	chunks := BuildChunks(text, nil, opts)
+	checkChunkOffsets(t, text, chunks)
+
+	if !strings.Contains(chunks[0].Text, "## Intro") || !strings.Contains(chunks[0].Text, "Short two.") {
+		t.Fatalf("small paragraphs should be packed with their heading: %q", chunks[0].Text)
+	}
+	for _, c := range chunks {
+		if n := len([]rune(c.Text)); n > opts.TargetSize {
+			t.Fatalf("chunk %d has %d chars, target %d", c.Index, n, opts.TargetSize)
+		}
+	}
+	if len(chunks) < 4 {
+		t.Fatalf("expected the long paragraph to be split, got %d chunks", len(chunks))
+	}
+}
+
+func TestBuildChunksSplitsAtMultibyteSpaces(t *testing.T) {
+	text := strings.Repeat("日本語の文章\u3000東京\u00a0大阪", 40)
+	opts := ChunkOptions{Strategy: ChunkByParagraph, Unit: ChunkUnitChars, TargetSize: 50}
+	chunks := BuildChunks(text, nil, opts)
+	if len(chunks) < 2 {
+		t.Fatalf("expected the paragraph to be split, got %d chunks", len(chunks))
+	}
+	for _, c := range chunks {
+		if !utf8.ValidString(c.Text) {
+			t.Fatalf("chunk %d is not valid UTF-8: %q", c.Index, c.Text)
+		}
+	}
+	checkChunkOffsets(t, text, chunks)
+}
+
+func TestBuildChunksAttributesPDFPages(t *testing.T) {
+	pages := []PageResult{
+		{PageNumber: 3, Text: "Page three introduces the topic."},
+		{PageNumber: 4, Text: "Page four has the conclusion."},
+	}
+	text := "Page three introduces the topic.\n\n---\n\nPage four has the conclusion."
+	chunks := BuildChunks(text, pages, ChunkOptions{Strategy: ChunkByParagraph, Unit: ChunkUnitTokens, TargetSize: 512})
+	checkChunkOffsets(t, text, chunks)
+
+	if len(chunks) != 2 || chunks[0].PageNumber != 3 || chunks[1].PageNumber != 4 {
+		t.Fatalf("expected one chunk per page, got %+v", chunks)
+	}
+	if strings.Contains(chunks[0].Text, "---") {
+		t.Fatalf("page separator leaked into chunk: %q", chunks[0].Text)
+	}
+
+	marked := "[Page 1]\n\nFirst.\n\n---\n\n[Page 2]\n\nSecond."
+	chunks = BuildChunks(marked, nil, ChunkOptions{Strategy: ChunkByHeading, Unit: ChunkUnitTokens, TargetSize: 512})
+	checkChunkOffsets(t, marked, chunks)
+	if len(chunks) != 2 || chunks[0].PageNumber != 1 || chunks[1].Text != "Second." || chunks[1].PageNumber != 2 {
+		t.Fatalf("expected page markers to be honored, got %+v", chunks)
+	}
+}
+
+func TestBuildChunksFixedWithOverlap(t *testing.T) {
+	text := strings.Repeat("naïve word ", 100)
+	opts := ChunkOptions{Strategy: ChunkFixed, Unit: ChunkUnitChars, TargetSize: 150, Overlap: 30}
+	chunks := BuildChunks(text, nil, opts)
+	checkChunkOffsets(t, text, chunks)
+
+	if len(chunks) < 7 {
+		t.Fatalf("expected at least 7 chunks, got %d", len(chunks))
+	}
+	for i := 1; i < len(chunks); i++ {
+		if chunks[i].StartChar >= chunks[i-1].EndChar {
+			t.Fatalf("chunk %d does not overlap the previous one: %d >= %d", i, chunks[i].StartChar, chunks[i-1].EndChar)
+		}
+		if strings.HasPrefix(chunks[i].Text, "ve ") || strings.HasPrefix(chunks[i].Text, "ord") {
+			t.Fatalf("chunk %d starts mid-word: %q", i, chunks[i].Text)
+		}
+	}
+}
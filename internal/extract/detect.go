@@ -0,0 +1,70 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Detection is how a file would be routed, without extracting it.
+type Detection struct {
+	Success           bool    `json:"success"`
+	FileType          string  `json:"fileType"` // extractor name, "unknown" when none applies
+	DetectedMIMEType  string  `json:"detectedMimeType"`
+	DeclaredExtension string  `json:"declaredExtension"`
+	ResolutionReason  string  `json:"resolutionReason,omitempty"`
+	Size              int64   `json:"size"`
+	SHA256            string  `json:"sha256,omitempty"`
+	Error             *string `json:"error,omitempty"`
+}
+
+// Detect downloads the file and classifies it.
+func (r *Router) Detect(ctx context.Context, req UniversalExtractRequest) (Detection, error) {
+	if strings.TrimSpace(req.PresignedURL) == "" {
+		msg := "presignedUrl required"
+		return Detection{FileType: "unknown", Error: &msg}, errors.New(msg)
+	}
+	fileName := strings.TrimSpace(req.FileName)
+	if fileName == "" {
+		fileName = "input.bin"
+	}
+	dl, err := DownloadToTemp(ctx, req.PresignedURL, fileName, r.maxFileBytes, r.downloadTimeout)
+	if err != nil {
+		msg := err.Error()
+		return Detection{FileType: "unknown", Error: &msg}, err
+	}
+	defer dl.Cleanup()
+	return r.DetectFile(ctx, dl, fileName)
+}
+
+// DetectFile classifies a file that is already on disk. The caller owns dl.
+// A file too large for its extractor is reported as a failure, since
+// extracting it would fail the same way.
+func (r *Router) DetectFile(ctx context.Context, dl DownloadedFile, fileName string) (Detection, error) {
+	ext := strings.ToLower(filepath.Ext(strings.TrimSpace(fileName)))
+	resolution, err := r.registry.ClassifyContext(ctx, dl.MIMEType, ext)
+	d := Detection{
+		FileType:          "unknown",
+		DetectedMIMEType:  resolution.DetectedMIMEType,
+		DeclaredExtension: resolution.DeclaredExtension,
+		ResolutionReason:  resolution.Reason,
+		Size:              dl.Size,
+		SHA256:            dl.SHA256,
+	}
+	if err != nil {
+		msg := err.Error()
+		d.Error = &msg
+		return d, err
+	}
+	extractor := resolution.Extractor
+	d.FileType = extractor.Name()
+	if max := extractor.MaxFileSize(); max > 0 && dl.Size > max {
+		msg := fmt.Sprintf("file exceeds extractor limit (%dMB)", max/(1<<20))
+		d.Error = &msg
+		return d, errors.New(msg)
+	}
+	d.Success = true
+	return d, nil
+}
@@ -2,6 +2,8 @@ package extract
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net"
@@ -13,6 +15,8 @@ import (
 	"time"
 
 	"github.com/gabriel-vasile/mimetype"
+	"github.com/toricodesthings/file-processing-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type DownloadedFile struct {
@@ -20,6 +24,7 @@ type DownloadedFile struct {
 	Path     string
 	MIMEType string
 	Size     int64
+	SHA256   string // hex digest of the file contents, computed while streaming
 }
 
 func (d DownloadedFile) Cleanup() {
@@ -29,6 +34,16 @@ func (d DownloadedFile) Cleanup() {
 }
 
 func DownloadToTemp(ctx context.Context, url string, fileName string, maxBytes int64, timeout time.Duration) (DownloadedFile, error) {
+	ctx, span := tracing.StartClient(ctx, "DownloadToTemp")
+	dl, err := downloadToTemp(ctx, url, fileName, maxBytes, timeout)
+	if err == nil {
+		span.SetAttributes(attribute.Int64("file.size", dl.Size), attribute.String("file.mime_type", dl.MIMEType))
+	}
+	tracing.End(span, err)
+	return dl, err
+}
+
+func downloadToTemp(ctx context.Context, url string, fileName string, maxBytes int64, timeout time.Duration) (DownloadedFile, error) {
 	if err := validateDownloadURL(url); err != nil {
 		return DownloadedFile{}, err
 	}
@@ -67,8 +82,9 @@ func DownloadToTemp(ctx context.Context, url string, fileName string, maxBytes i
 	}
 	defer f.Close()
 
+	hash := sha256.New()
 	lr := &io.LimitedReader{R: resp.Body, N: maxBytes + 1}
-	n, err := io.Copy(f, lr)
+	n, err := io.Copy(io.MultiWriter(f, hash), lr)
 	if err != nil {
 		_ = os.RemoveAll(tmpDir)
 		return DownloadedFile{}, fmt.Errorf("write: %w", err)
@@ -96,6 +112,7 @@ func DownloadToTemp(ctx context.Context, url string, fileName string, maxBytes i
 		Path:     outPath,
 		MIMEType: mt,
 		Size:     n,
+		SHA256:   hex.EncodeToString(hash.Sum(nil)),
 	}, nil
 }
 
@@ -121,8 +138,9 @@ func SaveBodyToTemp(body io.Reader, fileName string, maxBytes int64) (Downloaded
 	}
 	defer f.Close()
 
+	hash := sha256.New()
 	lr := &io.LimitedReader{R: body, N: maxBytes + 1}
-	n, err := io.Copy(f, lr)
+	n, err := io.Copy(io.MultiWriter(f, hash), lr)
 	if err != nil {
 		_ = os.RemoveAll(tmpDir)
 		return DownloadedFile{}, fmt.Errorf("write: %w", err)
@@ -143,6 +161,7 @@ func SaveBodyToTemp(body io.Reader, fileName string, maxBytes int64) (Downloaded
 		Path:     outPath,
 		MIMEType: mt,
 		Size:     n,
+		SHA256:   hex.EncodeToString(hash.Sum(nil)),
 	}, nil
 }
 
@@ -208,6 +227,12 @@ func isPrivateOrLocalIP(ip net.IP) bool {
 	return false
 }
 
+// SniffMIMEType detects a local file's MIME type from its content. Extractors
+// that unpack container formats use it to resolve inner entries.
+func SniffMIMEType(path string) string {
+	return sniffMIMEType(path)
+}
+
 func sniffMIMEType(path string) string {
 	m, err := mimetype.DetectFile(path)
 	if err == nil && m != nil {
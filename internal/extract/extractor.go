@@ -1,6 +1,11 @@
 package extract
 
-import "context"
+import (
+	"context"
+
+	"github.com/toricodesthings/file-processing-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
 
 // Extractor is implemented by every file-type handler.
 type Extractor interface {
@@ -10,3 +15,19 @@ type Extractor interface {
 	Name() string
 	MaxFileSize() int64
 }
+
+// RunExtractor calls e.Extract inside a trace span. Callers that pick an
+// extractor from a Registry use it so nested extractions show up per file.
+func RunExtractor(ctx context.Context, e Extractor, job Job) (Result, error) {
+	ctx, span := tracing.Start(ctx, "Extractor.Extract",
+		attribute.String("extractor.name", e.Name()),
+		attribute.String("file.mime_type", job.MIMEType),
+		attribute.Int64("file.size", job.FileSize),
+	)
+	res, err := e.Extract(ctx, job)
+	if res.Method != "" {
+		span.SetAttributes(attribute.String("extract.method", res.Method))
+	}
+	tracing.End(span, err)
+	return res, err
+}
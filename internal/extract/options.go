@@ -0,0 +1,165 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OptionError reports a request option that is present but has an invalid
+// type or value. Callers surface it as a 400 with the message unchanged.
+type OptionError struct {
+	Key    string
+	Reason string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("invalid option %q: %s", e.Key, e.Reason)
+}
+
+// IntOption reads an integer option. ok is false when the key is absent or null.
+// JSON numbers arrive as float64, so fractional values are rejected explicitly.
+func IntOption(options map[string]any, key string) (value int, ok bool, err error) {
+	v, present := lookupOption(options, key)
+	if !present {
+		return 0, false, nil
+	}
+	if f32, isF32 := v.(float32); isF32 {
+		v = float64(f32)
+	}
+	switch n := v.(type) {
+	case float64:
+		if n != math.Trunc(n) || math.IsInf(n, 0) || math.IsNaN(n) {
+			return 0, false, &OptionError{Key: key, Reason: "must be an integer"}
+		}
+		return int(n), true, nil
+	case int:
+		return n, true, nil
+	case int64:
+		return int(n), true, nil
+	case json.Number:
+		i, err := n.Int64()
+		if err != nil {
+			return 0, false, &OptionError{Key: key, Reason: "must be an integer"}
+		}
+		return int(i), true, nil
+	case string:
+		i, err := strconv.Atoi(strings.TrimSpace(n))
+		if err != nil {
+			return 0, false, &OptionError{Key: key, Reason: "must be an integer"}
+		}
+		return i, true, nil
+	}
+	return 0, false, &OptionError{Key: key, Reason: "must be an integer"}
+}
+
+// FloatOption reads a numeric option. ok is false when the key is absent or null.
+func FloatOption(options map[string]any, key string) (value float64, ok bool, err error) {
+	v, present := lookupOption(options, key)
+	if !present {
+		return 0, false, nil
+	}
+	var f float64
+	switch n := v.(type) {
+	case float64:
+		f = n
+	case float32:
+		f = float64(n)
+	case int:
+		f = float64(n)
+	case int64:
+		f = float64(n)
+	case json.Number:
+		parsed, err := n.Float64()
+		if err != nil {
+			return 0, false, &OptionError{Key: key, Reason: "must be a number"}
+		}
+		f = parsed
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil {
+			return 0, false, &OptionError{Key: key, Reason: "must be a number"}
+		}
+		f = parsed
+	default:
+		return 0, false, &OptionError{Key: key, Reason: "must be a number"}
+	}
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, false, &OptionError{Key: key, Reason: "must be a finite number"}
+	}
+	return f, true, nil
+}
+
+// BoolOption reads a boolean option. ok is false when the key is absent or null.
+func BoolOption(options map[string]any, key string) (value bool, ok bool, err error) {
+	v, present := lookupOption(options, key)
+	if !present {
+		return false, false, nil
+	}
+	switch b := v.(type) {
+	case bool:
+		return b, true, nil
+	case string:
+		parsed, err := strconv.ParseBool(strings.TrimSpace(b))
+		if err != nil {
+			return false, false, &OptionError{Key: key, Reason: "must be a boolean"}
+		}
+		return parsed, true, nil
+	}
+	return false, false, &OptionError{Key: key, Reason: "must be a boolean"}
+}
+
+// StringOption reads a string option. ok is false when the key is absent or null.
+// The value is returned as sent; callers decide whether to trim.
+func StringOption(options map[string]any, key string) (value string, ok bool, err error) {
+	v, present := lookupOption(options, key)
+	if !present {
+		return "", false, nil
+	}
+	s, isString := v.(string)
+	if !isString {
+		return "", false, &OptionError{Key: key, Reason: "must be a string"}
+	}
+	return s, true, nil
+}
+
+// ChoiceOption reads a string option that must be one of choices, matched
+// case-insensitively. It returns choices[0] when the key is absent or blank.
+func ChoiceOption(options map[string]any, key string, choices []string) (string, error) {
+	v, _, err := StringOption(options, key)
+	if err != nil {
+		return "", err
+	}
+	v = strings.ToLower(strings.TrimSpace(v))
+	if v == "" {
+		return choices[0], nil
+	}
+	if !slices.Contains(choices, v) {
+		return "", &OptionError{Key: key, Reason: "must be one of " + strings.Join(choices, ", ")}
+	}
+	return v, nil
+}
+
+// withOption returns a copy of options with key set to value.
+func withOption(options map[string]any, key string, value any) map[string]any {
+	out := make(map[string]any, len(options)+1)
+	for k, v := range options {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
+
+func lookupOption(options map[string]any, key string) (any, bool) {
+	if options == nil {
+		return nil, false
+	}
+	v, ok := options[key]
+	if !ok || v == nil {
+		return nil, false
+	}
+	return v, true
+}
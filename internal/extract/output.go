@@ -0,0 +1,35 @@
+package extract
+
+import "github.com/toricodesthings/file-processing-service/internal/format"
+
+// ParseOutputFormat reads the "outputFormat" option, which picks the format
+// of Text and page text. It defaults to markdown.
+func ParseOutputFormat(options map[string]any) (string, error) {
+	return ChoiceOption(options, "outputFormat", format.OutputFormats)
+}
+
+// applyOutputFormat renders a result in outputFormat, the same way for every
+// extractor. Text is rendered from the result's blocks, so extractors that
+// build their own keep their structure; pages are rendered from their
+// markdown. The blocks are kept, since the rendered text no longer parses.
+func applyOutputFormat(res *Result, outputFormat string) {
+	if outputFormat == format.OutputMarkdown {
+		return
+	}
+	res.Blocks = ResultBlocks(*res)
+	res.Text = format.RenderBlocks(res.Blocks, outputFormat)
+	for i := range res.Pages {
+		res.Pages[i].Text = format.Render(res.Pages[i].Text, outputFormat)
+	}
+}
+
+// outputFormatEmitter wraps emit to render streamed pages in outputFormat.
+func outputFormatEmitter(outputFormat string, emit func(PageResult)) func(PageResult) {
+	if outputFormat == format.OutputMarkdown {
+		return emit
+	}
+	return func(p PageResult) {
+		p.Text = format.Render(p.Text, outputFormat)
+		emit(p)
+	}
+}
@@ -0,0 +1,209 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/budget"
+	"github.com/toricodesthings/file-processing-service/internal/format"
+	"github.com/toricodesthings/file-processing-service/internal/metrics"
+	"github.com/toricodesthings/file-processing-service/internal/tracing"
+	"github.com/toricodesthings/file-processing-service/internal/usage"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PageStreamer is implemented by extractors that can hand out pages one at a
+// time instead of buffering the whole document. The returned Result carries
+// counts and metadata but no Text or Pages.
+type PageStreamer interface {
+	ExtractPages(ctx context.Context, job Job, emit func(PageResult)) (Result, error)
+}
+
+// StreamPages downloads the file and delivers it page by page to emit. It
+// returns the summary Result once every page has been emitted.
+func (r *Router) StreamPages(ctx context.Context, req UniversalExtractRequest, emit func(PageResult)) (Result, error) {
+	start := time.Now()
+
+	if strings.TrimSpace(req.PresignedURL) == "" {
+		return errResult("presignedUrl required"), fmt.Errorf("presignedUrl required")
+	}
+	fileName := strings.TrimSpace(req.FileName)
+	if fileName == "" {
+		fileName = "input.bin"
+	}
+	if err := rejectWholeResultOptions(req.Options); err != nil {
+		return errResult(err.Error()), err
+	}
+	if _, err := ParseTableFormat(req.Options); err != nil {
+		return errResult(err.Error()), err
+	}
+	if _, err := ParseOutputFormat(req.Options); err != nil {
+		return errResult(err.Error()), err
+	}
+
+	dl, err := DownloadToTemp(ctx, req.PresignedURL, fileName, r.maxFileBytes, r.downloadTimeout)
+	if err != nil {
+		return errResult(err.Error()), err
+	}
+	defer dl.Cleanup()
+
+	return r.streamFile(ctx, dl, req.PresignedURL, fileName, req.Options, start, emit)
+}
+
+// StreamFilePages is StreamPages for a file that is already on disk. The
+// caller owns dl and cleans it up.
+func (r *Router) StreamFilePages(ctx context.Context, dl DownloadedFile, fileName string, options map[string]any, emit func(PageResult)) (Result, error) {
+	fileName = strings.TrimSpace(fileName)
+	if fileName == "" {
+		fileName = "input.bin"
+	}
+	if err := rejectWholeResultOptions(options); err != nil {
+		return errResult(err.Error()), err
+	}
+	return r.streamFile(ctx, dl, "", fileName, options, time.Now(), emit)
+}
+
+// rejectWholeResultOptions refuses chunk and block options: both are built
+// from the combined result, which a page stream never holds.
+func rejectWholeResultOptions(options map[string]any) error {
+	chunking, err := ParseChunkOptions(options)
+	if err != nil {
+		return err
+	}
+	if chunking != nil {
+		return &OptionError{Key: "chunking", Reason: "not available when streaming pages"}
+	}
+	blocks, err := ParseBlocksOption(options)
+	if err != nil {
+		return err
+	}
+	if blocks {
+		return &OptionError{Key: "blocks", Reason: "not available when streaming pages"}
+	}
+	return nil
+}
+
+// streamFile mirrors extractFile without the result cache, which needs the
+// whole result. Extractors that are not PageStreamers run normally and their
+// pages, or their whole text as page 1, are emitted afterwards.
+func (r *Router) streamFile(ctx context.Context, dl DownloadedFile, presignedURL, fileName string, options map[string]any, start time.Time, emit func(PageResult)) (Result, error) {
+	tableFormat, err := ParseTableFormat(options)
+	if err != nil {
+		return errResult(err.Error()), err
+	}
+	outputFormat, err := ParseOutputFormat(options)
+	if err != nil {
+		return errResult(err.Error()), err
+	}
+	if outputFormat != format.OutputMarkdown && tableFormat != format.TableMarkdown {
+		tableFormat = format.TableMarkdown
+		options = withOption(options, "tableFormat", tableFormat)
+	}
+	emit = outputFormatEmitter(outputFormat, emit)
+
+	meter := usage.NewMeter()
+	ctx = usage.WithMeter(ctx, meter)
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	resolution, err := r.registry.ClassifyContext(ctx, dl.MIMEType, ext)
+	if err != nil {
+		msg := err.Error()
+		res := Result{Success: false, MIMEType: dl.MIMEType, FileType: "unknown", Error: &msg}
+		res.setResolution(resolution)
+		return res, err
+	}
+	chain, err := fitChain(resolution.Chain(), dl.Size)
+	if err != nil {
+		msg := err.Error()
+		res := Result{Success: false, MIMEType: dl.MIMEType, FileType: resolution.Extractor.Name(), Error: &msg}
+		res.setResolution(resolution)
+		return res, err
+	}
+	extractor := chain[0]
+
+	job := Job{
+		PresignedURL: presignedURL,
+		LocalPath:    dl.Path,
+		FileName:     fileName,
+		MIMEType:     dl.MIMEType,
+		FileSize:     dl.Size,
+		FileSHA256:   dl.SHA256,
+		Options:      options,
+	}
+
+	var res Result
+	if ps, ok := extractor.(PageStreamer); ok {
+		// Pages already emitted cannot be taken back, so there is no fallback.
+		extractStart := time.Now()
+		res, err = runPageStreamer(ctx, extractor.Name(), ps, job, tableFormatEmitter(extractor, tableFormat, emit))
+		fileType := res.FileType
+		if strings.TrimSpace(fileType) == "" {
+			fileType = extractor.Name()
+		}
+		metrics.ObserveExtraction(fileType, res.Method, dl.Size, time.Since(extractStart), err)
+	} else {
+		res, extractor, err = runChain(ctx, chain, job)
+		if err == nil {
+			emitWholeResult(res, tableFormatEmitter(extractor, tableFormat, emit))
+		}
+		res.Text, res.Pages, res.Blocks, res.Tables = "", nil, nil, nil
+	}
+	res.setResolution(resolution)
+	res.Usage = meter.Summary()
+	if res.Usage != nil {
+		budget.Charge(ctx, res.Usage.EstimatedCostUSD)
+	}
+
+	if strings.TrimSpace(res.FileType) == "" {
+		res.FileType = extractor.Name()
+	}
+	if res.MIMEType == "" {
+		res.MIMEType = dl.MIMEType
+	}
+	if err != nil {
+		if res.Error == nil {
+			msg := err.Error()
+			res.Error = &msg
+		}
+		res.Success = false
+		return res, err
+	}
+	res.Success = true
+	if r.successHook != nil {
+		r.successHook(res.FileType, dl.Size, time.Since(start))
+	}
+	return res, nil
+}
+
+func runPageStreamer(ctx context.Context, name string, ps PageStreamer, job Job, emit func(PageResult)) (Result, error) {
+	ctx, span := tracing.Start(ctx, "Extractor.ExtractPages",
+		attribute.String("extractor.name", name),
+		attribute.String("file.mime_type", job.MIMEType),
+		attribute.Int64("file.size", job.FileSize),
+	)
+	res, err := ps.ExtractPages(ctx, job, emit)
+	tracing.End(span, err)
+	return res, err
+}
+
+// emitWholeResult streams a buffered result: its pages when it has them,
+// otherwise its text as a single page.
+func emitWholeResult(res Result, emit func(PageResult)) {
+	if len(res.Pages) > 0 {
+		for _, p := range res.Pages {
+			emit(p)
+		}
+		return
+	}
+	if res.Text == "" {
+		return
+	}
+	words := res.WordCount
+	if words == 0 {
+		words, _ = BuildCounts(res.Text)
+	}
+	emit(PageResult{PageNumber: 1, Text: res.Text, Method: res.Method, WordCount: words})
+}
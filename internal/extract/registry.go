@@ -1,63 +1,230 @@
 package extract
 
 import (
+	"context"
 	"fmt"
 	"strings"
+
+	"github.com/toricodesthings/file-processing-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Registration priorities. Each MIME type and extension keeps its
+// extractors ordered by priority; the router tries them in turn until one
+// succeeds.
+const (
+	PriorityNative   = 100 // Register
+	PriorityFallback = 50  // slower or lossier converters tried after a native failure
 )
 
 type Registry struct {
-	byMIME      map[string]Extractor
-	byExtension map[string]Extractor
-	extractors  []Extractor
+	byMIME      map[string][]candidate
+	byExtension map[string][]candidate
+	extractors  []candidate // registration order
+}
+
+type candidate struct {
+	extractor Extractor
+	priority  int
 }
 
 func NewRegistry() *Registry {
 	return &Registry{
-		byMIME:      make(map[string]Extractor),
-		byExtension: make(map[string]Extractor),
-		extractors:  make([]Extractor, 0),
+		byMIME:      make(map[string][]candidate),
+		byExtension: make(map[string][]candidate),
+		extractors:  make([]candidate, 0),
 	}
 }
 
+// Register adds e at PriorityNative.
 func (r *Registry) Register(e Extractor) {
-	r.extractors = append(r.extractors, e)
+	r.RegisterPriority(e, PriorityNative)
+}
+
+// RegisterPriority adds e as a candidate for its types and extensions.
+// Higher priorities are tried first; among equal priorities the most
+// recently registered extractor goes first.
+func (r *Registry) RegisterPriority(e Extractor, priority int) {
+	r.extractors = append(r.extractors, candidate{e, priority})
 	for _, mt := range e.SupportedTypes() {
 		key := strings.ToLower(strings.TrimSpace(mt))
 		if key != "" {
-			r.byMIME[key] = e
+			r.byMIME[key] = insertCandidate(r.byMIME[key], candidate{e, priority})
 		}
 	}
 	for _, ext := range e.SupportedExtensions() {
 		key := strings.ToLower(strings.TrimSpace(ext))
 		if key != "" {
-			r.byExtension[key] = e
+			r.byExtension[key] = insertCandidate(r.byExtension[key], candidate{e, priority})
 		}
 	}
 }
 
+func insertCandidate(list []candidate, c candidate) []candidate {
+	i := 0
+	for i < len(list) && list[i].priority > c.priority {
+		i++
+	}
+	return append(list[:i], append([]candidate{c}, list[i:]...)...)
+}
+
+func extractorsOf(list []candidate) []Extractor {
+	out := make([]Extractor, len(list))
+	for i, c := range list {
+		out[i] = c.extractor
+	}
+	return out
+}
+
+// Resolution reasons, reported to clients as resolutionReason.
+const (
+	ResolvedByExtension       = "extension"        // the extension's extractor; content agrees or is inconclusive
+	ResolvedByContent         = "content"          // no extractor claims the extension
+	ResolvedByContentMismatch = "content-mismatch" // content contradicts the extension and wins
+	ResolvedByTextFallback    = "text-fallback"    // unknown text/* type read as plain text
+)
+
+// Resolution is how a file was matched to an extractor.
+type Resolution struct {
+	Extractor         Extractor
+	Fallbacks         []Extractor // lower-priority candidates for the same match, in order
+	DetectedMIMEType  string      // sniffed from content
+	DeclaredExtension string      // from the file name
+	Reason            string
+}
+
+// Chain returns Extractor followed by its fallbacks.
+func (rs Resolution) Chain() []Extractor {
+	if rs.Extractor == nil {
+		return nil
+	}
+	return append([]Extractor{rs.Extractor}, rs.Fallbacks...)
+}
+
+func (rs *Resolution) use(list []candidate, reason string) {
+	chain := extractorsOf(list)
+	rs.Extractor, rs.Fallbacks, rs.Reason = chain[0], chain[1:], reason
+}
+
+// ResolveContext is Resolve recorded as a trace span.
+func (r *Registry) ResolveContext(ctx context.Context, mimeType, extension string) (Extractor, error) {
+	res, err := r.ClassifyContext(ctx, mimeType, extension)
+	return res.Extractor, err
+}
+
+// ClassifyContext is Classify recorded as a trace span.
+func (r *Registry) ClassifyContext(ctx context.Context, mimeType, extension string) (Resolution, error) {
+	_, span := tracing.Start(ctx, "Registry.Resolve",
+		attribute.String("file.mime_type", mimeType),
+		attribute.String("file.extension", extension),
+	)
+	res, err := r.Classify(mimeType, extension)
+	if err == nil {
+		span.SetAttributes(
+			attribute.String("extractor.name", res.Extractor.Name()),
+			attribute.String("extract.resolution", res.Reason),
+		)
+	}
+	tracing.End(span, err)
+	return res, err
+}
+
 func (r *Registry) Resolve(mimeType, extension string) (Extractor, error) {
+	res, err := r.Classify(mimeType, extension)
+	return res.Extractor, err
+}
+
+// Classify picks the extractor for a file from its sniffed MIME type and its
+// extension. The extension normally wins, since it is more specific than
+// most sniffed types (".go" vs text/plain). When the two name different
+// extractors and the sniff is trustworthy — a binary signature that is not
+// a generic container, or text where the extension promises a binary format
+// — the content wins instead, so a PDF renamed .txt is still read as a PDF.
+func (r *Registry) Classify(mimeType, extension string) (Resolution, error) {
 	mt := strings.ToLower(strings.TrimSpace(mimeType))
 	ext := strings.ToLower(strings.TrimSpace(extension))
+	res := Resolution{DetectedMIMEType: mt, DeclaredExtension: ext}
+
+	byContent, ok := r.byMIME[mt]
+	if !ok {
+		if i := strings.Index(mt, ";"); i > 0 {
+			byContent = r.byMIME[strings.TrimSpace(mt[:i])]
+		}
+	}
 
-	if e, ok := r.byExtension[ext]; ok {
-		return e, nil
+	if byExt, ok := r.byExtension[ext]; ok {
+		e := byExt[0].extractor
+		if len(byContent) > 0 && byContent[0].extractor != e && contentOverrides(baseMIME(mt), e) {
+			res.use(byContent, ResolvedByContentMismatch)
+			return res, nil
+		}
+		res.use(byExt, ResolvedByExtension)
+		return res, nil
 	}
 
-	if e, ok := r.byMIME[mt]; ok {
-		return e, nil
+	if len(byContent) > 0 {
+		res.use(byContent, ResolvedByContent)
+		return res, nil
 	}
 
-	if i := strings.Index(mt, ";"); i > 0 {
-		if e, ok := r.byMIME[strings.TrimSpace(mt[:i])]; ok {
-			return e, nil
+	if strings.HasPrefix(mt, "text/") {
+		if list, ok := r.byMIME["text/plain"]; ok {
+			res.use(list, ResolvedByTextFallback)
+			return res, nil
 		}
 	}
 
-	if strings.HasPrefix(mt, "text/") {
-		if e, ok := r.byMIME["text/plain"]; ok {
-			return e, nil
+	return res, fmt.Errorf("no extractor registered for mime=%q extension=%q", mimeType, extension)
+}
+
+// genericContainers are sniffed types shared by many formats: a .docx or
+// .epub often sniffs as a plain zip, and .msg or .doc as a bare OLE file.
+var genericContainers = map[string]bool{
+	"application/octet-stream":  true,
+	"application/zip":           true,
+	"application/x-ole-storage": true,
+}
+
+// contentOverrides reports whether a sniffed type mt is strong enough to
+// overrule the extension's extractor e.
+func contentOverrides(mt string, e Extractor) bool {
+	if genericContainers[mt] {
+		return false
+	}
+	if !isTextMIME(mt) {
+		return true
+	}
+	// Text against a text extension is a matter of dialect (HTML inside
+	// Markdown, JSON in a notebook): the extension knows better.
+	return !readsText(e)
+}
+
+// readsText reports whether e only takes text formats. Extractors that list
+// no MIME types are matched by extension alone and are text-based.
+func readsText(e Extractor) bool {
+	for _, t := range e.SupportedTypes() {
+		if !isTextMIME(baseMIME(t)) {
+			return false
 		}
 	}
+	return true
+}
+
+func isTextMIME(mt string) bool {
+	if strings.HasPrefix(mt, "text/") || strings.HasSuffix(mt, "+json") || strings.HasSuffix(mt, "+xml") {
+		return true
+	}
+	switch mt {
+	case "application/json", "application/xml", "application/javascript", "application/x-yaml", "application/yaml",
+		"application/x-tex", "application/rtf", "application/mbox", "message/rfc822":
+		return true
+	}
+	return false
+}
 
-	return nil, fmt.Errorf("no extractor registered for mime=%q extension=%q", mimeType, extension)
+func baseMIME(mt string) string {
+	if i := strings.Index(mt, ";"); i >= 0 {
+		mt = mt[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mt))
 }
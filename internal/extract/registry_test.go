@@ -2,6 +2,7 @@ package extract
 
 import (
 	"context"
+	"strings"
 	"testing"
 )
 
@@ -32,3 +33,84 @@ func TestResolvePrefersExtension(t *testing.T) {
 		t.Fatalf("expected go-code extractor, got %q", e.Name())
 	}
 }
+
+func classifyRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(&stubExtractor{name: "pdf", mts: []string{"application/pdf"}, exts: []string{".pdf"}})
+	r.Register(&stubExtractor{name: "text", mts: []string{"text/plain"}, exts: []string{".txt", ".md"}})
+	r.Register(&stubExtractor{name: "html", mts: []string{"text/html"}, exts: []string{".html"}})
+	r.Register(&stubExtractor{name: "docx", mts: []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"}, exts: []string{".docx"}})
+	r.Register(&stubExtractor{name: "archive", mts: []string{"application/zip"}, exts: []string{".zip"}})
+	return r
+}
+
+func TestClassifyResolvesMismatches(t *testing.T) {
+	r := classifyRegistry()
+	cases := []struct {
+		mime, ext, want, reason string
+	}{
+		{"application/pdf", ".txt", "pdf", ResolvedByContentMismatch},           // PDF renamed .txt
+		{"text/html; charset=utf-8", ".pdf", "html", ResolvedByContentMismatch}, // HTML saved as .pdf
+		{"text/html; charset=utf-8", ".md", "text", ResolvedByExtension},        // Markdown with inline HTML
+		{"application/zip", ".docx", "docx", ResolvedByExtension},               // DOCX sniffed as a bare zip
+		{"application/octet-stream", ".pdf", "pdf", ResolvedByExtension},
+		{"application/pdf", ".pdf", "pdf", ResolvedByExtension},
+		{"application/pdf", ".bin", "pdf", ResolvedByContent},
+		{"text/x-unknown", "", "text", ResolvedByTextFallback},
+	}
+	for _, tc := range cases {
+		res, err := r.Classify(tc.mime, tc.ext)
+		if err != nil {
+			t.Fatalf("Classify(%q, %q): %v", tc.mime, tc.ext, err)
+		}
+		if res.Extractor.Name() != tc.want || res.Reason != tc.reason {
+			t.Fatalf("Classify(%q, %q) = %s (%s), want %s (%s)", tc.mime, tc.ext, res.Extractor.Name(), res.Reason, tc.want, tc.reason)
+		}
+		if res.DeclaredExtension != tc.ext {
+			t.Fatalf("declared extension = %q, want %q", res.DeclaredExtension, tc.ext)
+		}
+	}
+
+	if _, err := r.Classify("application/x-unknown", ".xyz"); err == nil {
+		t.Fatal("expected an error for an unclaimed type and extension")
+	}
+}
+
+func TestDetectFileSniffsContent(t *testing.T) {
+	router := NewRouter(classifyRegistry(), 1<<20, 0)
+	dl, err := SaveBodyToTemp(strings.NewReader("%PDF-1.7\n1 0 obj\n<<>>\nendobj\n"), "report.txt", 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dl.Cleanup()
+
+	d, err := router.DetectFile(context.Background(), dl, "report.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Success || d.FileType != "pdf" || d.DetectedMIMEType != "application/pdf" ||
+		d.DeclaredExtension != ".txt" || d.ResolutionReason != ResolvedByContentMismatch {
+		t.Fatalf("unexpected detection %+v", d)
+	}
+}
+
+func TestRegisterOrdersCandidatesByPriority(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterPriority(&stubExtractor{name: "fallback", exts: []string{".docx"}}, PriorityFallback)
+	r.Register(&stubExtractor{name: "old-native", exts: []string{".docx"}})
+	r.Register(&stubExtractor{name: "native", exts: []string{".docx"}})
+
+	res, err := r.Classify("", ".docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range res.Chain() {
+		names = append(names, e.Name())
+	}
+	// Among equal priorities the latest registration goes first, as when
+	// Register used to overwrite.
+	if strings.Join(names, ",") != "native,old-native,fallback" {
+		t.Fatalf("chain = %v", names)
+	}
+}
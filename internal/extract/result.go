@@ -1,14 +1,28 @@
 package extract
 
+import (
+	"github.com/toricodesthings/file-processing-service/internal/types"
+	"github.com/toricodesthings/file-processing-service/internal/usage"
+)
+
 type Job struct {
 	PresignedURL string
 	LocalPath    string
 	FileName     string
 	MIMEType     string
 	FileSize     int64
+	FileSHA256   string // hex digest when the router hashed the file; may be empty
 	Options      map[string]any
+
+	// Progress receives events from extractors that report how far they
+	// got (PDF pages, OCR, transcription). It may be nil.
+	Progress types.ProgressFunc
 }
 
+// MetaWarning marks a successful but degraded result (for example, OCR failed
+// and only the text layer was returned). Such results are not cached.
+const MetaWarning = "warning"
+
 type Result struct {
 	Success   bool              `json:"success"`
 	Text      string            `json:"text"`
@@ -16,10 +30,43 @@ type Result struct {
 	FileType  string            `json:"fileType"`
 	MIMEType  string            `json:"mimeType"`
 	Pages     []PageResult      `json:"pages,omitempty"`
+	Chunks    []Chunk           `json:"chunks,omitempty"`
 	Metadata  map[string]string `json:"metadata,omitempty"`
 	WordCount int               `json:"wordCount"`
 	CharCount int               `json:"charCount"`
+	Usage     *usage.Summary    `json:"usage,omitempty"` // upstream usage and estimated cost of this request
 	Error     *string           `json:"error,omitempty"`
+
+	// How the router picked the extractor; see Registry.Classify.
+	DetectedMIMEType  string `json:"detectedMimeType,omitempty"`
+	DeclaredExtension string `json:"declaredExtension,omitempty"`
+	ResolutionReason  string `json:"resolutionReason,omitempty"`
+
+	// Attempts lists every extractor tried, in order, once the first one
+	// failed and a fallback ran. It is omitted when the first succeeded.
+	Attempts []Attempt `json:"attempts,omitempty"`
+
+	// Blocks is the document structure behind Text. Extractors fill it
+	// whenever they can; the router returns it only for the "blocks" option.
+	Blocks []types.Block `json:"blocks,omitempty"`
+
+	// Tables holds every table with its header rows and merged cells.
+	// Text renders them as the "tableFormat" option asks.
+	Tables []types.Table `json:"tables,omitempty"`
+}
+
+// Attempt is one extractor tried for a file. Error is empty for the one
+// that succeeded.
+type Attempt struct {
+	Extractor string `json:"extractor"`
+	Error     string `json:"error,omitempty"`
+}
+
+// setResolution records rs on the result.
+func (r *Result) setResolution(rs Resolution) {
+	r.DetectedMIMEType = rs.DetectedMIMEType
+	r.DeclaredExtension = rs.DeclaredExtension
+	r.ResolutionReason = rs.Reason
 }
 
 type PageResult struct {
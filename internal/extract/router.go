@@ -4,9 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/budget"
+	"github.com/toricodesthings/file-processing-service/internal/format"
+	"github.com/toricodesthings/file-processing-service/internal/metrics"
+	"github.com/toricodesthings/file-processing-service/internal/types"
+	"github.com/toricodesthings/file-processing-service/internal/usage"
 )
 
 type Router struct {
@@ -14,6 +21,7 @@ type Router struct {
 	maxFileBytes    int64
 	downloadTimeout time.Duration
 	successHook     func(fileType string, fileSize int64, duration time.Duration)
+	cache           ResultCache
 }
 
 func NewRouter(registry *Registry, maxFileBytes int64, downloadTimeout time.Duration) *Router {
@@ -24,7 +32,19 @@ func (r *Router) SetSuccessHook(hook func(fileType string, fileSize int64, durat
 	r.successHook = hook
 }
 
+// SetCache enables result caching. Cached results are keyed by file hash,
+// extractor and options; a nil cache disables it.
+func (r *Router) SetCache(cache ResultCache) {
+	r.cache = cache
+}
+
 func (r *Router) Extract(ctx context.Context, req UniversalExtractRequest) (Result, error) {
+	return r.ExtractWithProgress(ctx, req, nil)
+}
+
+// ExtractWithProgress is Extract that reports download completion and the
+// extractor's own progress events to progress.
+func (r *Router) ExtractWithProgress(ctx context.Context, req UniversalExtractRequest, progress types.ProgressFunc) (Result, error) {
 	start := time.Now()
 
 	if strings.TrimSpace(req.PresignedURL) == "" {
@@ -36,34 +56,136 @@ func (r *Router) Extract(ctx context.Context, req UniversalExtractRequest) (Resu
 		fileName = "input.bin"
 	}
 
+	// Reject bad router-level options before spending a download on them.
+	if _, err := ParseChunkOptions(req.Options); err != nil {
+		return errResult(err.Error()), err
+	}
+	if _, err := ParseBlocksOption(req.Options); err != nil {
+		return errResult(err.Error()), err
+	}
+	if _, err := ParseTableFormat(req.Options); err != nil {
+		return errResult(err.Error()), err
+	}
+	if _, err := ParseOutputFormat(req.Options); err != nil {
+		return errResult(err.Error()), err
+	}
+
 	dl, err := DownloadToTemp(ctx, req.PresignedURL, fileName, r.maxFileBytes, r.downloadTimeout)
 	if err != nil {
 		return errResult(err.Error()), err
 	}
 	defer dl.Cleanup()
 
+	return r.extractFile(ctx, dl, req.PresignedURL, fileName, req.Options, start, progress)
+}
+
+// ExtractFile runs extraction on a file that is already on disk, such as a
+// request body saved by SaveBodyToTemp. The caller owns dl and cleans it up.
+func (r *Router) ExtractFile(ctx context.Context, dl DownloadedFile, fileName string, options map[string]any) (Result, error) {
+	return r.ExtractFileWithProgress(ctx, dl, fileName, options, nil)
+}
+
+// ExtractFileWithProgress is ExtractFile with progress reporting.
+func (r *Router) ExtractFileWithProgress(ctx context.Context, dl DownloadedFile, fileName string, options map[string]any, progress types.ProgressFunc) (Result, error) {
+	fileName = strings.TrimSpace(fileName)
+	if fileName == "" {
+		fileName = "input.bin"
+	}
+	return r.extractFile(ctx, dl, "", fileName, options, time.Now(), progress)
+}
+
+func (r *Router) extractFile(ctx context.Context, dl DownloadedFile, presignedURL, fileName string, options map[string]any, start time.Time, progress types.ProgressFunc) (Result, error) {
+	chunking, err := ParseChunkOptions(options)
+	if err != nil {
+		return errResult(err.Error()), err
+	}
+	wantBlocks, err := ParseBlocksOption(options)
+	if err != nil {
+		return errResult(err.Error()), err
+	}
+	tableFormat, err := ParseTableFormat(options)
+	if err != nil {
+		return errResult(err.Error()), err
+	}
+	outputFormat, err := ParseOutputFormat(options)
+	if err != nil {
+		return errResult(err.Error()), err
+	}
+	if outputFormat != format.OutputMarkdown && tableFormat != format.TableMarkdown {
+		// Other outputs render tables from the blocks. The extractor and
+		// the cache key see the markdown tables that are actually stored.
+		tableFormat = format.TableMarkdown
+		options = withOption(options, "tableFormat", tableFormat)
+	}
+	useCache := r.cache != nil && dl.SHA256 != ""
+	if b, ok, err := BoolOption(options, "cache"); err != nil {
+		return errResult(err.Error()), err
+	} else if ok && !b {
+		useCache = false
+	}
+
+	// Upstream clients record billable usage into the meter; cache hits spend nothing.
+	meter := usage.NewMeter()
+	ctx = usage.WithMeter(ctx, meter)
+
 	ext := strings.ToLower(filepath.Ext(fileName))
-	extractor, err := r.registry.Resolve(dl.MIMEType, ext)
+	resolution, err := r.registry.ClassifyContext(ctx, dl.MIMEType, ext)
+	if err != nil {
+		msg := err.Error()
+		res := Result{Success: false, MIMEType: dl.MIMEType, FileType: "unknown", Error: &msg}
+		res.setResolution(resolution)
+		return res, err
+	}
+	chain, err := fitChain(resolution.Chain(), dl.Size)
 	if err != nil {
 		msg := err.Error()
-		return Result{Success: false, MIMEType: dl.MIMEType, FileType: "unknown", Error: &msg}, err
+		res := Result{Success: false, MIMEType: dl.MIMEType, FileType: resolution.Extractor.Name(), Error: &msg}
+		res.setResolution(resolution)
+		return res, err
 	}
+	extractor := chain[0]
+	progress.Report(types.ProgressEvent{Stage: types.ProgressDownloaded, Bytes: dl.Size, MIMEType: dl.MIMEType, FileType: extractor.Name()})
 
-	if max := extractor.MaxFileSize(); max > 0 && dl.Size > max {
-		msg := fmt.Sprintf("file exceeds extractor limit (%dMB)", max/(1<<20))
-		return Result{Success: false, MIMEType: dl.MIMEType, FileType: extractor.Name(), Error: &msg}, errors.New(msg)
+	var cacheKey string
+	if useCache {
+		if cacheKey, err = CacheKey(dl.SHA256, extractor, options); err != nil {
+			useCache = false
+		}
+	}
+	if useCache {
+		res, ok := r.cache.Get(ctx, cacheKey)
+		metrics.ObserveCache(metrics.CacheResult, ok)
+		if ok {
+			if res.Metadata == nil {
+				res.Metadata = map[string]string{}
+			}
+			res.Metadata["cached"] = "true"
+			res.setResolution(resolution)
+			shapeResult(&res, chunking, wantBlocks, outputFormat)
+			if r.successHook != nil {
+				r.successHook(res.FileType, dl.Size, time.Since(start))
+			}
+			return res, nil
+		}
 	}
 
 	job := Job{
-		PresignedURL: req.PresignedURL,
+		PresignedURL: presignedURL,
 		LocalPath:    dl.Path,
 		FileName:     fileName,
 		MIMEType:     dl.MIMEType,
 		FileSize:     dl.Size,
-		Options:      req.Options,
+		FileSHA256:   dl.SHA256,
+		Options:      options,
+		Progress:     progress,
 	}
 
-	res, err := extractor.Extract(ctx, job)
+	res, used, err := runChain(ctx, chain, job)
+	res.setResolution(resolution)
+	res.Usage = meter.Summary()
+	if res.Usage != nil {
+		budget.Charge(ctx, res.Usage.EstimatedCostUSD)
+	}
 	if err != nil {
 		if res.Error == nil {
 			msg := err.Error()
@@ -78,7 +200,7 @@ func (r *Router) Extract(ctx context.Context, req UniversalExtractRequest) (Resu
 
 	res.Success = true
 	if strings.TrimSpace(res.FileType) == "" {
-		res.FileType = extractor.Name()
+		res.FileType = used.Name()
 	}
 	if res.MIMEType == "" {
 		res.MIMEType = dl.MIMEType
@@ -86,16 +208,123 @@ func (r *Router) Extract(ctx context.Context, req UniversalExtractRequest) (Resu
 	if res.CharCount == 0 && res.Text != "" {
 		res.WordCount, res.CharCount = BuildCounts(res.Text)
 	}
+	res.Tables = ResultTables(res)
+	applyTableFormat(used, &res, tableFormat)
+	// The key names the first extractor in the chain, so a fallback's result
+	// would outlive a version bump of the extractor that produced it.
+	if useCache && used == extractor && res.Metadata[MetaWarning] == "" {
+		r.cache.Set(ctx, cacheKey, res)
+	}
+	shapeResult(&res, chunking, wantBlocks, outputFormat)
 	if r.successHook != nil {
 		r.successHook(res.FileType, dl.Size, time.Since(start))
 	}
 	return res, nil
 }
 
+// shapeResult applies the options that work on the cached result: the text
+// is rendered in outputFormat, chunks are cut from what was rendered so they
+// stay slices of Text, and blocks are dropped unless asked for.
+func shapeResult(res *Result, chunking *ChunkOptions, wantBlocks bool, outputFormat string) {
+	applyOutputFormat(res, outputFormat)
+	if chunking != nil {
+		res.Chunks = BuildChunks(res.Text, res.Pages, *chunking)
+	}
+	res.Blocks = selectBlocks(*res, wantBlocks)
+}
+
+// selectBlocks returns the blocks to send back: none unless they were asked
+// for, parsed from the text when the extractor built none.
+func selectBlocks(res Result, want bool) []types.Block {
+	if !want {
+		return nil
+	}
+	return ResultBlocks(res)
+}
+
+// fitChain drops the extractors whose size limit the file exceeds. It fails
+// when none is left, reporting the first extractor's limit.
+func fitChain(chain []Extractor, size int64) ([]Extractor, error) {
+	fit := make([]Extractor, 0, len(chain))
+	for _, e := range chain {
+		if max := e.MaxFileSize(); max > 0 && size > max {
+			continue
+		}
+		fit = append(fit, e)
+	}
+	if len(fit) == 0 {
+		max := chain[0].MaxFileSize()
+		return nil, fmt.Errorf("file exceeds extractor limit (%dMB)", max/(1<<20))
+	}
+	return fit, nil
+}
+
+// runChain runs the extractors in order until one succeeds and returns its
+// result along with the extractor used. When every extractor fails, the
+// first one's result and error are returned, since fallbacks are best
+// effort. Once a fallback has been tried, the result lists every attempt.
+func runChain(ctx context.Context, chain []Extractor, job Job) (Result, Extractor, error) {
+	var (
+		attempts []Attempt
+		first    Result
+		firstErr error
+	)
+	for i, e := range chain {
+		start := time.Now()
+		res, err := RunExtractor(ctx, e, job)
+		fileType := res.FileType
+		if strings.TrimSpace(fileType) == "" {
+			fileType = e.Name()
+		}
+		metrics.ObserveExtraction(fileType, res.Method, job.FileSize, time.Since(start), err)
+		if err == nil {
+			if len(attempts) > 0 {
+				res.Attempts = append(attempts, Attempt{Extractor: e.Name()})
+			}
+			return res, e, nil
+		}
+		attempts = append(attempts, Attempt{Extractor: e.Name(), Error: attemptError(err)})
+		if i == 0 {
+			first, firstErr = res, err
+		}
+		if !canFallBack(ctx, err) {
+			break
+		}
+	}
+	if len(attempts) > 1 {
+		first.Attempts = attempts
+	}
+	return first, chain[0], firstErr
+}
+
+// canFallBack reports whether another extractor might succeed where one
+// failed with err. Bad options and cancelled requests fail every extractor.
+func canFallBack(ctx context.Context, err error) bool {
+	var optErr *OptionError
+	return ctx.Err() == nil && !errors.As(err, &optErr)
+}
+
+// attemptError shortens err for an Attempt. Attempts are returned on
+// successful results too, which the server does not sanitize.
+func attemptError(err error) string {
+	msg := strings.ReplaceAll(err.Error(), os.TempDir(), "[tmp]")
+	if len(msg) > 300 {
+		msg = msg[:300] + "..."
+	}
+	return msg
+}
+
 type UniversalExtractRequest struct {
 	PresignedURL string         `json:"presignedUrl"`
 	FileName     string         `json:"fileName"`
 	Options      map[string]any `json:"options"`
+
+	// TenantID carries the paying tenant to async jobs, which run outside
+	// the request context. It is set by the server, never by clients.
+	TenantID string `json:"-"`
+	// TraceContext links a queued job to the trace of the request that
+	// submitted it (see tracing.Inject). Also server-set.
+	TraceContext map[string]string `json:"-"`
 }
 
 func errResult(message string) Result {
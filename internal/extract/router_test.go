@@ -0,0 +1,239 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+// failingExtractor fails every file with err.
+type failingExtractor struct {
+	stubExtractor
+	err error
+}
+
+func (f *failingExtractor) Extract(ctx context.Context, job Job) (Result, error) {
+	msg := f.err.Error()
+	return Result{Success: false, FileType: f.name, Error: &msg}, f.err
+}
+
+func saveTemp(t *testing.T, body, name string) DownloadedFile {
+	t.Helper()
+	dl, err := SaveBodyToTemp(strings.NewReader(body), name, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(dl.Cleanup)
+	return dl
+}
+
+func TestRouterFallsBackAndRecordsAttempts(t *testing.T) {
+	reg := NewRegistry()
+	reg.RegisterPriority(&stubExtractor{name: "converter", exts: []string{".doc"}}, PriorityFallback)
+	reg.Register(&failingExtractor{stubExtractor{name: "native", exts: []string{".doc"}}, errors.New("corrupt file")})
+
+	res, err := NewRouter(reg, 1<<20, 0).ExtractFile(context.Background(), saveTemp(t, "data", "a.doc"), "a.doc", nil)
+	if err != nil {
+		t.Fatalf("expected the fallback to succeed: %v", err)
+	}
+	if res.FileType != "converter" {
+		t.Fatalf("file type = %q, want converter", res.FileType)
+	}
+	want := []Attempt{{Extractor: "native", Error: "corrupt file"}, {Extractor: "converter"}}
+	if len(res.Attempts) != 2 || res.Attempts[0] != want[0] || res.Attempts[1] != want[1] {
+		t.Fatalf("attempts = %+v, want %+v", res.Attempts, want)
+	}
+}
+
+func TestRouterReportsFirstFailureWhenAllFail(t *testing.T) {
+	reg := NewRegistry()
+	reg.RegisterPriority(&failingExtractor{stubExtractor{name: "converter", exts: []string{".doc"}}, errors.New("not installed")}, PriorityFallback)
+	reg.Register(&failingExtractor{stubExtractor{name: "native", exts: []string{".doc"}}, errors.New("corrupt file")})
+
+	res, err := NewRouter(reg, 1<<20, 0).ExtractFile(context.Background(), saveTemp(t, "data", "a.doc"), "a.doc", nil)
+	if err == nil || err.Error() != "corrupt file" {
+		t.Fatalf("err = %v, want the native failure", err)
+	}
+	if res.Success || res.FileType != "native" || len(res.Attempts) != 2 || res.Attempts[1].Error != "not installed" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
+
+func TestRouterDoesNotFallBackOnOptionErrors(t *testing.T) {
+	reg := NewRegistry()
+	reg.RegisterPriority(&stubExtractor{name: "converter", exts: []string{".doc"}}, PriorityFallback)
+	reg.Register(&failingExtractor{stubExtractor{name: "native", exts: []string{".doc"}}, &OptionError{Key: "pages", Reason: "bad"}})
+
+	res, err := NewRouter(reg, 1<<20, 0).ExtractFile(context.Background(), saveTemp(t, "data", "a.doc"), "a.doc", nil)
+	if err == nil || res.FileType != "native" || res.Attempts != nil {
+		t.Fatalf("expected the option error without fallback, got %+v (%v)", res, err)
+	}
+}
+
+// pagedExtractor returns a two-page result without blocks of its own.
+type pagedExtractor struct{ stubExtractor }
+
+func (p *pagedExtractor) Extract(ctx context.Context, job Job) (Result, error) {
+	return Result{Success: true, Text: "# Intro\n\nHello\n\n- a\n- b", Pages: []PageResult{
+		{PageNumber: 1, Text: "# Intro\n\nHello"},
+		{PageNumber: 2, Text: "- a\n- b"},
+	}}, nil
+}
+
+func TestRouterReturnsBlocksOnlyWhenAsked(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(&pagedExtractor{stubExtractor{name: "paged", exts: []string{".pdf"}}})
+	router := NewRouter(reg, 1<<20, 0)
+
+	res, err := router.ExtractFile(context.Background(), saveTemp(t, "data", "a.pdf"), "a.pdf", nil)
+	if err != nil || res.Blocks != nil {
+		t.Fatalf("expected no blocks by default, got %+v (%v)", res.Blocks, err)
+	}
+
+	res, err = router.ExtractFile(context.Background(), saveTemp(t, "data", "a.pdf"), "a.pdf", map[string]any{"blocks": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		typ  string
+		page int
+	}{{types.BlockHeading, 1}, {types.BlockParagraph, 1}, {types.BlockPageBreak, 1}, {types.BlockListItem, 2}, {types.BlockListItem, 2}}
+	if len(res.Blocks) != len(want) {
+		t.Fatalf("blocks = %+v", res.Blocks)
+	}
+	for i, w := range want {
+		if b := res.Blocks[i]; b.Type != w.typ || b.Source.Page != w.page {
+			t.Fatalf("block %d = %+v, want %s on page %d", i, b, w.typ, w.page)
+		}
+	}
+
+	if _, err := router.ExtractFile(context.Background(), saveTemp(t, "data", "a.pdf"), "a.pdf", map[string]any{"blocks": "maybe"}); err == nil {
+		t.Fatal("expected an option error for a non-boolean blocks option")
+	}
+}
+
+// tableExtractor returns a markdown table on its second page, as OCR does.
+type tableExtractor struct{ stubExtractor }
+
+func (e *tableExtractor) Extract(ctx context.Context, job Job) (Result, error) {
+	table := "| Name | Score |\n| --- | --- |\n| Ada | 3 |"
+	return Result{Success: true, Text: "Intro\n\n" + table, Pages: []PageResult{
+		{PageNumber: 1, Text: "Intro"},
+		{PageNumber: 2, Text: table},
+	}}, nil
+}
+
+func TestRouterReturnsTablesAndRendersTableFormat(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(&tableExtractor{stubExtractor{name: "ocr", exts: []string{".pdf"}}})
+	router := NewRouter(reg, 1<<20, 0)
+
+	res, err := router.ExtractFile(context.Background(), saveTemp(t, "data", "a.pdf"), "a.pdf", map[string]any{"tableFormat": "csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Tables) != 1 || res.Tables[0].Source.Page != 2 || res.Tables[0].HeaderRows != 1 || res.Tables[0].Cells[1][0] != "Ada" {
+		t.Fatalf("tables = %+v", res.Tables)
+	}
+	want := "```csv\nName,Score\nAda,3\n```"
+	if res.Text != "Intro\n\n"+want || res.Pages[1].Text != want {
+		t.Fatalf("text = %q, pages = %+v", res.Text, res.Pages)
+	}
+
+	if _, err := router.ExtractFile(context.Background(), saveTemp(t, "data", "a.pdf"), "a.pdf", map[string]any{"tableFormat": "xml"}); err == nil {
+		t.Fatal("expected an option error for an unknown table format")
+	}
+}
+
+// markupExtractor returns markdown with inline markup and unsafe HTML.
+type markupExtractor struct{ stubExtractor }
+
+func (e *markupExtractor) Extract(ctx context.Context, job Job) (Result, error) {
+	return Result{Success: true, Text: "---\ntitle: T\n---\n\n# Notes\n\nSee **the** [docs](https://example.com) and [this](javascript:alert(1)) <script>x()</script>\n\n- one\n  - two\n\n| A | B |\n| --- | --- |\n| 1 | 2 |"}, nil
+}
+
+func TestRouterRendersOutputFormat(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(&markupExtractor{stubExtractor{name: "md", exts: []string{".md"}}})
+	router := NewRouter(reg, 1<<20, 0)
+	extract := func(outputFormat string) Result {
+		t.Helper()
+		res, err := router.ExtractFile(context.Background(), saveTemp(t, "data", "a.md"), "a.md", map[string]any{"outputFormat": outputFormat})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	if got, want := extract("text").Text, "Notes\n\nSee the docs and this x()\n\none\n  two\n\nA\tB\n1\t2"; got != want {
+		t.Fatalf("text = %q, want %q", got, want)
+	}
+
+	html := extract("html").Text
+	for _, want := range []string{
+		"<h1>Notes</h1>",
+		`<strong>the</strong> <a href="https://example.com">docs</a> and this &lt;script&gt;`,
+		"<ul>\n<li>one<ul>\n<li>two</li></ul>\n</li></ul>",
+		"<th>A</th>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("html = %q, missing %q", html, want)
+		}
+	}
+	if strings.Contains(html, "javascript:") || strings.Contains(html, "title: T") {
+		t.Fatalf("html kept unsafe link or frontmatter: %q", html)
+	}
+
+	ast := extract("json")
+	if !strings.HasPrefix(ast.Text, `{"type":"document","children":[{"type":"heading","text":"Notes","level":1`) || ast.Blocks != nil {
+		t.Fatalf("json = %q, blocks = %v", ast.Text, ast.Blocks)
+	}
+
+	if _, err := router.ExtractFile(context.Background(), saveTemp(t, "data", "a.md"), "a.md", map[string]any{"outputFormat": "pdf"}); err == nil {
+		t.Fatal("expected an option error for an unknown output format")
+	}
+}
+
+func TestRouterCachesTablesInTheRenderedTableFormat(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(&tableExtractor{stubExtractor{name: "ocr", exts: []string{".pdf"}}})
+	router := NewRouter(reg, 1<<20, 0)
+	router.SetCache(NewMemoryCache(10, time.Hour))
+
+	html, err := router.ExtractFile(context.Background(), saveTemp(t, "data", "a.pdf"), "a.pdf", map[string]any{"tableFormat": "csv", "outputFormat": "html"})
+	if err != nil || !strings.Contains(html.Text, "<th>Name</th>") {
+		t.Fatalf("html = %q (%v)", html.Text, err)
+	}
+
+	res, err := router.ExtractFile(context.Background(), saveTemp(t, "data", "a.pdf"), "a.pdf", map[string]any{"tableFormat": "csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Metadata["cached"] == "true" || !strings.Contains(res.Text, "```csv\nName,Score\nAda,3\n```") {
+		t.Fatalf("expected a fresh CSV rendering, got cached=%q text=%q", res.Metadata["cached"], res.Text)
+	}
+}
+
+func TestRouterChunksTheRenderedText(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(&markupExtractor{stubExtractor{name: "md", exts: []string{".md"}}})
+	router := NewRouter(reg, 1<<20, 0)
+
+	for _, outputFormat := range []string{"text", "html"} {
+		res, err := router.ExtractFile(context.Background(), saveTemp(t, "data", "a.md"), "a.md", map[string]any{
+			"outputFormat": outputFormat,
+			"chunking":     map[string]any{"strategy": "fixed", "unit": "chars", "targetSize": float64(128)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Chunks) == 0 || strings.Contains(res.Chunks[0].Text, "title: T") {
+			t.Fatalf("%s: expected chunks of the rendered text, got %+v", outputFormat, res.Chunks)
+		}
+		checkChunkOffsets(t, res.Text, res.Chunks)
+	}
+}
@@ -0,0 +1,68 @@
+package extract
+
+import (
+	"slices"
+
+	"github.com/toricodesthings/file-processing-service/internal/format"
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+// ParseTableFormat reads the "tableFormat" option, which picks how tables
+// are rendered in Text. It defaults to markdown.
+func ParseTableFormat(options map[string]any) (string, error) {
+	return ChoiceOption(options, "tableFormat", format.TableFormats)
+}
+
+// ResultTables returns res.Tables, or the tables found in the markdown and
+// HTML of extractors that don't build their own, such as OCR. Pages are
+// searched one by one so each table records its page.
+func ResultTables(res Result) []types.Table {
+	if res.Tables != nil {
+		return res.Tables
+	}
+	if len(res.Pages) == 0 {
+		return format.ParseTables(res.Text)
+	}
+	var tables []types.Table
+	for _, p := range res.Pages {
+		for _, t := range format.ParseTables(p.Text) {
+			t.Source.Page = p.PageNumber
+			tables = append(tables, t)
+		}
+	}
+	return tables
+}
+
+// rendersTables reports whether e applies the tableFormat option itself.
+// Other extractors' markdown tables are re-rendered by the router.
+func rendersTables(e Extractor) bool {
+	d, ok := e.(Describer)
+	return ok && slices.Contains(d.Capabilities().Options, "tableFormat")
+}
+
+// applyTableFormat renders the markdown tables in the text and pages of a
+// result from an extractor that doesn't apply tableFormat itself. Blocks
+// are parsed from the markdown first, since the rendered tables would no
+// longer read as tables.
+func applyTableFormat(e Extractor, res *Result, tableFormat string) {
+	if rendersTables(e) || tableFormat == format.TableMarkdown {
+		return
+	}
+	res.Blocks = ResultBlocks(*res)
+	res.Text = format.RenderTables(res.Text, tableFormat)
+	for i := range res.Pages {
+		res.Pages[i].Text = format.RenderTables(res.Pages[i].Text, tableFormat)
+	}
+}
+
+// tableFormatEmitter wraps emit to apply tableFormat to streamed pages, for
+// extractors that don't apply it themselves.
+func tableFormatEmitter(e Extractor, tableFormat string, emit func(PageResult)) func(PageResult) {
+	if rendersTables(e) || tableFormat == format.TableMarkdown {
+		return emit
+	}
+	return func(p PageResult) {
+		p.Text = format.RenderTables(p.Text, tableFormat)
+		emit(p)
+	}
+}
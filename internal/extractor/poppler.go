@@ -13,6 +13,9 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type ExtractorConfig struct {
@@ -96,7 +99,10 @@ func ValidatePDF(ctx context.Context, pdfPath string, cfg ExtractorConfig) error
 
 // TextForPage extracts text for one page using pdftotext.
 // Output is capped to maxPerPageBytes to avoid OOM.
-func TextForPage(ctx context.Context, pdfPath string, page int, cfg ExtractorConfig) (string, error) {
+func TextForPage(ctx context.Context, pdfPath string, page int, cfg ExtractorConfig) (text string, err error) {
+	ctx, span := tracing.Start(ctx, "TextForPage", attribute.Int("pdf.page", page))
+	defer func() { tracing.End(span, err) }()
+
 	cfg = cfg.withDefaults()
 
 	if page < 1 {
@@ -0,0 +1,466 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+// Expansion below this many bytes never trips the ratio check; small
+// text-heavy archives legitimately compress far better than MaxRatio.
+const ratioCheckFloor = 1 << 20
+
+// Limits bound how much work a single upload can cause. Entry and byte
+// budgets are shared across nested archives.
+type Limits struct {
+	MaxEntries    int
+	MaxTotalBytes int64   // total uncompressed bytes across all entries
+	MaxDepth      int     // 1 = no archives inside archives
+	MaxRatio      float64 // uncompressed / compressed upload size
+}
+
+type Extractor struct {
+	registry *extract.Registry
+	maxBytes int64
+	limits   Limits
+}
+
+// New returns an archive extractor that resolves entries through registry.
+// Register it in the same registry so nested archives recurse.
+func New(registry *extract.Registry, maxBytes int64, limits Limits) *Extractor {
+	if limits.MaxEntries <= 0 {
+		limits.MaxEntries = 1000
+	}
+	if limits.MaxTotalBytes <= 0 {
+		limits.MaxTotalBytes = 512 << 20
+	}
+	if limits.MaxDepth <= 0 {
+		limits.MaxDepth = 3
+	}
+	if limits.MaxRatio <= 0 {
+		limits.MaxRatio = 100
+	}
+	return &Extractor{registry: registry, maxBytes: maxBytes, limits: limits}
+}
+
+func (e *Extractor) Name() string       { return "archive" }
+func (e *Extractor) MaxFileSize() int64 { return e.maxBytes }
+func (e *Extractor) SupportedTypes() []string {
+	return []string{"application/zip", "application/x-zip-compressed", "application/x-tar", "application/gzip", "application/x-gzip"}
+}
+func (e *Extractor) SupportedExtensions() []string { return []string{".zip", ".tar", ".tgz", ".gz"} }
+
+type ctxKey struct{}
+
+// walkState travels through ctx so nested archives share one budget.
+type walkState struct {
+	depth           int
+	prefix          string
+	entries         int
+	bytes           int64
+	compressedBytes int64
+}
+
+var errEntryLimit = errors.New("entry limit reached")
+
+func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
+	select {
+	case <-ctx.Done():
+		return extract.Result{Success: false}, ctx.Err()
+	default:
+	}
+
+	fail := func(err error) (extract.Result, error) {
+		msg := err.Error()
+		return extract.Result{Success: false, FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
+	}
+
+	st, _ := ctx.Value(ctxKey{}).(*walkState)
+	if st == nil {
+		st = &walkState{compressedBytes: job.FileSize}
+	}
+	if st.depth >= e.limits.MaxDepth {
+		return fail(fmt.Errorf("archive nesting exceeds %d levels", e.limits.MaxDepth))
+	}
+	parentDepth, parentPrefix := st.depth, st.prefix
+	st.depth++
+	defer func() { st.depth, st.prefix = parentDepth, parentPrefix }()
+	ctx = context.WithValue(ctx, ctxKey{}, st)
+
+	workDir, err := os.MkdirTemp(filepath.Dir(job.LocalPath), "archive-*")
+	if err != nil {
+		return fail(fmt.Errorf("archive workdir: %w", err))
+	}
+	defer os.RemoveAll(workDir)
+
+	w := &walker{
+		e:       e,
+		ctx:     ctx,
+		state:   st,
+		prefix:  parentPrefix,
+		workDir: workDir,
+		meta:    map[string]string{},
+	}
+
+	format := detectFormat(job.FileName, job.MIMEType)
+	switch format {
+	case "zip":
+		err = w.walkZip(job.LocalPath)
+	case "tar", "tar.gz":
+		err = w.walkTarFile(job.LocalPath, format == "tar.gz")
+	case "gzip":
+		err = w.walkGzip(job.LocalPath, job.FileName)
+	default:
+		err = fmt.Errorf("unsupported archive format")
+	}
+	if errors.Is(err, errEntryLimit) {
+		w.meta["truncated"] = "true"
+		w.meta["truncatedReason"] = fmt.Sprintf("more than %d entries", e.limits.MaxEntries)
+		err = nil
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if w.extracted == 0 {
+		return fail(fmt.Errorf("archive contains no extractable entries"))
+	}
+
+	w.meta["format"] = format
+	w.meta["entries"] = strconv.Itoa(w.seen)
+	w.meta["extracted"] = strconv.Itoa(w.extracted)
+	w.meta["skipped"] = strconv.Itoa(w.skipped)
+	w.meta["failed"] = strconv.Itoa(w.failed)
+
+	text := strings.TrimSpace(strings.Join(w.sections, "\n\n---\n\n"))
+	words, chars := extract.BuildCounts(text)
+	return extract.Result{Success: true, Text: text, Method: "archive", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: w.meta, WordCount: words, CharCount: chars, Blocks: w.blocks, Tables: w.tables}, nil
+}
+
+func detectFormat(fileName, mimeType string) string {
+	name := strings.ToLower(fileName)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(name, ".tar"):
+		return "tar"
+	case strings.HasSuffix(name, ".zip"):
+		return "zip"
+	case strings.HasSuffix(name, ".gz"):
+		return "gzip"
+	}
+	switch strings.ToLower(mimeType) {
+	case "application/zip", "application/x-zip-compressed":
+		return "zip"
+	case "application/x-tar":
+		return "tar"
+	case "application/gzip", "application/x-gzip":
+		return "gzip"
+	}
+	return ""
+}
+
+type walker struct {
+	e       *Extractor
+	ctx     context.Context
+	state   *walkState
+	prefix  string
+	workDir string
+
+	sections []string
+	blocks   []types.Block
+	tables   []types.Table
+	meta     map[string]string
+
+	seen, extracted, skipped, failed int
+}
+
+func (w *walker) walkZip(p string) error {
+	zr, err := zip.OpenReader(p)
+	if err != nil {
+		return fmt.Errorf("open zip: %w", err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || f.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		if f.Flags&0x1 != 0 {
+			w.skip(f.Name, "encrypted")
+			continue
+		}
+		// Reject bombs from the declared sizes before inflating anything;
+		// the copy below still enforces the limits on actual bytes.
+		if remaining := w.e.limits.MaxTotalBytes - w.state.bytes; int64(f.UncompressedSize64) > remaining {
+			return fmt.Errorf("archive exceeds %dMB uncompressed limit", w.e.limits.MaxTotalBytes/(1<<20))
+		}
+		if f.CompressedSize64 > 0 && f.UncompressedSize64 > ratioCheckFloor &&
+			float64(f.UncompressedSize64)/float64(f.CompressedSize64) > w.e.limits.MaxRatio {
+			return fmt.Errorf("%s exceeds compression ratio limit", cleanName(f.Name))
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			w.fail(f.Name, err)
+			continue
+		}
+		err = w.handle(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *walker) walkTarFile(p string, gzipped bool) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return fmt.Errorf("open tar: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if gzipped {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("open gzip: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read tar: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Size > w.e.limits.MaxTotalBytes-w.state.bytes {
+			return fmt.Errorf("archive exceeds %dMB uncompressed limit", w.e.limits.MaxTotalBytes/(1<<20))
+		}
+		if err := w.handle(hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func (w *walker) walkGzip(p, fileName string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return fmt.Errorf("open gzip: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("open gzip: %w", err)
+	}
+	defer gz.Close()
+
+	inner := gz.Name
+	if inner == "" {
+		inner = strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	}
+	return w.handle(inner, gz)
+}
+
+// handle writes one entry to the work dir, extracts it through the registry
+// and removes it again. Only limit violations and cancellation are returned;
+// per-entry problems are recorded in metadata.
+func (w *walker) handle(rawName string, r io.Reader) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
+	name := cleanName(rawName)
+	if name == "" || isJunkEntry(name) {
+		return nil
+	}
+
+	if w.state.entries >= w.e.limits.MaxEntries {
+		return errEntryLimit
+	}
+	w.state.entries++
+	idx := w.seen
+	w.seen++
+	label := w.prefix + name
+	key := "entry." + strconv.Itoa(idx) + "."
+	w.meta[key+"path"] = label
+
+	entryDir := filepath.Join(w.workDir, strconv.Itoa(idx))
+	if err := os.Mkdir(entryDir, 0o700); err != nil {
+		return fmt.Errorf("entry dir: %w", err)
+	}
+	defer os.RemoveAll(entryDir)
+
+	// Entries are written under a fresh numbered dir using only their base
+	// name, so archive paths can never escape the work dir.
+	outPath := filepath.Join(entryDir, path.Base(name))
+	n, err := w.copyEntry(outPath, r)
+	if err != nil {
+		return err
+	}
+	w.meta[key+"bytes"] = strconv.FormatInt(n, 10)
+
+	mt := extract.SniffMIMEType(outPath)
+	ext := strings.ToLower(filepath.Ext(name))
+	extractor, err := w.e.registry.ResolveContext(w.ctx, mt, ext)
+	if err != nil {
+		w.skipped++
+		w.meta[key+"skipped"] = "unsupported type"
+		return nil
+	}
+	w.meta[key+"fileType"] = extractor.Name()
+	if max := extractor.MaxFileSize(); max > 0 && n > max {
+		w.skipped++
+		w.meta[key+"skipped"] = fmt.Sprintf("exceeds extractor limit (%dMB)", max/(1<<20))
+		return nil
+	}
+
+	w.state.prefix = label + "/"
+	res, err := extract.RunExtractor(w.ctx, extractor, extract.Job{
+		LocalPath: outPath,
+		FileName:  path.Base(name),
+		MIMEType:  mt,
+		FileSize:  n,
+	})
+	w.state.prefix = w.prefix
+	if err != nil {
+		if ctxErr := w.ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		w.failed++
+		msg := err.Error()
+		if res.Error != nil {
+			msg = *res.Error
+		}
+		w.meta[key+"error"] = msg
+		return nil
+	}
+
+	text := strings.TrimSpace(res.Text)
+	w.extracted++
+	w.meta[key+"method"] = res.Method
+	words, _ := extract.BuildCounts(text)
+	w.meta[key+"words"] = strconv.Itoa(words)
+	if text != "" {
+		w.sections = append(w.sections, "## "+label+"\n\n"+text)
+		w.addBlocks(label, extract.ResultBlocks(res))
+		for _, t := range extract.ResultTables(res) {
+			t.Source.Part = entryPart(label, t.Source.Part)
+			w.tables = append(w.tables, t)
+		}
+	}
+	return nil
+}
+
+// addBlocks appends an entry's blocks under a heading naming it, with each
+// source's part prefixed by the entry path. Blocks from nested archives
+// already carry full paths.
+func (w *walker) addBlocks(label string, blocks []types.Block) {
+	if len(w.blocks) > 0 {
+		w.blocks = append(w.blocks, types.Block{Type: types.BlockPageBreak})
+	}
+	w.blocks = append(w.blocks, types.Block{Type: types.BlockHeading, Level: 2, Text: label, Source: types.Source{Part: label}})
+	for _, b := range blocks {
+		w.blocks = append(w.blocks, entryBlock(label, b))
+	}
+}
+
+func entryBlock(label string, b types.Block) types.Block {
+	b.Source.Part = entryPart(label, b.Source.Part)
+	if len(b.Children) > 0 {
+		children := make([]types.Block, len(b.Children))
+		for i, c := range b.Children {
+			children[i] = entryBlock(label, c)
+		}
+		b.Children = children
+	}
+	return b
+}
+
+// entryPart prefixes a source part with the entry path.
+func entryPart(label, part string) string {
+	switch {
+	case part == "":
+		return label
+	case part != label && !strings.HasPrefix(part, label+"/"):
+		return label + "/" + part
+	}
+	return part
+}
+
+func (w *walker) copyEntry(outPath string, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(outPath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("create entry: %w", err)
+	}
+	defer f.Close()
+
+	remaining := w.e.limits.MaxTotalBytes - w.state.bytes
+	n, err := io.Copy(f, io.LimitReader(r, remaining+1))
+	w.state.bytes += n
+	if err != nil {
+		return n, fmt.Errorf("read entry: %w", err)
+	}
+	if n > remaining {
+		return n, fmt.Errorf("archive exceeds %dMB uncompressed limit", w.e.limits.MaxTotalBytes/(1<<20))
+	}
+	if w.state.bytes > ratioCheckFloor && w.state.compressedBytes > 0 &&
+		float64(w.state.bytes)/float64(w.state.compressedBytes) > w.e.limits.MaxRatio {
+		return n, fmt.Errorf("archive exceeds compression ratio limit (%.0f:1)", w.e.limits.MaxRatio)
+	}
+	return n, nil
+}
+
+func (w *walker) skip(rawName, reason string) {
+	name := cleanName(rawName)
+	if name == "" || isJunkEntry(name) {
+		return
+	}
+	key := "entry." + strconv.Itoa(w.seen) + "."
+	w.seen++
+	w.skipped++
+	w.meta[key+"path"] = w.prefix + name
+	w.meta[key+"skipped"] = reason
+}
+
+func (w *walker) fail(rawName string, err error) {
+	key := "entry." + strconv.Itoa(w.seen) + "."
+	w.seen++
+	w.failed++
+	w.meta[key+"path"] = w.prefix + cleanName(rawName)
+	w.meta[key+"error"] = err.Error()
+}
+
+// cleanName normalises an entry path for display: forward slashes, no
+// leading slash, no "..".
+func cleanName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = path.Clean("/" + name)
+	return strings.TrimPrefix(name, "/")
+}
+
+func isJunkEntry(name string) bool {
+	base := path.Base(name)
+	return strings.HasPrefix(name, "__MACOSX/") || base == ".DS_Store" || base == "Thumbs.db" || strings.HasPrefix(base, "._")
+}
@@ -0,0 +1,147 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/extractors/plaintext"
+)
+
+func buildZip(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create entry: %v", err)
+		}
+		if _, err := w.Write(content); err != nil {
+			t.Fatalf("write entry: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func newTestExtractor(limits Limits) *Extractor {
+	reg := extract.NewRegistry()
+	reg.Register(plaintext.New(1 << 20))
+	e := New(reg, 1<<30, limits)
+	reg.Register(e)
+	return e
+}
+
+func runArchive(t *testing.T, e *Extractor, name string, data []byte) (extract.Result, error) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, data, 0o600); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+	return e.Extract(context.Background(), extract.Job{LocalPath: p, FileName: name, FileSize: int64(len(data))})
+}
+
+func TestExtractZipWithNestedArchive(t *testing.T) {
+	inner := buildZip(t, map[string][]byte{"notes/inner.txt": []byte("inner words")})
+	outer := buildZip(t, map[string][]byte{
+		"readme.md":        []byte("# Project\n\nhello"),
+		"lib/nested.zip":   inner,
+		"bin/tool.exe":     {0x4d, 0x5a, 0x00, 0x01, 0x02},
+		"__MACOSX/._junk":  []byte("junk"),
+		"../../escape.txt": []byte("escaped"),
+	})
+
+	res, err := runArchive(t, newTestExtractor(Limits{}), "project.zip", outer)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	for _, want := range []string{"## readme.md", "hello", "## lib/nested.zip/notes/inner.txt", "inner words", "## escape.txt"} {
+		if !strings.Contains(res.Text, want) {
+			t.Fatalf("expected %q in text:\n%s", want, res.Text)
+		}
+	}
+	if strings.Contains(res.Text, "junk") {
+		t.Fatalf("expected __MACOSX entries to be ignored")
+	}
+	if res.Metadata["format"] != "zip" || res.Metadata["skipped"] != "1" {
+		t.Fatalf("unexpected metadata: %v", res.Metadata)
+	}
+}
+
+func TestExtractTarGz(t *testing.T) {
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	content := []byte("from a tarball")
+	if err := tw.WriteHeader(&tar.Header{Name: "src/a.txt", Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatalf("tar header: %v", err)
+	}
+	_, _ = tw.Write(content)
+	_ = tw.WriteHeader(&tar.Header{Name: "src/link", Linkname: "/etc/passwd", Typeflag: tar.TypeSymlink})
+	_ = tw.Close()
+
+	var gzBuf bytes.Buffer
+	gz := gzip.NewWriter(&gzBuf)
+	_, _ = gz.Write(tarBuf.Bytes())
+	_ = gz.Close()
+
+	res, err := runArchive(t, newTestExtractor(Limits{}), "src.tar.gz", gzBuf.Bytes())
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if !strings.Contains(res.Text, "from a tarball") || res.Metadata["entries"] != "1" {
+		t.Fatalf("unexpected result: %q %v", res.Text, res.Metadata)
+	}
+}
+
+func TestExtractRejectsCompressionBomb(t *testing.T) {
+	bomb := buildZip(t, map[string][]byte{"zeros.txt": bytes.Repeat([]byte{'0'}, 4<<20)})
+
+	_, err := runArchive(t, newTestExtractor(Limits{MaxRatio: 10}), "bomb.zip", bomb)
+	if err == nil || !strings.Contains(err.Error(), "ratio") {
+		t.Fatalf("expected ratio error, got %v", err)
+	}
+}
+
+func TestExtractEnforcesTotalSize(t *testing.T) {
+	data := buildZip(t, map[string][]byte{"a.txt": bytes.Repeat([]byte("abc "), 1024)})
+
+	_, err := runArchive(t, newTestExtractor(Limits{MaxTotalBytes: 1024}), "big.zip", data)
+	if err == nil || !strings.Contains(err.Error(), "uncompressed limit") {
+		t.Fatalf("expected size error, got %v", err)
+	}
+}
+
+func TestExtractEnforcesNestingDepth(t *testing.T) {
+	inner := buildZip(t, map[string][]byte{"deep.txt": []byte("too deep")})
+	outer := buildZip(t, map[string][]byte{"top.txt": []byte("top"), "inner.zip": inner})
+
+	res, err := runArchive(t, newTestExtractor(Limits{MaxDepth: 1}), "outer.zip", outer)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if strings.Contains(res.Text, "too deep") || res.Metadata["failed"] != "1" {
+		t.Fatalf("expected nested archive to fail, got %q %v", res.Text, res.Metadata)
+	}
+}
+
+func TestExtractTruncatesAtEntryLimit(t *testing.T) {
+	data := buildZip(t, map[string][]byte{"a.txt": []byte("a"), "b.txt": []byte("b"), "c.txt": []byte("c")})
+
+	res, err := runArchive(t, newTestExtractor(Limits{MaxEntries: 2}), "many.zip", data)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if res.Metadata["truncated"] != "true" || res.Metadata["extracted"] != "2" {
+		t.Fatalf("expected truncation after 2 entries, got %v", res.Metadata)
+	}
+}
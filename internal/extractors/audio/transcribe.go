@@ -11,8 +11,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/toricodesthings/file-processing-service/internal/budget"
 	"github.com/toricodesthings/file-processing-service/internal/extract"
 	"github.com/toricodesthings/file-processing-service/internal/transcribe"
+	"github.com/toricodesthings/file-processing-service/internal/types"
 )
 
 type Extractor struct {
@@ -36,6 +38,12 @@ func (e *Extractor) SupportedTypes() []string {
 func (e *Extractor) SupportedExtensions() []string {
 	return []string{".mp3", ".wav", ".m4a", ".ogg", ".flac", ".aac", ".wma", ".opus", ".webm"}
 }
+func (e *Extractor) Capabilities() extract.Capabilities {
+	return extract.Capabilities{PaidAPIs: []string{"groq"}, Options: transcriptionOptions}
+}
+
+// transcriptionOptions are read here and, for video, after ffmpeg.
+var transcriptionOptions = []string{"model", "responseFormat", "temperature", "language", "prompt", "timestamps"}
 
 func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
 	if e.client == nil {
@@ -48,6 +56,10 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 		return extract.Result{Success: false, Method: "groq", FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, errors.New(msg)
 	}
 
+	if !budget.Allowed(ctx) {
+		return BudgetExceededResult(e.Name(), job.MIMEType), nil
+	}
+
 	b, err := os.ReadFile(job.LocalPath)
 	if err != nil {
 		msg := err.Error()
@@ -65,6 +77,7 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 	if temp, ok := floatOption(job.Options, "temperature"); ok {
 		temperature = &temp
 	}
+	job.Progress.Report(types.ProgressEvent{Stage: types.ProgressTranscriptionStarted, Provider: transcribe.UpstreamName})
 	payload, err := e.client.Transcribe(ctx, filepath.Base(job.LocalPath), b, transcribe.Options{
 		Model:          model,
 		Language:       stringOption(job.Options, "language", ""),
@@ -99,6 +112,22 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 	return extract.Result{Success: true, Text: text, Method: "groq", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: words, CharCount: chars}, nil
 }
 
+// BudgetExceededResult is returned instead of calling Groq when the tenant's
+// budget is used up. It has no text and is flagged for a later retry.
+func BudgetExceededResult(fileType, mimeType string) extract.Result {
+	return extract.Result{
+		Success:  true,
+		Method:   "none",
+		FileType: fileType,
+		MIMEType: mimeType,
+		Metadata: map[string]string{
+			"budgetExceeded":     "true",
+			"needsTranscription": "true",
+			extract.MetaWarning:  "transcription skipped: tenant budget exceeded",
+		},
+	}
+}
+
 func formatTimestampedTranscript(segments []transcribe.Segment) string {
 	parts := make([]string, 0, len(segments))
 	for _, seg := range segments {
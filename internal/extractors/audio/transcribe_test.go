@@ -12,7 +12,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/toricodesthings/file-processing-service/internal/budget"
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/usage"
 )
 
 func TestExtractSuccessWithTimestamps(t *testing.T) {
@@ -82,6 +84,40 @@ func TestExtractSuccessWithTimestamps(t *testing.T) {
 	}
 }
 
+func TestExtractRecordsUsageForPlainJSONFormat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(8 << 20); err != nil {
+			t.Fatalf("parse multipart: %v", err)
+		}
+		if got := r.FormValue("response_format"); got != "verbose_json" {
+			t.Fatalf("duration is only reported by verbose_json, got %q", got)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"text": "hello", "language": "en", "duration": 42.0})
+	}))
+	defer srv.Close()
+
+	meter := usage.NewMeter()
+	ctx := usage.WithMeter(context.Background(), meter)
+	audioPath := writeTempAudioFile(t)
+	e := New("test-key", srv.URL, "whisper-large-v3-turbo", 2<<20, 5*time.Second)
+	res, err := e.Extract(ctx, extract.Job{
+		LocalPath: audioPath,
+		MIMEType:  "audio/mpeg",
+		FileSize:  16,
+		Options:   map[string]any{"responseFormat": "json"},
+	})
+	if err != nil || res.Text != "hello" {
+		t.Fatalf("unexpected result: %+v %v", res, err)
+	}
+	if _, ok := res.Metadata["language"]; ok {
+		t.Fatalf("json responses carry only the transcript, got %v", res.Metadata)
+	}
+	sum := meter.Summary()
+	if sum == nil || sum.Items[0].Unit != usage.UnitAudioSeconds || sum.Items[0].Quantity != 42 {
+		t.Fatalf("expected 42 audio seconds recorded, got %+v", sum)
+	}
+}
+
 func TestExtractHTTPError(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusUnauthorized)
@@ -109,6 +145,69 @@ func TestExtractHTTPError(t *testing.T) {
 	}
 }
 
+func TestExtractSkipsTranscriptionOverBudget(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("groq must not be called over budget")
+	}))
+	defer srv.Close()
+
+	store := budget.NewMemoryStore()
+	budget.SetGuard(budget.NewGuard(store, budget.Limits{MonthlyUSD: 1}))
+	defer budget.SetGuard(nil)
+	ctx := budget.WithTenant(context.Background(), "acme")
+	_ = store.Add(ctx, "acme", time.Now().UTC().Format("2006-01"), 1)
+
+	audioPath := writeTempAudioFile(t)
+	e := New("test-key", srv.URL, "whisper-large-v3-turbo", 2<<20, 5*time.Second)
+	res, err := e.Extract(ctx, extract.Job{LocalPath: audioPath, MIMEType: "audio/mpeg", FileSize: 16})
+	if err != nil || !res.Success {
+		t.Fatalf("expected degraded success, got %+v %v", res, err)
+	}
+	if res.Text != "" || res.Metadata["needsTranscription"] != "true" || res.Metadata[extract.MetaWarning] == "" {
+		t.Fatalf("expected flagged empty result, got %+v", res)
+	}
+}
+
+func TestRouterChargesBudgetForPlainJSONFormat(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		// Like Groq, only verbose_json reports the billed duration.
+		body := map[string]any{"text": "hello"}
+		if r.FormValue("response_format") == "verbose_json" {
+			body["duration"] = 60.0
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	defer srv.Close()
+
+	usage.SetPrices(usage.Prices{usage.PriceKey("groq", usage.UnitAudioSeconds): 0.01})
+	defer usage.SetPrices(nil)
+	budget.SetGuard(budget.NewGuard(budget.NewMemoryStore(), budget.Limits{DailyUSD: 0.5}))
+	defer budget.SetGuard(nil)
+	ctx := budget.WithTenant(context.Background(), "acme")
+
+	reg := extract.NewRegistry()
+	reg.Register(New("test-key", srv.URL, "whisper-large-v3-turbo", 2<<20, 5*time.Second))
+	router := extract.NewRouter(reg, 1<<20, 0)
+	opts := map[string]any{"responseFormat": "json"}
+	for range 2 {
+		dl, err := extract.SaveBodyToTemp(strings.NewReader("fake-audio-content"), "a.mp3", 1<<20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dl.Cleanup()
+		if _, err := router.ExtractFile(ctx, dl, "a.mp3", opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first minute costs $0.60, past the $0.50 limit, so the second
+	// request must not reach Groq.
+	if calls != 1 {
+		t.Fatalf("expected the budget to stop the second transcription, got %d calls", calls)
+	}
+}
+
 func TestFormatTimecode(t *testing.T) {
 	if got := formatTimecode(5.1); got != "00:05" {
 		t.Fatalf("unexpected mm:ss: %q", got)
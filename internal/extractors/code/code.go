@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/types"
 )
 
 type SourceExtractor struct {
@@ -53,10 +54,11 @@ func (e *SourceExtractor) Extract(ctx context.Context, job extract.Job) (extract
 		lines = strings.Count(text, "\n") + 1
 	}
 
+	block := types.Block{Type: types.BlockCode, Text: text, Language: lang, Source: types.Source{Line: 1}}
 	wrapped := fmt.Sprintf("<!-- lang: %s, lines: %d -->\n\n```%s\n%s\n```", lang, lines, lang, text)
 	w, c := extract.BuildCounts(wrapped)
 	meta := map[string]string{"language": lang}
-	return extract.Result{Success: true, Text: wrapped, Method: "code", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: w, CharCount: c}, nil
+	return extract.Result{Success: true, Text: wrapped, Method: "code", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: w, CharCount: c, Blocks: []types.Block{block}}, nil
 }
 
 func summarizeLargeCode(src string) string {
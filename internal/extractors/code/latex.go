@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/format"
 )
 
 type LaTeXExtractor struct {
@@ -47,5 +48,5 @@ func (e *LaTeXExtractor) Extract(ctx context.Context, job extract.Job) (extract.
 	s = strings.TrimSpace(s)
 
 	w, c := extract.BuildCounts(s)
-	return extract.Result{Success: true, Text: s, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: w, CharCount: c}, nil
+	return extract.Result{Success: true, Text: s, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: w, CharCount: c, Blocks: format.ParseMarkdown(s)}, nil
 }
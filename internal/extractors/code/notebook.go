@@ -7,6 +7,8 @@ import (
 	"strings"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/format"
+	"github.com/toricodesthings/file-processing-service/internal/types"
 )
 
 type NotebookExtractor struct {
@@ -47,19 +49,30 @@ func (e *NotebookExtractor) Extract(ctx context.Context, job extract.Job) (extra
 	}
 
 	parts := make([]string, 0, len(nb.Cells))
-	for _, c := range nb.Cells {
+	var blocks []types.Block
+	for i, c := range nb.Cells {
 		src := strings.TrimSpace(strings.Join(c.Source, ""))
 		if src == "" {
 			continue
 		}
+		if len(parts) > 0 {
+			blocks = append(blocks, types.Block{Type: types.BlockPageBreak, Source: types.Source{Element: i + 1}})
+		}
+		var cellBlocks []types.Block
 		if c.CellType == "code" {
 			parts = append(parts, "```python\n"+src+"\n```")
+			cellBlocks = []types.Block{{Type: types.BlockCode, Text: src, Language: "python", Source: types.Source{Line: 1}}}
 		} else {
 			parts = append(parts, src)
+			cellBlocks = format.ParseMarkdown(src)
+		}
+		for _, b := range cellBlocks {
+			b.Source.Element = i + 1
+			blocks = append(blocks, b)
 		}
 	}
 
 	text := strings.Join(parts, "\n\n---\n\n")
 	w, c := extract.BuildCounts(text)
-	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: w, CharCount: c}, nil
+	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: w, CharCount: c, Blocks: blocks}, nil
 }
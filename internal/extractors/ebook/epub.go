@@ -10,6 +10,8 @@ import (
 	"strings"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/extractors/plaintext"
+	"github.com/toricodesthings/file-processing-service/internal/types"
 )
 
 type EPUBExtractor struct {
@@ -70,6 +72,8 @@ func (e *EPUBExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 	}
 
 	var chapters []string
+	var blocks []types.Block
+	var tables []types.Table
 	for i, item := range spineItems {
 		b, err := readZipEntry(zr, item, 16<<20)
 		if err != nil {
@@ -80,6 +84,8 @@ func (e *EPUBExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 			continue
 		}
 		chapters = append(chapters, fmt.Sprintf("## Chapter %d\n\n%s", i+1, chapterText))
+		blocks = appendChapterBlocks(blocks, b, item)
+		tables = append(tables, plaintext.HTMLTables(b, item)...)
 	}
 
 	text := strings.Join(chapters, "\n\n---\n\n")
@@ -90,7 +96,7 @@ func (e *EPUBExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 
 	text = strings.TrimSpace(text)
 	words, chars := extract.BuildCounts(text)
-	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: words, CharCount: chars}, nil
+	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: words, CharCount: chars, Blocks: blocks, Tables: tables}, nil
 }
 
 // findOPFPath reads META-INF/container.xml and returns the rootfile full-path.
@@ -207,6 +213,15 @@ func parseOPF(data []byte, opfDir string) ([]string, map[string]string) {
 	return paths, meta
 }
 
+// appendChapterBlocks parses a chapter's XHTML into blocks, after a page
+// break when it is not the first chapter.
+func appendChapterBlocks(blocks []types.Block, b []byte, name string) []types.Block {
+	if len(blocks) > 0 {
+		blocks = append(blocks, types.Block{Type: types.BlockPageBreak, Source: types.Source{Part: name}})
+	}
+	return append(blocks, plaintext.HTMLBlocks(b, name)...)
+}
+
 // epubStripHTML converts basic HTML to markdown-like text.
 func epubStripHTML(s string) string {
 	// Convert block elements
@@ -0,0 +1,153 @@
+package ebook
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/extractors/plaintext"
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+const (
+	localHeaderSig     = "PK\x03\x04"
+	localHeaderLen     = 30
+	flagDataDescriptor = 0x8
+	zipStore           = 0
+	zipDeflate         = 8
+	maxSalvagedEntry   = 16 << 20
+	maxSalvagedTotal   = 128 << 20 // guards against zip bombs, like the native reader's per-entry limit
+)
+
+// HTMLFallbackExtractor recovers text from EPUBs the native extractor cannot
+// open, typically truncated uploads whose zip central directory is missing.
+// It walks the local file headers instead, strips every (X)HTML entry it can
+// inflate and keeps them in archive order, without OPF metadata or spine.
+type HTMLFallbackExtractor struct {
+	maxBytes int64
+}
+
+func NewHTMLFallback(maxBytes int64) *HTMLFallbackExtractor {
+	return &HTMLFallbackExtractor{maxBytes: maxBytes}
+}
+
+func (e *HTMLFallbackExtractor) Name() string                  { return "document/epub-html" }
+func (e *HTMLFallbackExtractor) MaxFileSize() int64            { return e.maxBytes }
+func (e *HTMLFallbackExtractor) SupportedTypes() []string      { return []string{"application/epub+zip"} }
+func (e *HTMLFallbackExtractor) SupportedExtensions() []string { return []string{".epub"} }
+
+func (e *HTMLFallbackExtractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
+	select {
+	case <-ctx.Done():
+		return extract.Result{Success: false}, ctx.Err()
+	default:
+	}
+
+	b, err := os.ReadFile(job.LocalPath)
+	if err != nil {
+		msg := err.Error()
+		return extract.Result{Success: false, Method: "html-strip", FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
+	}
+
+	var chapters []string
+	var blocks []types.Block
+	var tables []types.Table
+	for _, entry := range scanLocalEntries(b) {
+		name := strings.ToLower(entry.name)
+		if !strings.HasSuffix(name, ".xhtml") && !strings.HasSuffix(name, ".html") && !strings.HasSuffix(name, ".htm") {
+			continue
+		}
+		text := epubStripHTML(string(entry.data))
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		chapters = append(chapters, fmt.Sprintf("## Chapter %d\n\n%s", len(chapters)+1, text))
+		blocks = appendChapterBlocks(blocks, entry.data, entry.name)
+		tables = append(tables, plaintext.HTMLTables(entry.data, entry.name)...)
+	}
+	if len(chapters) == 0 {
+		msg := "no readable HTML entries found"
+		return extract.Result{Success: false, Method: "html-strip", FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, errors.New(msg)
+	}
+
+	text := strings.Join(chapters, "\n\n---\n\n")
+	words, chars := extract.BuildCounts(text)
+	return extract.Result{
+		Success:   true,
+		Text:      text,
+		Method:    "html-strip",
+		FileType:  e.Name(),
+		MIMEType:  job.MIMEType,
+		Metadata:  map[string]string{"chapters": fmt.Sprintf("%d", len(chapters))},
+		WordCount: words,
+		CharCount: chars,
+		Blocks:    blocks,
+		Tables:    tables,
+	}, nil
+}
+
+type localEntry struct {
+	name string
+	data []byte
+}
+
+// scanLocalEntries reads zip entries from their local headers. Stored and
+// deflated entries are returned, a truncated final entry with whatever
+// inflated before the cut; anything else is skipped. Scanning stops once
+// maxSalvagedTotal bytes have been inflated.
+func scanLocalEntries(b []byte) []localEntry {
+	var out []localEntry
+	pos, total := 0, 0
+	for total < maxSalvagedTotal {
+		i := bytes.Index(b[pos:], []byte(localHeaderSig))
+		if i < 0 || pos+i+localHeaderLen > len(b) {
+			return out
+		}
+		hdr := b[pos+i:]
+		flags := binary.LittleEndian.Uint16(hdr[6:])
+		method := binary.LittleEndian.Uint16(hdr[8:])
+		compSize := int(binary.LittleEndian.Uint32(hdr[18:]))
+		nameLen := int(binary.LittleEndian.Uint16(hdr[26:]))
+		extraLen := int(binary.LittleEndian.Uint16(hdr[28:]))
+		start := localHeaderLen + nameLen + extraLen
+		if start > len(hdr) {
+			return out
+		}
+		name := string(hdr[localHeaderLen : localHeaderLen+nameLen])
+		body := hdr[start:]
+		sized := flags&flagDataDescriptor == 0 && compSize > 0 && compSize <= len(body)
+		if sized {
+			body = body[:compSize]
+		}
+
+		var data []byte
+		switch method {
+		case zipStore:
+			if sized {
+				data = body
+			}
+		case zipDeflate:
+			// Inflate stops at the end of the deflate stream, so unsized
+			// entries need no length; a read error keeps what was inflated.
+			data, _ = io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(body)), maxSalvagedEntry))
+		}
+		if len(data) > 0 {
+			out = append(out, localEntry{name: name, data: data})
+			total += len(data)
+		}
+
+		next := pos + i + 1
+		if sized {
+			next = pos + i + start + compSize
+		}
+		pos = next
+	}
+	return out
+}
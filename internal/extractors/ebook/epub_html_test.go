@@ -0,0 +1,55 @@
+package ebook
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/toricodesthings/file-processing-service/internal/extract"
+)
+
+func TestHTMLFallbackReadsTruncatedEPUB(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range map[string]string{
+		"mimetype":             "application/epub+zip",
+		"OEBPS/chapter1.xhtml": "<html><body><h1>One</h1><p>First chapter text.</p></body></html>",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Drop the central directory, as a cut-off upload would.
+	data := buf.Bytes()
+	data = data[:bytes.Index(data, []byte("PK\x01\x02"))]
+	path := filepath.Join(t.TempDir(), "book.epub")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	job := extract.Job{LocalPath: path, MIMEType: "application/epub+zip"}
+	if _, err := NewEPUB(1<<20).Extract(context.Background(), job); err == nil {
+		t.Fatal("expected the native extractor to reject a zip without a central directory")
+	}
+	res, err := NewHTMLFallback(1<<20).Extract(context.Background(), job)
+	if err != nil {
+		t.Fatalf("fallback failed: %v", err)
+	}
+	if !strings.Contains(res.Text, "First chapter text.") || res.Method != "html-strip" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if len(res.Blocks) != 2 || res.Blocks[0].Text != "One" || res.Blocks[1].Source.Part != "OEBPS/chapter1.xhtml" {
+		t.Fatalf("unexpected blocks %+v", res.Blocks)
+	}
+}
@@ -0,0 +1,520 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/extractors/plaintext"
+	"github.com/toricodesthings/file-processing-service/internal/format"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	maxBodyPartBytes   = 16 << 20
+	maxMultipartDepth  = 8
+	maxAttachments     = 100
+	maxMboxMessages    = 10000
+	maxEmbeddedMessage = 3 // message/rfc822 attachments inside messages
+)
+
+type Extractor struct {
+	registry *extract.Registry
+	maxBytes int64
+}
+
+// New returns an .eml/.mbox extractor. Attachments are resolved through
+// registry, so it should be the same registry the extractor is added to.
+func New(registry *extract.Registry, maxBytes int64) *Extractor {
+	return &Extractor{registry: registry, maxBytes: maxBytes}
+}
+
+func (e *Extractor) Name() string       { return "message/email" }
+func (e *Extractor) MaxFileSize() int64 { return e.maxBytes }
+func (e *Extractor) SupportedTypes() []string {
+	return []string{"message/rfc822", "application/mbox"}
+}
+func (e *Extractor) SupportedExtensions() []string { return []string{".eml", ".mbox"} }
+
+type depthKey struct{}
+
+func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
+	select {
+	case <-ctx.Done():
+		return extract.Result{Success: false}, ctx.Err()
+	default:
+	}
+
+	fail := func(err error) (extract.Result, error) {
+		msg := err.Error()
+		return extract.Result{Success: false, FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
+	}
+
+	depth, _ := ctx.Value(depthKey{}).(int)
+	if depth >= maxEmbeddedMessage {
+		return fail(fmt.Errorf("embedded messages nested deeper than %d levels", maxEmbeddedMessage))
+	}
+	ctx = context.WithValue(ctx, depthKey{}, depth+1)
+
+	workDir, err := os.MkdirTemp(filepath.Dir(job.LocalPath), "email-*")
+	if err != nil {
+		return fail(fmt.Errorf("email workdir: %w", err))
+	}
+	defer os.RemoveAll(workDir)
+
+	f, err := os.Open(job.LocalPath)
+	if err != nil {
+		return fail(err)
+	}
+	defer f.Close()
+
+	r := &renderer{e: e, ctx: ctx, workDir: workDir, meta: map[string]string{}}
+
+	var text string
+	if strings.EqualFold(filepath.Ext(job.FileName), ".mbox") || job.MIMEType == "application/mbox" {
+		text, err = r.renderMbox(f)
+	} else {
+		text, err = r.renderEML(f)
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	r.meta["attachments"] = strconv.Itoa(r.attachments)
+	text = strings.TrimSpace(text)
+	words, chars := extract.BuildCounts(text)
+	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: r.meta, WordCount: words, CharCount: chars, Blocks: format.ParseMarkdown(text)}, nil
+}
+
+type renderer struct {
+	e       *Extractor
+	ctx     context.Context
+	workDir string
+	meta    map[string]string
+
+	attachments int // saved so far; caps work across an mbox
+	rendered    int // numbering for attachment.<n>.* metadata
+}
+
+// message is one parsed email with its bodies and saved attachments.
+type message struct {
+	from, to, cc, date, subject string
+
+	plain       []string
+	html        []string
+	attachments []attachment
+}
+
+type attachment struct {
+	name     string
+	path     string
+	size     int64
+	mimeType string
+	skipped  string
+	// text is set for attachments already rendered in place (embedded .msg
+	// messages); such attachments have no file on disk.
+	text     string
+	fileType string
+}
+
+func (r *renderer) renderEML(src io.Reader) (string, error) {
+	msg, err := r.parse(src)
+	if err != nil {
+		return "", err
+	}
+	for key, v := range map[string]string{"from": msg.from, "to": msg.to, "cc": msg.cc, "date": msg.date, "subject": msg.subject} {
+		if v != "" {
+			r.meta[key] = v
+		}
+	}
+	return emailFrontmatter(msg) + r.renderBody(msg), nil
+}
+
+func (r *renderer) renderMbox(src io.Reader) (string, error) {
+	var sections []string
+	count := 0
+	err := splitMbox(src, func(raw []byte) error {
+		if count >= maxMboxMessages {
+			r.meta["truncated"] = "true"
+			return errStopMbox
+		}
+		if err := r.ctx.Err(); err != nil {
+			return err
+		}
+		count++
+		msg, err := r.parse(bytes.NewReader(raw))
+		if err != nil {
+			// One malformed message should not sink the whole mailbox.
+			r.meta["message."+strconv.Itoa(count)+".error"] = err.Error()
+			return nil
+		}
+		sections = append(sections, emailHeading(msg, count)+r.renderBody(msg))
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopMbox) {
+		return "", err
+	}
+	if count == 0 {
+		return "", fmt.Errorf("mbox contains no messages")
+	}
+	r.meta["messages"] = strconv.Itoa(count)
+	return strings.Join(sections, "\n\n---\n\n"), nil
+}
+
+func (r *renderer) parse(src io.Reader) (*message, error) {
+	m, err := mail.ReadMessage(bufio.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("parse message: %w", err)
+	}
+
+	msg := &message{
+		from:    decodeHeader(m.Header.Get("From")),
+		to:      decodeHeader(m.Header.Get("To")),
+		cc:      decodeHeader(m.Header.Get("Cc")),
+		subject: decodeHeader(m.Header.Get("Subject")),
+	}
+	if raw := m.Header.Get("Date"); raw != "" {
+		if t, err := mail.ParseDate(raw); err == nil {
+			msg.date = t.UTC().Format(time.RFC3339)
+		} else {
+			msg.date = strings.TrimSpace(raw)
+		}
+	}
+
+	if err := r.walk(msg, m.Header, m.Body, 0); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+type headerGetter interface {
+	Get(key string) string
+}
+
+// walk visits a MIME part, collecting text bodies and saving attachments.
+func (r *renderer) walk(msg *message, h headerGetter, body io.Reader, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	body = decodeTransferEncoding(h.Get("Content-Transfer-Encoding"), body)
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxMultipartDepth || params["boundary"] == "" {
+			return nil
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				// Truncated or malformed multipart: keep what was parsed so far.
+				return nil
+			}
+			if err := r.walk(msg, part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := decodeHeader(dparams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+
+	isBody := disposition != "attachment" && filename == "" && (mediaType == "text/plain" || mediaType == "text/html")
+	if isBody {
+		b, err := io.ReadAll(io.LimitReader(toUTF8(body, params["charset"]), maxBodyPartBytes))
+		if err != nil {
+			return nil
+		}
+		if mediaType == "text/html" {
+			msg.html = append(msg.html, string(b))
+		} else {
+			msg.plain = append(msg.plain, string(b))
+		}
+		return nil
+	}
+
+	return r.saveAttachment(msg, filename, mediaType, body)
+}
+
+func (r *renderer) saveAttachment(msg *message, filename, mediaType string, body io.Reader) error {
+	if r.attachments >= maxAttachments {
+		return nil
+	}
+	r.attachments++
+	idx := r.attachments
+
+	filename = filepath.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if filename == "." || filename == ".." || filename == "/" {
+		filename = ""
+	}
+	if filename == "" {
+		filename = "attachment-" + strconv.Itoa(idx)
+		if mediaType == "message/rfc822" {
+			filename += ".eml"
+		} else if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			filename += exts[0]
+		}
+	}
+	att := attachment{name: filename, mimeType: mediaType}
+
+	dir := filepath.Join(r.workDir, strconv.Itoa(idx))
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		return fmt.Errorf("attachment dir: %w", err)
+	}
+	att.path = filepath.Join(dir, filename)
+
+	out, err := os.OpenFile(att.path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("create attachment: %w", err)
+	}
+	n, err := io.Copy(out, io.LimitReader(body, r.e.maxBytes+1))
+	out.Close()
+	att.size = n
+	switch {
+	case err != nil:
+		att.skipped = "unreadable: " + err.Error()
+	case n > r.e.maxBytes:
+		att.skipped = fmt.Sprintf("exceeds %dMB limit", r.e.maxBytes/(1<<20))
+	case n == 0:
+		att.skipped = "empty"
+	}
+	msg.attachments = append(msg.attachments, att)
+	return nil
+}
+
+// renderBody returns the preferred text body followed by extracted attachments.
+func (r *renderer) renderBody(msg *message) string {
+	var sb strings.Builder
+
+	body := strings.TrimSpace(strings.Join(msg.plain, "\n\n"))
+	if body == "" && len(msg.html) > 0 {
+		var parts []string
+		for _, h := range msg.html {
+			if t := strings.TrimSpace(plaintext.HTMLToText([]byte(h))); t != "" {
+				parts = append(parts, t)
+			}
+		}
+		body = strings.Join(parts, "\n\n")
+	}
+	sb.WriteString(normalizeNewlines(body))
+
+	for _, att := range msg.attachments {
+		text := r.extractAttachment(att)
+		if text == "" {
+			continue
+		}
+		sb.WriteString("\n\n### Attachment: " + att.name + "\n\n" + text)
+	}
+	return sb.String()
+}
+
+func (r *renderer) extractAttachment(att attachment) string {
+	r.rendered++
+	key := "attachment." + strconv.Itoa(r.rendered) + "."
+	r.meta[key+"name"] = att.name
+	if att.path == "" {
+		if att.fileType != "" {
+			r.meta[key+"fileType"] = att.fileType
+		}
+		if att.skipped != "" {
+			r.meta[key+"skipped"] = att.skipped
+		}
+		return att.text
+	}
+	defer os.RemoveAll(filepath.Dir(att.path))
+
+	if att.skipped != "" {
+		r.meta[key+"skipped"] = att.skipped
+		return ""
+	}
+
+	mt := extract.SniffMIMEType(att.path)
+	if mt == "" || mt == "application/octet-stream" {
+		mt = att.mimeType
+	}
+	extractor, err := r.e.registry.ResolveContext(r.ctx, mt, strings.ToLower(filepath.Ext(att.name)))
+	if err != nil {
+		r.meta[key+"skipped"] = "unsupported type"
+		return ""
+	}
+	r.meta[key+"fileType"] = extractor.Name()
+	if max := extractor.MaxFileSize(); max > 0 && att.size > max {
+		r.meta[key+"skipped"] = fmt.Sprintf("exceeds extractor limit (%dMB)", max/(1<<20))
+		return ""
+	}
+
+	res, err := extract.RunExtractor(r.ctx, extractor, extract.Job{
+		LocalPath: att.path,
+		FileName:  att.name,
+		MIMEType:  mt,
+		FileSize:  att.size,
+	})
+	if err != nil {
+		msg := err.Error()
+		if res.Error != nil {
+			msg = *res.Error
+		}
+		r.meta[key+"error"] = msg
+		return ""
+	}
+	return strings.TrimSpace(res.Text)
+}
+
+func decodeTransferEncoding(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// base64Cleaner drops whitespace and other non-alphabet bytes that mailers
+// leave in base64 bodies; the stdlib decoder only tolerates CR and LF.
+type base64Cleaner struct {
+	r io.Reader
+}
+
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	for {
+		n, err := c.r.Read(p)
+		out := 0
+		for _, b := range p[:n] {
+			if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '+' || b == '/' || b == '=' {
+				p[out] = b
+				out++
+			}
+		}
+		if out > 0 || err != nil {
+			return out, err
+		}
+	}
+}
+
+func toUTF8(r io.Reader, label string) io.Reader {
+	label = strings.TrimSpace(label)
+	if label == "" || strings.EqualFold(label, "utf-8") || strings.EqualFold(label, "us-ascii") {
+		return r
+	}
+	if cr, err := charset.NewReaderLabel(label, r); err == nil {
+		return cr
+	}
+	return r
+}
+
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: func(label string, input io.Reader) (io.Reader, error) {
+		return charset.NewReaderLabel(label, input)
+	},
+}
+
+func decodeHeader(v string) string {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return ""
+	}
+	if d, err := wordDecoder.DecodeHeader(v); err == nil {
+		v = d
+	}
+	return strings.Join(strings.Fields(v), " ")
+}
+
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
+
+func emailFrontmatter(msg *message) string {
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	for _, kv := range [][2]string{{"from", msg.from}, {"to", msg.to}, {"cc", msg.cc}, {"date", msg.date}, {"subject", msg.subject}} {
+		if kv[1] != "" {
+			sb.WriteString(kv[0] + ": " + kv[1] + "\n")
+		}
+	}
+	sb.WriteString("---\n\n")
+	return sb.String()
+}
+
+func emailHeading(msg *message, n int) string {
+	subject := msg.subject
+	if subject == "" {
+		subject = "(no subject)"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## Message %d: %s\n\n", n, subject))
+	for _, kv := range [][2]string{{"From", msg.from}, {"To", msg.to}, {"Cc", msg.cc}, {"Date", msg.date}} {
+		if kv[1] != "" {
+			sb.WriteString(kv[0] + ": " + kv[1] + "\n")
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+var errStopMbox = errors.New("stop mbox")
+
+// splitMbox calls fn with each message of an mbox file. Messages start at a
+// "From " line; mboxrd ">From " escaping is undone.
+func splitMbox(src io.Reader, fn func(raw []byte) error) error {
+	br := bufio.NewReaderSize(src, 64<<10)
+	var cur bytes.Buffer
+	started := false
+	prevBlank := true
+
+	flush := func() error {
+		if !started || cur.Len() == 0 {
+			return nil
+		}
+		raw := append([]byte(nil), cur.Bytes()...)
+		cur.Reset()
+		return fn(raw)
+	}
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			trimmed := bytes.TrimRight(line, "\r\n")
+			switch {
+			case prevBlank && bytes.HasPrefix(trimmed, []byte("From ")):
+				if ferr := flush(); ferr != nil {
+					return ferr
+				}
+				started = true
+			case started:
+				if bytes.HasPrefix(trimmed, []byte(">")) && bytes.HasPrefix(bytes.TrimLeft(trimmed, ">"), []byte("From ")) {
+					line = line[1:]
+				}
+				cur.Write(line)
+			}
+			prevBlank = len(trimmed) == 0
+		}
+		if err == io.EOF {
+			return flush()
+		}
+		if err != nil {
+			return fmt.Errorf("read mbox: %w", err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package email
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/extractors/plaintext"
+)
+
+func newTestExtractor() *Extractor {
+	reg := extract.NewRegistry()
+	reg.Register(plaintext.New(1 << 20))
+	e := New(reg, 1<<20)
+	reg.Register(e)
+	return e
+}
+
+func runEmail(t *testing.T, name, content string) extract.Result {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(strings.ReplaceAll(content, "\n", "\r\n")), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	res, err := newTestExtractor().Extract(context.Background(), extract.Job{LocalPath: p, FileName: name})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	return res
+}
+
+const multipartEML = `From: =?UTF-8?Q?Ren=C3=A9e?= <renee@example.com>
+To: team@example.com
+Cc: boss@example.com
+Date: Mon, 02 Jun 2025 10:30:00 +0200
+Subject: =?UTF-8?B?UXVhcnRlcmx5IHJlcG9ydA==?=
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Hello team,=0A=0Athe numbers are in.
+--inner
+Content-Type: text/html; charset=utf-8
+
+<p>HTML version should be ignored</p>
+--inner--
+--outer
+Content-Type: text/plain; name="notes.txt"
+Content-Disposition: attachment; filename="notes.txt"
+Content-Transfer-Encoding: base64
+
+YXR0YWNoZWQgbm90ZXM=
+--outer--
+`
+
+func TestExtractEMLPrefersPlainTextAndExtractsAttachments(t *testing.T) {
+	res := runEmail(t, "report.eml", multipartEML)
+
+	for _, want := range []string{
+		"from: Renée <renee@example.com>",
+		"subject: Quarterly report",
+		"date: 2025-06-02T08:30:00Z",
+		"the numbers are in.",
+		"### Attachment: notes.txt",
+		"attached notes",
+	} {
+		if !strings.Contains(res.Text, want) {
+			t.Fatalf("expected %q in text:\n%s", want, res.Text)
+		}
+	}
+	if strings.Contains(res.Text, "HTML version") {
+		t.Fatalf("expected text/plain to win over text/html")
+	}
+	if res.Metadata["subject"] != "Quarterly report" || res.Metadata["attachments"] != "1" {
+		t.Fatalf("unexpected metadata: %v", res.Metadata)
+	}
+	if res.Metadata["attachment.1.fileType"] != "text" {
+		t.Fatalf("expected attachment to resolve through the registry, got %v", res.Metadata)
+	}
+}
+
+func TestExtractEMLFallsBackToHTML(t *testing.T) {
+	res := runEmail(t, "html.eml", `From: a@example.com
+Subject: HTML only
+Content-Type: text/html; charset=iso-8859-1
+
+<html><body><p>Caf`+"\xe9"+` opening hours</p><script>x()</script></body></html>
+`)
+	if !strings.Contains(res.Text, "Café opening hours") {
+		t.Fatalf("expected decoded html body, got:\n%s", res.Text)
+	}
+	if strings.Contains(res.Text, "x()") {
+		t.Fatalf("expected scripts to be stripped")
+	}
+}
+
+func TestExtractMbox(t *testing.T) {
+	res := runEmail(t, "inbox.mbox", `From alice@example.com Mon Jun  2 10:00:00 2025
+From: alice@example.com
+Subject: First
+
+Line one
+>From the archives
+
+From bob@example.com Mon Jun  2 11:00:00 2025
+From: bob@example.com
+Subject: Second
+
+Line two
+`)
+	if res.Metadata["messages"] != "2" {
+		t.Fatalf("expected 2 messages, got %v", res.Metadata)
+	}
+	for _, want := range []string{"## Message 1: First", "From the archives", "## Message 2: Second", "Line two"} {
+		if !strings.Contains(res.Text, want) {
+			t.Fatalf("expected %q in text:\n%s", want, res.Text)
+		}
+	}
+	if strings.Contains(res.Text, ">From") {
+		t.Fatalf("expected mboxrd escaping to be undone")
+	}
+}
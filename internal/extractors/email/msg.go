@@ -0,0 +1,367 @@
+package email
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/extractors/plaintext"
+	"github.com/toricodesthings/file-processing-service/internal/format"
+)
+
+// MAPI property IDs used by Outlook .msg files (MS-OXPROPS).
+const (
+	propSubject            = 0x0037
+	propClientSubmitTime   = 0x0039
+	propSentRepName        = 0x0042
+	propSentRepEmail       = 0x0065
+	propSenderName         = 0x0C1A
+	propSenderEmail        = 0x0C1F
+	propRecipientType      = 0x0C15
+	propDisplayCc          = 0x0E03
+	propDisplayTo          = 0x0E04
+	propDeliveryTime       = 0x0E06
+	propBody               = 0x1000
+	propRTFCompressed      = 0x1009
+	propHTML               = 0x1013
+	propDisplayName        = 0x3001
+	propEmailAddress       = 0x3003
+	propAttachData         = 0x3701
+	propAttachFilename     = 0x3704
+	propAttachLongFilename = 0x3707
+	propAttachMIMETag      = 0x370E
+	propSMTPAddress        = 0x39FE
+	propSenderSMTPAddress  = 0x5D01
+
+	typeString8  = 0x001E
+	typeUnicode  = 0x001F
+	typeBinary   = 0x0102
+	typeObject   = 0x000D
+	typeLong     = 0x0003
+	typeSysTime  = 0x0040
+	maxPropBytes = 16 << 20
+
+	recipPrefix  = "__recip_version1.0_#"
+	attachPrefix = "__attach_version1.0_#"
+	propsStream  = "__properties_version1.0"
+)
+
+// MSGExtractor reads Outlook .msg compound files. Output mirrors the .eml
+// extractor: frontmatter, preferred body, then attachment sections.
+type MSGExtractor struct {
+	registry *extract.Registry
+	maxBytes int64
+}
+
+func NewMSG(registry *extract.Registry, maxBytes int64) *MSGExtractor {
+	return &MSGExtractor{registry: registry, maxBytes: maxBytes}
+}
+
+func (e *MSGExtractor) Name() string                  { return "message/outlook" }
+func (e *MSGExtractor) MaxFileSize() int64            { return e.maxBytes }
+func (e *MSGExtractor) SupportedTypes() []string      { return []string{"application/vnd.ms-outlook"} }
+func (e *MSGExtractor) SupportedExtensions() []string { return []string{".msg"} }
+
+func (e *MSGExtractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
+	select {
+	case <-ctx.Done():
+		return extract.Result{Success: false}, ctx.Err()
+	default:
+	}
+
+	fail := func(err error) (extract.Result, error) {
+		msg := err.Error()
+		return extract.Result{Success: false, FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
+	}
+
+	depth, _ := ctx.Value(depthKey{}).(int)
+	if depth >= maxEmbeddedMessage {
+		return fail(fmt.Errorf("embedded messages nested deeper than %d levels", maxEmbeddedMessage))
+	}
+	ctx = context.WithValue(ctx, depthKey{}, depth+1)
+
+	f, err := os.Open(job.LocalPath)
+	if err != nil {
+		return fail(err)
+	}
+	defer f.Close()
+
+	doc, err := mscfb.New(f)
+	if err != nil {
+		return fail(fmt.Errorf("open msg: %w", err))
+	}
+	store := newMSGStore(doc)
+	if !store.has(store.streamName("", propSubject, typeUnicode)) && !store.has(propsStream) {
+		return fail(fmt.Errorf("not an Outlook message"))
+	}
+
+	workDir, err := os.MkdirTemp(filepath.Dir(job.LocalPath), "msg-*")
+	if err != nil {
+		return fail(fmt.Errorf("msg workdir: %w", err))
+	}
+	defer os.RemoveAll(workDir)
+
+	r := &renderer{
+		e:       &Extractor{registry: e.registry, maxBytes: e.maxBytes},
+		ctx:     ctx,
+		workDir: workDir,
+		meta:    map[string]string{},
+	}
+	msg := r.parseMSG(store, "", 32, depth)
+
+	for key, v := range map[string]string{"from": msg.from, "to": msg.to, "cc": msg.cc, "date": msg.date, "subject": msg.subject} {
+		if v != "" {
+			r.meta[key] = v
+		}
+	}
+	text := strings.TrimSpace(emailFrontmatter(msg) + r.renderBody(msg))
+	r.meta["attachments"] = strconv.Itoa(r.attachments)
+
+	words, chars := extract.BuildCounts(text)
+	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: r.meta, WordCount: words, CharCount: chars, Blocks: format.ParseMarkdown(text)}, nil
+}
+
+// parseMSG reads one message rooted at prefix ("" for the top-level message,
+// or an embedded-message storage path ending in "/"). headerSize is the size
+// of the fixed header in that message's property stream: 32 at the top level,
+// 24 for embedded messages.
+func (r *renderer) parseMSG(s *msgStore, prefix string, headerSize int, depth int) *message {
+	fixed := s.fixedProps(prefix+propsStream, headerSize)
+
+	msg := &message{subject: s.str(prefix, propSubject)}
+
+	senderName := firstNonEmpty(s.str(prefix, propSenderName), s.str(prefix, propSentRepName))
+	senderEmail := firstNonEmpty(s.str(prefix, propSenderSMTPAddress), smtpOnly(s.str(prefix, propSenderEmail)), smtpOnly(s.str(prefix, propSentRepEmail)))
+	msg.from = formatAddress(senderName, senderEmail)
+
+	var to, cc []string
+	for _, storage := range s.children(prefix, recipPrefix) {
+		rp := prefix + storage + "/"
+		addr := formatAddress(s.str(rp, propDisplayName), firstNonEmpty(s.str(rp, propSMTPAddress), smtpOnly(s.str(rp, propEmailAddress))))
+		if addr == "" {
+			continue
+		}
+		switch s.fixedProps(rp+propsStream, 8)[propRecipientType] {
+		case 2:
+			cc = append(cc, addr)
+		case 3:
+			// Bcc is only present on drafts/sent items; omit like .eml headers do.
+		default:
+			to = append(to, addr)
+		}
+	}
+	msg.to = firstNonEmpty(strings.Join(to, ", "), s.str(prefix, propDisplayTo))
+	msg.cc = firstNonEmpty(strings.Join(cc, ", "), s.str(prefix, propDisplayCc))
+
+	for _, id := range []uint16{propClientSubmitTime, propDeliveryTime} {
+		if v, ok := fixed[id]; ok && v != 0 {
+			msg.date = filetimeToTime(v).UTC().Format(time.RFC3339)
+			break
+		}
+	}
+
+	if body := s.str(prefix, propBody); strings.TrimSpace(body) != "" {
+		msg.plain = append(msg.plain, body)
+	} else if html := firstNonEmpty(string(s.bin(prefix, propHTML)), s.str(prefix, propHTML)); strings.TrimSpace(html) != "" {
+		msg.html = append(msg.html, html)
+	} else if rtf := s.bin(prefix, propRTFCompressed); len(rtf) > 0 {
+		if raw, err := decompressRTF(rtf); err == nil {
+			msg.plain = append(msg.plain, plaintext.RTFToText(raw))
+		}
+	}
+
+	for _, storage := range s.children(prefix, attachPrefix) {
+		r.msgAttachment(s, msg, prefix+storage+"/", depth)
+	}
+	return msg
+}
+
+func (r *renderer) msgAttachment(s *msgStore, msg *message, ap string, depth int) {
+	name := firstNonEmpty(s.str(ap, propAttachLongFilename), s.str(ap, propAttachFilename), s.str(ap, propDisplayName))
+
+	// Embedded Outlook item: the attachment data is a nested message storage.
+	embedded := s.streamName(ap, propAttachData, typeObject) + "/"
+	if s.hasPrefix(embedded) {
+		if r.attachments >= maxAttachments {
+			return
+		}
+		r.attachments++
+		att := attachment{name: firstNonEmpty(name, "message-"+strconv.Itoa(r.attachments)+".msg"), fileType: "message/outlook"}
+		if depth+1 >= maxEmbeddedMessage {
+			att.skipped = fmt.Sprintf("embedded messages nested deeper than %d levels", maxEmbeddedMessage)
+		} else {
+			inner := r.parseMSG(s, embedded, 24, depth+1)
+			att.text = strings.TrimSpace(emailFrontmatter(inner) + r.renderBody(inner))
+		}
+		msg.attachments = append(msg.attachments, att)
+		return
+	}
+
+	f := s.file(s.streamName(ap, propAttachData, typeBinary))
+	if f == nil {
+		return
+	}
+	_ = r.saveAttachment(msg, name, s.str(ap, propAttachMIMETag), io.NewSectionReader(f, 0, f.Size))
+}
+
+// ---------- compound file access ----------
+
+type msgStore struct {
+	files map[string]*mscfb.File
+}
+
+func newMSGStore(doc *mscfb.Reader) *msgStore {
+	s := &msgStore{files: make(map[string]*mscfb.File, len(doc.File))}
+	for _, f := range doc.File {
+		if len(f.Path) == 0 && f.Name == "Root Entry" {
+			continue
+		}
+		key := strings.Join(append(append([]string(nil), f.Path...), f.Name), "/")
+		s.files[key] = f
+	}
+	return s
+}
+
+func (s *msgStore) streamName(prefix string, id, typ uint16) string {
+	return fmt.Sprintf("%s__substg1.0_%04X%04X", prefix, id, typ)
+}
+
+func (s *msgStore) has(key string) bool { return s.files[key] != nil }
+
+func (s *msgStore) hasPrefix(prefix string) bool {
+	for key := range s.files {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *msgStore) file(key string) *mscfb.File { return s.files[key] }
+
+// children lists the direct child storages of prefix whose names start with kind.
+func (s *msgStore) children(prefix, kind string) []string {
+	seen := map[string]bool{}
+	for key := range s.files {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok || !strings.HasPrefix(rest, kind) {
+			continue
+		}
+		name, _, _ := strings.Cut(rest, "/")
+		seen[name] = true
+	}
+	out := make([]string, 0, len(seen))
+	for name := range seen {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (s *msgStore) read(key string) []byte {
+	f := s.files[key]
+	if f == nil || f.Size <= 0 {
+		return nil
+	}
+	b, err := io.ReadAll(io.LimitReader(io.NewSectionReader(f, 0, f.Size), maxPropBytes))
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// str returns a string property, preferring the UTF-16 form over the 8-bit one.
+func (s *msgStore) str(prefix string, id uint16) string {
+	if b := s.read(s.streamName(prefix, id, typeUnicode)); len(b) > 0 {
+		return strings.TrimRight(decodeUTF16LE(b), "\x00")
+	}
+	if b := s.read(s.streamName(prefix, id, typeString8)); len(b) > 0 {
+		b = []byte(strings.TrimRight(string(b), "\x00"))
+		if out, err := io.ReadAll(toUTF8(strings.NewReader(string(b)), "windows-1252")); err == nil {
+			return string(out)
+		}
+		return string(b)
+	}
+	return ""
+}
+
+func (s *msgStore) bin(prefix string, id uint16) []byte {
+	return s.read(s.streamName(prefix, id, typeBinary))
+}
+
+// fixedProps parses the 16-byte entries of a __properties_version1.0 stream
+// and returns the 8-byte values of fixed-length (long/time) properties by ID.
+func (s *msgStore) fixedProps(key string, headerSize int) map[uint16]uint64 {
+	out := map[uint16]uint64{}
+	b := s.read(key)
+	if len(b) <= headerSize {
+		return out
+	}
+	for off := headerSize; off+16 <= len(b); off += 16 {
+		tag := binary.LittleEndian.Uint32(b[off:])
+		typ, id := uint16(tag&0xFFFF), uint16(tag>>16)
+		switch typ {
+		case typeLong:
+			out[id] = uint64(binary.LittleEndian.Uint32(b[off+8:]))
+		case typeSysTime:
+			out[id] = binary.LittleEndian.Uint64(b[off+8:])
+		}
+	}
+	return out
+}
+
+func decodeUTF16LE(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// filetimeToTime converts a Windows FILETIME (100ns ticks since 1601).
+func filetimeToTime(ft uint64) time.Time {
+	const ticksTo1970 = 116444736000000000
+	if ft < ticksTo1970 {
+		return time.Unix(0, 0)
+	}
+	return time.Unix(0, int64(ft-ticksTo1970)*100)
+}
+
+// smtpOnly drops Exchange legacy DNs ("/O=ORG/OU=...") which are not addresses.
+func smtpOnly(addr string) string {
+	if strings.HasPrefix(addr, "/") {
+		return ""
+	}
+	return addr
+}
+
+func formatAddress(name, addr string) string {
+	name, addr = strings.TrimSpace(name), strings.TrimSpace(addr)
+	switch {
+	case name != "" && addr != "" && !strings.EqualFold(name, addr):
+		return name + " <" + addr + ">"
+	case addr != "":
+		return addr
+	default:
+		return name
+	}
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,283 @@
+package email
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/extractors/plaintext"
+)
+
+func TestDecompressRTF(t *testing.T) {
+	if len(rtfPrebuf) != 207 {
+		t.Fatalf("prebuf length = %d, want 207", len(rtfPrebuf))
+	}
+
+	// Example from MS-OXRTFCP 3.1.1.
+	compressed := []byte{
+		0x2d, 0x00, 0x00, 0x00, 0x2b, 0x00, 0x00, 0x00, 0x4c, 0x5a, 0x46, 0x75, 0xf1, 0xc5, 0xc7, 0xa7,
+		0x03, 0x00, 0x0a, 0x00, 0x72, 0x63, 0x70, 0x67, 0x31, 0x32, 0x35, 0x42, 0x32, 0x0a, 0xf3, 0x20,
+		0x68, 0x65, 0x6c, 0x09, 0x00, 0x20, 0x62, 0x77, 0x05, 0xb0, 0x6c, 0x64, 0x7d, 0x0a, 0x80, 0x0f,
+		0xa0,
+	}
+	out, err := decompressRTF(compressed)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	if want := "{\\rtf1\\ansi\\ansicpg1252\\pard hello world}\r\n"; string(out) != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+
+	raw := "{\\rtf1 plain}"
+	uncompressed := make([]byte, 16, 16+len(raw))
+	binary.LittleEndian.PutUint32(uncompressed[0:], uint32(12+len(raw)))
+	binary.LittleEndian.PutUint32(uncompressed[4:], uint32(len(raw)))
+	binary.LittleEndian.PutUint32(uncompressed[8:], rtfUncompressed)
+	uncompressed = append(uncompressed, raw...)
+	if out, err := decompressRTF(uncompressed); err != nil || string(out) != raw {
+		t.Fatalf("uncompressed: got %q, %v", out, err)
+	}
+}
+
+func TestExtractMSG(t *testing.T) {
+	ts := uint64(133933266000000000) // 2025-06-02T08:30:00Z
+
+	props := make([]byte, 32)
+	props = append(props, fixedProp(propClientSubmitTime, typeSysTime, ts)...)
+	ccProps := append(make([]byte, 8), fixedProp(propRecipientType, typeLong, 2)...)
+
+	streams := map[string][]byte{
+		propsStream:                                    props,
+		substg("", propSubject, typeUnicode):           utf16le("Quarterly report"),
+		substg("", propSenderName, typeUnicode):        utf16le("Renée"),
+		substg("", propSenderSMTPAddress, typeUnicode): utf16le("renee@example.com"),
+		substg("", propBody, typeUnicode):              utf16le("Hello team,\r\nthe numbers are in."),
+
+		recipPrefix + "00000000/" + substg("", propDisplayName, typeUnicode): utf16le("Team"),
+		recipPrefix + "00000000/" + substg("", propSMTPAddress, typeUnicode): utf16le("team@example.com"),
+		recipPrefix + "00000001/" + substg("", propSMTPAddress, typeUnicode): utf16le("boss@example.com"),
+		recipPrefix + "00000001/" + propsStream:                              ccProps,
+
+		attachPrefix + "00000000/" + substg("", propAttachLongFilename, typeUnicode): utf16le("notes.txt"),
+		attachPrefix + "00000000/" + substg("", propAttachData, typeBinary):          []byte("attached notes"),
+	}
+
+	p := filepath.Join(t.TempDir(), "report.msg")
+	if err := os.WriteFile(p, buildCFB(streams), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	reg := extract.NewRegistry()
+	reg.Register(plaintext.New(1 << 20))
+	res, err := NewMSG(reg, 1<<20).Extract(context.Background(), extract.Job{LocalPath: p, FileName: "report.msg"})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	for _, want := range []string{
+		"from: Renée <renee@example.com>",
+		"to: Team <team@example.com>",
+		"cc: boss@example.com",
+		"subject: Quarterly report",
+		"date: 2025-06-02T08:30:00Z",
+		"the numbers are in.",
+		"### Attachment: notes.txt",
+		"attached notes",
+	} {
+		if !strings.Contains(res.Text, want) {
+			t.Fatalf("expected %q in text:\n%s", want, res.Text)
+		}
+	}
+	if res.Metadata["attachments"] != "1" {
+		t.Fatalf("attachments = %q", res.Metadata["attachments"])
+	}
+}
+
+func TestExtractMSGRejectsNonCompoundFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "fake.msg")
+	if err := os.WriteFile(p, []byte("not a compound file"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	res, err := NewMSG(extract.NewRegistry(), 1<<20).Extract(context.Background(), extract.Job{LocalPath: p})
+	if err == nil || res.Success {
+		t.Fatalf("expected failure, got %+v", res)
+	}
+}
+
+func substg(prefix string, id, typ uint16) string {
+	return (&msgStore{}).streamName(prefix, id, typ)
+}
+
+func utf16le(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+	return b
+}
+
+func fixedProp(id, typ uint16, v uint64) []byte {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint32(b[0:], uint32(id)<<16|uint32(typ))
+	binary.LittleEndian.PutUint64(b[8:], v)
+	return b
+}
+
+// buildCFB writes a minimal version-3 compound file holding the given
+// streams ("storage/…/stream" paths). Every stream lives in the mini stream,
+// so it only suits small fixtures.
+func buildCFB(streams map[string][]byte) []byte {
+	const (
+		sector     = 512
+		mini       = 64
+		endOfChain = 0xFFFFFFFE
+		freeSect   = 0xFFFFFFFF
+		fatSect    = 0xFFFFFFFD
+		noStream   = 0xFFFFFFFF
+	)
+	type entry struct {
+		name     string
+		typ      byte
+		children []int
+		data     []byte
+		start    uint32
+	}
+	entries := []*entry{{name: "Root Entry", typ: 5}}
+	index := map[string]int{"": 0}
+	var lookup func(path string) int
+	lookup = func(path string) int {
+		if i, ok := index[path]; ok {
+			return i
+		}
+		parent, name := "", path
+		if i := strings.LastIndex(path, "/"); i >= 0 {
+			parent, name = path[:i], path[i+1:]
+		}
+		p := lookup(parent)
+		entries = append(entries, &entry{name: name, typ: 1})
+		i := len(entries) - 1
+		entries[p].children = append(entries[p].children, i)
+		index[path] = i
+		return i
+	}
+
+	var ministream []byte
+	var minifat []uint32
+	for path, data := range streams {
+		i := lookup(path)
+		e := entries[i]
+		e.typ, e.data, e.start = 2, data, uint32(len(minifat))
+		n := (len(data) + mini - 1) / mini
+		for k := 0; k < n; k++ {
+			next := uint32(len(minifat) + 1)
+			if k == n-1 {
+				next = endOfChain
+			}
+			minifat = append(minifat, next)
+		}
+		ministream = append(ministream, data...)
+		ministream = append(ministream, make([]byte, n*mini-len(data))...)
+	}
+
+	dirSectors := (len(entries)*128 + sector - 1) / sector
+	minifatSectors := (len(minifat)*4 + sector - 1) / sector
+	streamSectors := (len(ministream) + sector - 1) / sector
+	dirStart, minifatStart := 1, 1+dirSectors
+	streamStart := minifatStart + minifatSectors
+	total := streamStart + streamSectors
+
+	fat := make([]uint32, sector/4)
+	for i := range fat {
+		fat[i] = freeSect
+	}
+	fat[0] = fatSect
+	chain := func(start, n int) {
+		for k := 0; k < n; k++ {
+			fat[start+k] = uint32(start + k + 1)
+		}
+		fat[start+n-1] = endOfChain
+	}
+	chain(dirStart, dirSectors)
+	chain(minifatStart, minifatSectors)
+	chain(streamStart, streamSectors)
+
+	out := make([]byte, sector*(total+1))
+	h := out[:sector]
+	copy(h, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1})
+	binary.LittleEndian.PutUint16(h[24:], 0x3E)
+	binary.LittleEndian.PutUint16(h[26:], 3)
+	binary.LittleEndian.PutUint16(h[28:], 0xFFFE)
+	binary.LittleEndian.PutUint16(h[30:], 9)
+	binary.LittleEndian.PutUint16(h[32:], 6)
+	binary.LittleEndian.PutUint32(h[44:], 1)
+	binary.LittleEndian.PutUint32(h[48:], uint32(dirStart))
+	binary.LittleEndian.PutUint32(h[56:], 4096)
+	binary.LittleEndian.PutUint32(h[60:], uint32(minifatStart))
+	binary.LittleEndian.PutUint32(h[64:], uint32(minifatSectors))
+	binary.LittleEndian.PutUint32(h[68:], endOfChain)
+	for i := 76; i < sector; i += 4 {
+		binary.LittleEndian.PutUint32(h[i:], freeSect)
+	}
+	binary.LittleEndian.PutUint32(h[76:], 0)
+
+	sectorAt := func(n int) []byte { return out[sector*(n+1):] }
+	for i, v := range fat {
+		binary.LittleEndian.PutUint32(sectorAt(0)[4*i:], v)
+	}
+	for i := 0; i < minifatSectors*sector/4; i++ {
+		v := uint32(freeSect)
+		if i < len(minifat) {
+			v = minifat[i]
+		}
+		binary.LittleEndian.PutUint32(sectorAt(minifatStart)[4*i:], v)
+	}
+	copy(sectorAt(streamStart), ministream)
+
+	// Siblings form a right-leaning chain; mscfb walks it without balancing checks.
+	right := make([]uint32, len(entries))
+	child := make([]uint32, len(entries))
+	for i := range entries {
+		right[i], child[i] = noStream, noStream
+	}
+	for i, e := range entries {
+		for k, c := range e.children {
+			if k == 0 {
+				child[i] = uint32(c)
+			}
+			if k+1 < len(e.children) {
+				right[c] = uint32(e.children[k+1])
+			}
+		}
+	}
+	dir := sectorAt(dirStart)
+	for i := 0; i < dirSectors*sector/128; i++ {
+		d := dir[i*128 : (i+1)*128]
+		binary.LittleEndian.PutUint32(d[68:], noStream)
+		binary.LittleEndian.PutUint32(d[72:], noStream)
+		binary.LittleEndian.PutUint32(d[76:], noStream)
+		if i >= len(entries) {
+			continue
+		}
+		e := entries[i]
+		name := utf16le(e.name)
+		copy(d, name)
+		binary.LittleEndian.PutUint16(d[64:], uint16(len(name)+2))
+		d[66], d[67] = e.typ, 1
+		binary.LittleEndian.PutUint32(d[72:], right[i])
+		binary.LittleEndian.PutUint32(d[76:], child[i])
+		switch e.typ {
+		case 5:
+			binary.LittleEndian.PutUint32(d[116:], uint32(streamStart))
+			binary.LittleEndian.PutUint32(d[120:], uint32(len(ministream)))
+		case 2:
+			binary.LittleEndian.PutUint32(d[116:], e.start)
+			binary.LittleEndian.PutUint32(d[120:], uint32(len(e.data)))
+		}
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package email
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	rtfCompressed   = 0x75465A4C // "LZFu"
+	rtfUncompressed = 0x414C454D // "MELA"
+	rtfDictSize     = 4096
+	rtfMaxOutput    = 64 << 20
+)
+
+// rtfPrebuf seeds the LZFu dictionary (MS-OXRTFCP 2.1.3.1.1).
+const rtfPrebuf = "{\\rtf1\\ansi\\mac\\deff0\\deftab720{\\fonttbl;}{\\f0\\fnil \\froman \\fswiss \\fmodern \\fscript \\fdecor MS Sans SerifSymbolArialTimes New RomanCourier{\\colortbl\\red0\\green0\\blue0\r\n\\par \\pard\\plain\\f0\\fs20\\b\\i\\u\\tab\\tx"
+
+// decompressRTF expands a PR_RTF_COMPRESSED stream into raw RTF.
+func decompressRTF(b []byte) ([]byte, error) {
+	if len(b) < 16 {
+		return nil, fmt.Errorf("compressed rtf header truncated")
+	}
+	compSize := int(binary.LittleEndian.Uint32(b[0:]))
+	rawSize := int(binary.LittleEndian.Uint32(b[4:]))
+	compType := binary.LittleEndian.Uint32(b[8:])
+
+	data := b[16:]
+	if end := compSize - 12; end >= 0 && end < len(data) {
+		data = data[:end]
+	}
+	if compType == rtfUncompressed {
+		return data, nil
+	}
+	if compType != rtfCompressed {
+		return nil, fmt.Errorf("unknown compressed rtf type %#x", compType)
+	}
+	if rawSize < 0 || rawSize > rtfMaxOutput {
+		rawSize = rtfMaxOutput
+	}
+
+	var dict [rtfDictSize]byte
+	copy(dict[:], rtfPrebuf)
+	wp := len(rtfPrebuf)
+
+	out := make([]byte, 0, rawSize)
+	for i := 0; i < len(data); {
+		control := data[i]
+		i++
+		for bit := 0; bit < 8 && i < len(data); bit++ {
+			if control&(1<<bit) == 0 {
+				c := data[i]
+				i++
+				out = append(out, c)
+				dict[wp] = c
+				wp = (wp + 1) % rtfDictSize
+				continue
+			}
+			if i+1 >= len(data) {
+				return out, nil
+			}
+			ref := int(data[i])<<8 | int(data[i+1])
+			i += 2
+			offset, length := ref>>4, ref&0xF+2
+			if offset == wp {
+				return out, nil
+			}
+			for k := 0; k < length; k++ {
+				c := dict[(offset+k)%rtfDictSize]
+				out = append(out, c)
+				dict[wp] = c
+				wp = (wp + 1) % rtfDictSize
+			}
+			if len(out) > rtfMaxOutput {
+				return nil, fmt.Errorf("compressed rtf expands beyond %dMB", rtfMaxOutput>>20)
+			}
+		}
+	}
+	return out, nil
+}
@@ -5,21 +5,24 @@ import (
 	"encoding/base64"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
 	img "github.com/toricodesthings/file-processing-service/internal/image"
+	"github.com/toricodesthings/file-processing-service/internal/ocr"
 )
 
 type Extractor struct {
+	providers     *ocr.Providers
 	ocrModel      string
 	visionModel   string
 	visionTimeout time.Duration
 	maxBytes      int64
 }
 
-func New(ocrModel, visionModel string, visionTimeout time.Duration, maxBytes int64) *Extractor {
-	return &Extractor{ocrModel: ocrModel, visionModel: visionModel, visionTimeout: visionTimeout, maxBytes: maxBytes}
+func New(providers *ocr.Providers, ocrModel, visionModel string, visionTimeout time.Duration, maxBytes int64) *Extractor {
+	return &Extractor{providers: providers, ocrModel: ocrModel, visionModel: visionModel, visionTimeout: visionTimeout, maxBytes: maxBytes}
 }
 
 func (e *Extractor) Name() string { return "image" }
@@ -36,7 +39,17 @@ func (e *Extractor) SupportedExtensions() []string {
 	return []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp", ".tiff", ".tif", ".svg", ".avif"}
 }
 
+func (e *Extractor) Capabilities() extract.Capabilities {
+	return extract.Capabilities{PaidAPIs: []string{"mistral", "openrouter"}, Options: []string{"ocrProvider"}}
+}
+
 func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
+	providerName, err := e.providerOption(job.Options)
+	if err != nil {
+		msg := err.Error()
+		return extract.Result{Success: false, Method: "image", FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
+	}
+
 	imageURL := job.PresignedURL
 	if imageURL == "" && job.LocalPath != "" {
 		// Binary upload path (R2 binding stream): no presigned URL available.
@@ -54,7 +67,7 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 		imageURL = fmt.Sprintf("data:%s;base64,%s", mime, base64.StdEncoding.EncodeToString(data))
 	}
 
-	res, err := img.ProcessImage(ctx, imageURL, e.ocrModel, e.visionModel, e.visionTimeout)
+	res, err := img.ProcessImage(ctx, e.providers, providerName, imageURL, job.LocalPath, e.ocrModel, e.visionModel, e.visionTimeout)
 	if err != nil {
 		msg := err.Error()
 		return extract.Result{Success: false, Method: "image", FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
@@ -68,6 +81,16 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 	if res.Description != "" {
 		metadata["description"] = res.Description
 	}
+	if res.OCRProvider != "" {
+		metadata["ocrProvider"] = res.OCRProvider
+	}
+	if res.BudgetExceeded {
+		metadata["budgetExceeded"] = "true"
+		metadata[extract.MetaWarning] = "vision skipped: tenant budget exceeded"
+	}
+	if res.NeedsOCR {
+		metadata["needsOcr"] = "true"
+	}
 
 	return extract.Result{
 		Success:   true,
@@ -80,3 +103,16 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 		CharCount: chars,
 	}, nil
 }
+
+// providerOption validates the optional "ocrProvider" request option.
+func (e *Extractor) providerOption(options map[string]any) (string, error) {
+	name, _, err := extract.StringOption(options, "ocrProvider")
+	if err != nil {
+		return "", err
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name != "" && !ocr.IsProviderName(name) {
+		return "", &extract.OptionError{Key: "ocrProvider", Reason: "must be one of " + strings.Join(ocr.ProviderNames, ", ")}
+	}
+	return name, nil
+}
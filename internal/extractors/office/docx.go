@@ -6,9 +6,12 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/format"
+	"github.com/toricodesthings/file-processing-service/internal/types"
 )
 
 type DOCXExtractor struct {
@@ -31,6 +34,10 @@ func (e *DOCXExtractor) SupportedTypes() []string {
 }
 func (e *DOCXExtractor) SupportedExtensions() []string { return []string{".docx"} }
 
+func (e *DOCXExtractor) Capabilities() extract.Capabilities {
+	return extract.Capabilities{Options: []string{"tableFormat"}}
+}
+
 func (e *DOCXExtractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
 	select {
 	case <-ctx.Done():
@@ -38,6 +45,12 @@ func (e *DOCXExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 	default:
 	}
 
+	tableFormat, err := extract.ParseTableFormat(job.Options)
+	if err != nil {
+		msg := err.Error()
+		return extract.Result{Success: false, FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
+	}
+
 	zr, err := zip.OpenReader(job.LocalPath)
 	if err != nil {
 		msg := err.Error()
@@ -51,7 +64,7 @@ func (e *DOCXExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 		return extract.Result{Success: false, FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
 	}
 
-	text := docxToMarkdown(body)
+	text, blocks, tables := docxToMarkdown(body, tableFormat)
 	meta := parseCoreMetadata(&zr.Reader, defaultMaxZipMetadataBytes)
 
 	// Prepend metadata frontmatter if available
@@ -61,15 +74,19 @@ func (e *DOCXExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 
 	text = strings.TrimSpace(text)
 	words, chars := extract.BuildCounts(text)
-	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: words, CharCount: chars}, nil
+	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: words, CharCount: chars, Blocks: blocks, Tables: tables}, nil
 }
 
-// docxToMarkdown walks <w:body> in word/document.xml producing markdown.
-// Handles paragraphs with heading styles, numbered/bulleted lists, and tables.
-func docxToMarkdown(b []byte) string {
+// docxToMarkdown walks <w:body> in word/document.xml producing markdown, the
+// matching blocks and the tables. Handles paragraphs with heading styles,
+// numbered/bulleted lists, and tables, rendered in tableFormat.
+func docxToMarkdown(b []byte, tableFormat string) (string, []types.Block, []types.Table) {
 	dec := xml.NewDecoder(strings.NewReader(string(b)))
 
-	var blocks []string
+	var out []string
+	var blocks []types.Block
+	var tables []types.Table
+	element := 0
 	for {
 		tok, err := dec.Token()
 		if err != nil {
@@ -79,29 +96,44 @@ func docxToMarkdown(b []byte) string {
 		if !ok {
 			continue
 		}
+		var md string
+		src := types.Source{Part: "word/document.xml", Element: element + 1}
 		switch se.Name.Local {
 		case "p":
-			blocks = append(blocks, docxParagraph(dec, se))
+			p := docxParagraph(dec, se)
+			md = p.markdown()
+			blocks = append(blocks, p.blocks(src)...)
 		case "tbl":
-			blocks = append(blocks, docxTable(dec))
+			table := docxTable(dec, src)
+			md = format.RenderTable(table, tableFormat)
+			if len(table.Cells) > 0 {
+				blocks = append(blocks, types.Block{Type: types.BlockTable, Cells: table.Cells, Source: src})
+				tables = append(tables, table)
+			}
+		default:
+			continue
 		}
-	}
-
-	var out []string
-	for _, b := range blocks {
-		b = strings.TrimSpace(b)
-		if b != "" {
-			out = append(out, b)
+		element++
+		if md = strings.TrimSpace(md); md != "" {
+			out = append(out, md)
 		}
 	}
-	return strings.Join(out, "\n\n")
+	return strings.Join(out, "\n\n"), blocks, tables
+}
+
+// docxPara is one <w:p> element as read from the XML.
+type docxPara struct {
+	style     string
+	numID     string
+	numLvl    string
+	text      string
+	images    []string // description or name of each inline drawing
+	pageBreak bool
 }
 
-// docxParagraph reads one <w:p> element and returns markdown text.
-func docxParagraph(dec *xml.Decoder, start xml.StartElement) string {
-	var style string
-	var numID string
-	var numLvl string
+// docxParagraph reads one <w:p> element.
+func docxParagraph(dec *xml.Decoder, start xml.StartElement) docxPara {
+	var p docxPara
 	var runs []string
 	depth := 1
 
@@ -115,23 +147,17 @@ func docxParagraph(dec *xml.Decoder, start xml.StartElement) string {
 			depth++
 			switch t.Name.Local {
 			case "pStyle":
-				for _, a := range t.Attr {
-					if a.Name.Local == "val" {
-						style = a.Value
-					}
-				}
+				p.style = xmlAttr(t, "val")
 			case "numId":
-				for _, a := range t.Attr {
-					if a.Name.Local == "val" {
-						numID = a.Value
-					}
-				}
+				p.numID = xmlAttr(t, "val")
 			case "ilvl":
-				for _, a := range t.Attr {
-					if a.Name.Local == "val" {
-						numLvl = a.Value
-					}
+				p.numLvl = xmlAttr(t, "val")
+			case "docPr":
+				name := xmlAttr(t, "descr")
+				if name == "" {
+					name = xmlAttr(t, "name")
 				}
+				p.images = append(p.images, name)
 			case "t":
 				text := readCharData(dec, &depth)
 				runs = append(runs, text)
@@ -139,39 +165,80 @@ func docxParagraph(dec *xml.Decoder, start xml.StartElement) string {
 				runs = append(runs, "\t")
 			case "br":
 				runs = append(runs, "\n")
+				if xmlAttr(t, "type") == "page" {
+					p.pageBreak = true
+				}
 			}
 		case xml.EndElement:
 			depth--
 		}
 	}
 
-	text := strings.Join(runs, "")
+	p.text = strings.Join(runs, "")
+	return p
+}
+
+// markdown renders the paragraph, or "" when it has no text.
+func (p docxPara) markdown() string {
+	text := p.text
 	if strings.TrimSpace(text) == "" {
 		return ""
 	}
 
 	// Check for heading styles (Heading1, Heading2, etc. or HeadingN patterns)
-	if h := headingLevel(style); h > 0 {
+	if h := headingLevel(p.style); h > 0 {
 		prefix := strings.Repeat("#", h)
 		return prefix + " " + strings.TrimSpace(text)
 	}
 
 	// List items
-	if numID != "" && numID != "0" {
-		indent := ""
-		if numLvl != "" && numLvl != "0" {
-			lvl := 0
-			for _, c := range numLvl {
-				lvl = lvl*10 + int(c-'0')
-			}
-			indent = strings.Repeat("  ", lvl)
-		}
-		return indent + "- " + strings.TrimSpace(text)
+	if p.isListItem() {
+		return strings.Repeat("  ", p.listLevel()) + "- " + strings.TrimSpace(text)
 	}
 
 	return strings.TrimSpace(text)
 }
 
+// blocks returns the paragraph's blocks: its text, then any images and a
+// page break it contains.
+func (p docxPara) blocks(src types.Source) []types.Block {
+	var out []types.Block
+	if text := strings.TrimSpace(p.text); text != "" {
+		style := strings.ToLower(p.style)
+		switch {
+		case headingLevel(p.style) > 0:
+			out = append(out, types.Block{Type: types.BlockHeading, Level: headingLevel(p.style), Text: text, Source: src})
+		case p.isListItem():
+			out = append(out, types.Block{Type: types.BlockListItem, Level: p.listLevel(), Text: text, Source: src})
+		case style == "quote" || style == "intensequote":
+			out = append(out, types.Block{Type: types.BlockQuote, Source: src, Children: []types.Block{
+				{Type: types.BlockParagraph, Text: text, Source: src},
+			}})
+		case strings.Contains(style, "code") || style == "htmlpreformatted":
+			out = append(out, types.Block{Type: types.BlockCode, Text: p.text, Source: src})
+		default:
+			out = append(out, types.Block{Type: types.BlockParagraph, Text: text, Source: src})
+		}
+	}
+	for _, name := range p.images {
+		out = append(out, types.Block{Type: types.BlockImage, Text: name, Source: src})
+	}
+	if p.pageBreak {
+		out = append(out, types.Block{Type: types.BlockPageBreak, Source: src})
+	}
+	return out
+}
+
+func (p docxPara) isListItem() bool { return p.numID != "" && p.numID != "0" }
+
+func (p docxPara) listLevel() int {
+	lvl := 0
+	for _, c := range p.numLvl {
+		lvl = lvl*10 + int(c-'0')
+	}
+	return lvl
+}
+
 // headingLevel returns the markdown heading level for OOXML paragraph styles.
 func headingLevel(style string) int {
 	s := strings.ToLower(style)
@@ -190,9 +257,24 @@ func headingLevel(style string) int {
 	return 0
 }
 
-// docxTable reads one <w:tbl> element and returns a markdown table.
-func docxTable(dec *xml.Decoder) string {
-	var rows [][]string
+// docxCell is one <w:tc> element: its text, the grid columns it spans and
+// its vertical merge state, "restart", "continue" or "" outside a merge.
+type docxCell struct {
+	text   string
+	span   int
+	vMerge string
+}
+
+// docxRow is one <w:tr> element; header marks a repeated header row.
+type docxRow struct {
+	cells  []docxCell
+	header bool
+}
+
+// docxTable reads one <w:tbl> element into a table with its merged cells.
+// Leading rows marked as repeating headers are the header rows.
+func docxTable(dec *xml.Decoder, src types.Source) types.Table {
+	var rows []docxRow
 	depth := 1
 
 	for depth > 0 {
@@ -205,45 +287,61 @@ func docxTable(dec *xml.Decoder) string {
 			depth++
 			if t.Name.Local == "tr" {
 				rows = append(rows, docxTableRow(dec, &depth))
+				depth-- // docxTableRow consumed the end tag
 			}
 		case xml.EndElement:
 			depth--
 		}
 	}
 
-	if len(rows) == 0 {
-		return ""
-	}
-
-	// Normalize column count
-	maxCols := 0
-	for _, row := range rows {
-		if len(row) > maxCols {
-			maxCols = len(row)
+	// Word marks vertical merges per cell: the first cell restarts the
+	// merge and the ones below continue it. Count the continuations under
+	// each restart to get its row span, then drop them so the layout sees
+	// only the cells that hold text.
+	cols := make([][]int, len(rows))
+	for r, row := range rows {
+		col := 0
+		for _, c := range row.cells {
+			cols[r] = append(cols[r], col)
+			col += max(c.span, 1)
 		}
 	}
-	for i := range rows {
-		for len(rows[i]) < maxCols {
-			rows[i] = append(rows[i], "")
+	continues := func(r, col int) bool {
+		for i, c := range rows[r].cells {
+			if cols[r][i] == col {
+				return c.vMerge == "continue"
+			}
 		}
+		return false
 	}
 
-	var sb strings.Builder
-	sb.WriteString("| " + strings.Join(rows[0], " | ") + " |\n")
-	sep := make([]string, maxCols)
-	for i := range sep {
-		sep[i] = "---"
-	}
-	sb.WriteString("| " + strings.Join(sep, " | ") + " |\n")
-	for _, row := range rows[1:] {
-		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	cells := make([][]format.TableCell, len(rows))
+	headers, body := 0, false
+	for r, row := range rows {
+		for i, c := range row.cells {
+			if c.vMerge == "continue" {
+				continue
+			}
+			cell := format.TableCell{Text: c.text, ColSpan: c.span, RowSpan: 1}
+			if c.vMerge == "restart" {
+				for below := r + 1; below < len(rows) && continues(below, cols[r][i]); below++ {
+					cell.RowSpan++
+				}
+			}
+			cells[r] = append(cells[r], cell)
+		}
+		if row.header && !body {
+			headers++
+		} else {
+			body = true
+		}
 	}
-	return sb.String()
+	return format.LayoutTable(cells, headers, src)
 }
 
-// docxTableRow reads one <w:tr> element and returns cell texts.
-func docxTableRow(dec *xml.Decoder, outerDepth *int) []string {
-	var cells []string
+// docxTableRow reads one <w:tr> element.
+func docxTableRow(dec *xml.Decoder, outerDepth *int) docxRow {
+	var row docxRow
 	depth := 0 // already counted by caller
 
 	for {
@@ -254,23 +352,28 @@ func docxTableRow(dec *xml.Decoder, outerDepth *int) []string {
 		switch t := tok.(type) {
 		case xml.StartElement:
 			depth++
-			if t.Name.Local == "tc" {
-				cells = append(cells, docxTableCell(dec, &depth))
+			switch t.Name.Local {
+			case "tc":
+				row.cells = append(row.cells, docxTableCell(dec, &depth))
+				depth-- // docxTableCell consumed the end tag
+			case "tblHeader":
+				row.header = xmlAttr(t, "val") != "0" && xmlAttr(t, "val") != "false"
 			}
 		case xml.EndElement:
 			if depth == 0 {
 				// end of <w:tr>
-				return cells
+				return row
 			}
 			depth--
 		}
 	}
-	return cells
+	return row
 }
 
-// docxTableCell reads one <w:tc> element and returns its text content.
-func docxTableCell(dec *xml.Decoder, outerDepth *int) string {
+// docxTableCell reads one <w:tc> element.
+func docxTableCell(dec *xml.Decoder, outerDepth *int) docxCell {
 	var texts []string
+	cell := docxCell{span: 1}
 	depth := 0
 
 	for {
@@ -281,17 +384,30 @@ func docxTableCell(dec *xml.Decoder, outerDepth *int) string {
 		switch t := tok.(type) {
 		case xml.StartElement:
 			depth++
-			if t.Name.Local == "t" {
+			switch t.Name.Local {
+			case "t":
 				texts = append(texts, readCharData(dec, &depth))
+			case "gridSpan":
+				if n, err := strconv.Atoi(xmlAttr(t, "val")); err == nil && n > 1 {
+					cell.span = n
+				}
+			case "vMerge":
+				// A bare <w:vMerge/> continues the merge above.
+				cell.vMerge = "continue"
+				if xmlAttr(t, "val") == "restart" {
+					cell.vMerge = "restart"
+				}
 			}
 		case xml.EndElement:
 			if depth == 0 {
-				return strings.TrimSpace(strings.Join(texts, " "))
+				cell.text = strings.TrimSpace(strings.Join(texts, " "))
+				return cell
 			}
 			depth--
 		}
 	}
-	return strings.TrimSpace(strings.Join(texts, " "))
+	cell.text = strings.TrimSpace(strings.Join(texts, " "))
+	return cell
 }
 
 // readCharData reads character data inside a text element, tracking depth.
@@ -317,6 +433,16 @@ func readCharData(dec *xml.Decoder, depth *int) string {
 
 // --- Shared helpers ---
 
+// xmlAttr returns the value of the attribute with the given local name.
+func xmlAttr(se xml.StartElement, local string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
 func readZipFile(zr *zip.Reader, name string, maxBytes int64) ([]byte, error) {
 	if maxBytes <= 0 {
 		maxBytes = defaultMaxZipEntryBytes
@@ -0,0 +1,91 @@
+package office
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+func TestDOCXToMarkdownBuildsBlocks(t *testing.T) {
+	t.Parallel()
+
+	doc := `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+		xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"><w:body>
+		<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>Results</w:t></w:r></w:p>
+		<w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="3"/></w:numPr></w:pPr><w:r><w:t>nested item</w:t></w:r></w:p>
+		<w:p/>
+		<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Name</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Score</w:t></w:r></w:p></w:tc></w:tr>
+		<w:tr><w:tc><w:p><w:r><w:t>Ada</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+		<w:p><w:pPr><w:pStyle w:val="Quote"/></w:pPr><w:r><w:t>Quoted</w:t></w:r><w:r><w:drawing><wp:inline><wp:docPr id="1" name="Picture 1" descr="A chart"/></wp:inline></w:drawing></w:r><w:r><w:br w:type="page"/></w:r></w:p>
+	</w:body></w:document>`
+
+	text, blocks, _ := docxToMarkdown([]byte(doc), "markdown")
+	if want := "## Results\n\n- nested item\n\n| Name | Score |\n| --- | --- |\n| Ada |  |\n\nQuoted"; text != want {
+		t.Fatalf("text = %q, want %q", text, want)
+	}
+
+	want := []types.Block{
+		{Type: types.BlockHeading, Level: 2, Text: "Results"},
+		{Type: types.BlockListItem, Level: 1, Text: "nested item"},
+		{Type: types.BlockTable, Cells: [][]string{{"Name", "Score"}, {"Ada", ""}}},
+		{Type: types.BlockQuote},
+		{Type: types.BlockImage, Text: "A chart"},
+		{Type: types.BlockPageBreak},
+	}
+	if len(blocks) != len(want) {
+		t.Fatalf("blocks = %+v", blocks)
+	}
+	for i, w := range want {
+		b := blocks[i]
+		if b.Type != w.Type || b.Level != w.Level || b.Text != w.Text || len(b.Cells) != len(w.Cells) {
+			t.Fatalf("block %d = %+v, want %+v", i, b, w)
+		}
+	}
+	if blocks[2].Cells[1][1] != "" || blocks[3].Children[0].Text != "Quoted" {
+		t.Fatalf("unexpected table or quote: %+v", blocks)
+	}
+	// The empty paragraph still counts as a body element.
+	if blocks[2].Source.Element != 4 || blocks[2].Source.Part != "word/document.xml" {
+		t.Fatalf("table source = %+v", blocks[2].Source)
+	}
+}
+
+func TestDOCXTableMerges(t *testing.T) {
+	t.Parallel()
+
+	cell := func(props, text string) string {
+		return `<w:tc><w:tcPr>` + props + `</w:tcPr><w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:tc>`
+	}
+	doc := `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body><w:tbl>
+		<w:tr><w:trPr><w:tblHeader/></w:trPr>` + cell(`<w:gridSpan w:val="2"/>`, "Region") + cell("", "Total") + `</w:tr>
+		<w:tr>` + cell(`<w:vMerge w:val="restart"/>`, "North") + cell("", "Q1") + cell("", "10") + `</w:tr>
+		<w:tr>` + cell(`<w:vMerge/>`, "") + cell("", "Q2") + cell("", "12") + `</w:tr>
+	</w:tbl></w:body></w:document>`
+
+	text, _, tables := docxToMarkdown([]byte(doc), "html")
+	if len(tables) != 1 {
+		t.Fatalf("tables = %+v", tables)
+	}
+	got := tables[0]
+	want := [][]string{{"Region", "", "Total"}, {"North", "Q1", "10"}, {"", "Q2", "12"}}
+	for r := range want {
+		for c := range want[r] {
+			if got.Cells[r][c] != want[r][c] {
+				t.Fatalf("cells = %q, want %q", got.Cells, want)
+			}
+		}
+	}
+	if got.HeaderRows != 1 || len(got.Merges) != 2 {
+		t.Fatalf("headerRows = %d, merges = %+v", got.HeaderRows, got.Merges)
+	}
+	if m := got.Merges[0]; m != (types.Merge{Row: 0, Col: 0, RowSpan: 1, ColSpan: 2}) {
+		t.Fatalf("column merge = %+v", m)
+	}
+	if m := got.Merges[1]; m != (types.Merge{Row: 1, Col: 0, RowSpan: 2, ColSpan: 1}) {
+		t.Fatalf("row merge = %+v", m)
+	}
+	if !strings.Contains(text, `<th colspan="2">Region</th>`) || !strings.Contains(text, `<td rowspan="2">North</td>`) {
+		t.Fatalf("text = %q", text)
+	}
+}
@@ -34,15 +34,13 @@ func (e *LegacyExtractor) SupportedTypes() []string {
 	return []string{"application/msword", "application/vnd.ms-excel", "application/vnd.ms-powerpoint"}
 }
 func (e *LegacyExtractor) SupportedExtensions() []string { return []string{".doc", ".xls", ".ppt"} }
+func (e *LegacyExtractor) Capabilities() extract.Capabilities {
+	return extract.Capabilities{Binaries: []string{e.binary}}
+}
 
 func (e *LegacyExtractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
-	localCtx, cancel := context.WithTimeout(ctx, e.timeout)
-	defer cancel()
-
-	outDir := filepath.Dir(job.LocalPath)
-	cmd := exec.CommandContext(localCtx, e.binary, "--headless", "--convert-to", "txt:Text", "--outdir", outDir, job.LocalPath)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		msg := fmt.Sprintf("libreoffice conversion failed: %v: %s", err, strings.TrimSpace(string(out)))
+	if err := sofficeConvert(ctx, e.binary, e.timeout, job.LocalPath, "txt:Text"); err != nil {
+		msg := err.Error()
 		return extract.Result{Success: false, Method: "libreoffice", FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
 	}
 
@@ -57,3 +55,16 @@ func (e *LegacyExtractor) Extract(ctx context.Context, job extract.Job) (extract
 	words, chars := extract.BuildCounts(text)
 	return extract.Result{Success: true, Text: text, Method: "libreoffice", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: words, CharCount: chars}, nil
 }
+
+// sofficeConvert runs a headless LibreOffice conversion of path into its own
+// directory. filter is a --convert-to argument such as "txt:Text".
+func sofficeConvert(ctx context.Context, binary string, timeout time.Duration, path, filter string) error {
+	localCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(localCtx, binary, "--headless", "--convert-to", filter, "--outdir", filepath.Dir(path), path)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("libreoffice conversion failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
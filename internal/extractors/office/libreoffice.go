@@ -0,0 +1,183 @@
+package office
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/format"
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+// sheetsCSVFilter exports every sheet (the trailing -1) to its own
+// <name>-<sheet>.csv as UTF-8 with comma separators. LibreOffice before 7.2
+// ignores the sheet selector and writes only the first sheet to <name>.csv.
+const sheetsCSVFilter = `csv:Text - txt - csv (StarCalc):44,34,76,1,,0,false,true,false,false,false,-1`
+
+// FallbackExtractor converts OOXML and OpenDocument files with LibreOffice.
+// It is registered below the native parsers and runs when they reject a
+// file, which LibreOffice often repairs on load.
+type FallbackExtractor struct {
+	binary  string
+	timeout time.Duration
+	maxSize int64
+}
+
+func NewFallback(binary string, timeout time.Duration, maxSize int64) *FallbackExtractor {
+	if strings.TrimSpace(binary) == "" {
+		binary = "soffice"
+	}
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	return &FallbackExtractor{binary: binary, timeout: timeout, maxSize: maxSize}
+}
+
+func (e *FallbackExtractor) Name() string       { return "document/libreoffice" }
+func (e *FallbackExtractor) MaxFileSize() int64 { return e.maxSize }
+func (e *FallbackExtractor) SupportedTypes() []string {
+	return []string{
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.oasis.opendocument.text",
+		"application/vnd.oasis.opendocument.spreadsheet",
+	}
+}
+func (e *FallbackExtractor) SupportedExtensions() []string {
+	return []string{".docx", ".xlsx", ".odt", ".ods"}
+}
+func (e *FallbackExtractor) Capabilities() extract.Capabilities {
+	return extract.Capabilities{Options: []string{"tableFormat"}, Binaries: []string{e.binary}}
+}
+
+func (e *FallbackExtractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
+	tableFormat, err := extract.ParseTableFormat(job.Options)
+	if err != nil {
+		msg := err.Error()
+		return extract.Result{Success: false, Method: "libreoffice", FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
+	}
+
+	var (
+		text   string
+		meta   map[string]string
+		blocks []types.Block
+		tables []types.Table
+	)
+	if isSpreadsheet(job) {
+		text, meta, blocks, tables, err = e.sheets(ctx, job.LocalPath, tableFormat)
+	} else {
+		text, err = e.text(ctx, job.LocalPath)
+		blocks = format.TextBlocks(text)
+	}
+	if err != nil {
+		msg := err.Error()
+		return extract.Result{Success: false, Method: "libreoffice", FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
+	}
+
+	words, chars := extract.BuildCounts(text)
+	return extract.Result{Success: true, Text: text, Method: "libreoffice", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: words, CharCount: chars, Blocks: blocks, Tables: tables}, nil
+}
+
+func isSpreadsheet(job extract.Job) bool {
+	switch strings.ToLower(filepath.Ext(job.LocalPath)) {
+	case ".xlsx", ".ods":
+		return true
+	}
+	return strings.Contains(job.MIMEType, "spreadsheet")
+}
+
+func (e *FallbackExtractor) text(ctx context.Context, path string) (string, error) {
+	if err := sofficeConvert(ctx, e.binary, e.timeout, path, "txt:Text"); err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(strings.TrimSuffix(path, filepath.Ext(path)) + ".txt")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// sheets exports each sheet to CSV and renders it the way the native XLSX
+// extractor does, without merged cells, which CSV loses. Sheets come out in
+// name order, not workbook order.
+func (e *FallbackExtractor) sheets(ctx context.Context, path, tableFormat string) (string, map[string]string, []types.Block, []types.Table, error) {
+	if err := sofficeConvert(ctx, e.binary, e.timeout, path, sheetsCSVFilter); err != nil {
+		return "", nil, nil, nil, err
+	}
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	files, err := filepath.Glob(globEscape(base) + "*.csv")
+	if err != nil || len(files) == 0 {
+		return "", nil, nil, nil, fmt.Errorf("libreoffice produced no CSV output")
+	}
+	sort.Strings(files)
+
+	var sections []string
+	var blocks []types.Block
+	var tables []types.Table
+	totalRows := 0
+	for _, f := range files {
+		rows, err := readCSVRows(f)
+		if err != nil || len(rows) == 0 {
+			continue
+		}
+		totalRows += len(rows)
+		sheet := strings.TrimPrefix(strings.TrimSuffix(filepath.Base(f), ".csv"), filepath.Base(base))
+		sheet = strings.TrimPrefix(sheet, "-")
+		if sheet == "" {
+			sheet = "Sheet1"
+		}
+		table, truncated := sheetTable(rows, sheet)
+		blocks = append(blocks, types.Block{Type: types.BlockTable, Cells: table.Cells, Source: table.Source})
+		tables = append(tables, table)
+		sections = append(sections, sheetSection(table, truncated, tableFormat))
+	}
+
+	text := strings.Join(sections, "\n\n---\n\n")
+	if strings.TrimSpace(text) == "" {
+		text = "(empty workbook)"
+	}
+	meta := map[string]string{
+		"sheets":    fmt.Sprintf("%d", len(files)),
+		"totalRows": fmt.Sprintf("%d", totalRows),
+	}
+	return text, meta, blocks, tables, nil
+}
+
+// readCSVRows reads a CSV file, skipping rows with no content.
+func readCSVRows(path string) ([][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	all, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]string, 0, len(all))
+	for _, row := range all {
+		for _, cell := range row {
+			if strings.TrimSpace(cell) != "" {
+				rows = append(rows, row)
+				break
+			}
+		}
+	}
+	return rows, nil
+}
+
+// globEscape quotes the glob metacharacters filepath.Glob would interpret
+// in a literal path.
+func globEscape(path string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`).Replace(path)
+}
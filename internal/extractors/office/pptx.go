@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/types"
 )
 
 type PPTXExtractor struct {
@@ -57,6 +58,7 @@ func (e *PPTXExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 	meta["slides"] = fmt.Sprintf("%d", len(slideNames))
 
 	parts := make([]string, 0, len(slideNames))
+	var blocks []types.Block
 	for i, name := range slideNames {
 		slideNum := i + 1
 		var sb strings.Builder
@@ -67,7 +69,12 @@ func (e *PPTXExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 		if err != nil {
 			continue
 		}
-		slideText := pptxExtractTextBlocks(b)
+		slideBlocks := pptxSlideBlocks(b, types.Source{Part: name, Page: slideNum})
+		if i > 0 {
+			blocks = append(blocks, types.Block{Type: types.BlockPageBreak, Source: types.Source{Page: slideNum - 1}})
+		}
+		blocks = append(blocks, slideBlocks...)
+		slideText := pptxBlocksText(slideBlocks)
 		if slideText != "" {
 			sb.WriteString("\n\n" + slideText)
 		}
@@ -75,10 +82,11 @@ func (e *PPTXExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 		// Extract speaker notes from ppt/notesSlides/notesSlideN.xml
 		notesPath := fmt.Sprintf("ppt/notesSlides/notesSlide%d.xml", slideNum)
 		if nb, err := readZipFile(&zr.Reader, notesPath, defaultMaxZipEntryBytes); err == nil {
-			notesText := pptxExtractTextBlocks(nb)
+			notesBlocks := pptxSlideBlocks(nb, types.Source{Part: notesPath, Page: slideNum})
 			// Filter out the slide number placeholder text that's often in notes
-			notesText = strings.TrimSpace(notesText)
+			notesText := strings.TrimSpace(pptxBlocksText(notesBlocks))
 			if notesText != "" {
+				blocks = append(blocks, types.Block{Type: types.BlockQuote, Children: notesBlocks, Source: types.Source{Part: notesPath, Page: slideNum}})
 				sb.WriteString("\n\n> **Speaker Notes:**\n> " + strings.ReplaceAll(notesText, "\n", "\n> "))
 			}
 		}
@@ -94,16 +102,23 @@ func (e *PPTXExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 
 	text = strings.TrimSpace(text)
 	words, chars := extract.BuildCounts(text)
-	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: words, CharCount: chars}, nil
+	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: words, CharCount: chars, Blocks: blocks}, nil
 }
 
-// pptxExtractTextBlocks walks OOXML slide/notes XML and returns text organized by paragraphs.
-// Groups <a:p> elements, joining <a:r>/<a:t> text runs within each paragraph.
-func pptxExtractTextBlocks(b []byte) string {
+// pptxSlideBlocks walks OOXML slide/notes XML and returns its paragraphs,
+// joining the <a:r>/<a:t> text runs of each <a:p>. Paragraphs in a title
+// placeholder become headings, and pictures become image placeholders.
+// Each block's source is src numbered by its position on the slide.
+func pptxSlideBlocks(b []byte, src types.Source) []types.Block {
 	dec := xml.NewDecoder(strings.NewReader(string(b)))
-	var paragraphs []string
+	var blocks []types.Block
 	var currentPara []string
-	inParagraph := false
+	inParagraph, inTitle, inPicture := false, false, false
+	add := func(blk types.Block) {
+		blk.Source = src
+		blk.Source.Element = len(blocks) + 1
+		blocks = append(blocks, blk)
+	}
 
 	for {
 		tok, err := dec.Token()
@@ -113,6 +128,22 @@ func pptxExtractTextBlocks(b []byte) string {
 		switch t := tok.(type) {
 		case xml.StartElement:
 			switch t.Name.Local {
+			case "sp":
+				inTitle = false
+			case "ph":
+				if typ := xmlAttr(t, "type"); typ == "title" || typ == "ctrTitle" {
+					inTitle = true
+				}
+			case "pic":
+				inPicture = true
+			case "cNvPr":
+				if inPicture {
+					name := xmlAttr(t, "descr")
+					if name == "" {
+						name = xmlAttr(t, "name")
+					}
+					add(types.Block{Type: types.BlockImage, Text: name})
+				}
 			case "p":
 				if t.Name.Space == "http://schemas.openxmlformats.org/drawingml/2006/main" || t.Name.Space == "" {
 					inParagraph = true
@@ -127,17 +158,34 @@ func pptxExtractTextBlocks(b []byte) string {
 				}
 			}
 		case xml.EndElement:
-			if t.Name.Local == "p" && inParagraph {
+			switch {
+			case t.Name.Local == "pic":
+				inPicture = false
+			case t.Name.Local == "p" && inParagraph:
 				text := strings.TrimSpace(strings.Join(currentPara, " "))
 				if text != "" {
-					paragraphs = append(paragraphs, text)
+					if inTitle {
+						add(types.Block{Type: types.BlockHeading, Level: 1, Text: text})
+					} else {
+						add(types.Block{Type: types.BlockParagraph, Text: text})
+					}
 				}
 				inParagraph = false
 				currentPara = nil
 			}
 		}
 	}
+	return blocks
+}
 
+// pptxBlocksText joins the text of a slide's blocks into paragraphs.
+func pptxBlocksText(blocks []types.Block) string {
+	var paragraphs []string
+	for _, blk := range blocks {
+		if blk.Type != types.BlockImage {
+			paragraphs = append(paragraphs, blk.Text)
+		}
+	}
 	return strings.Join(paragraphs, "\n\n")
 }
 
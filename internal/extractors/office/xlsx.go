@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/format"
+	"github.com/toricodesthings/file-processing-service/internal/types"
 	"github.com/xuri/excelize/v2"
 )
 
@@ -24,6 +26,10 @@ func (e *XLSXExtractor) SupportedTypes() []string {
 }
 func (e *XLSXExtractor) SupportedExtensions() []string { return []string{".xlsx"} }
 
+func (e *XLSXExtractor) Capabilities() extract.Capabilities {
+	return extract.Capabilities{Options: []string{"tableFormat"}}
+}
+
 func (e *XLSXExtractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
 	select {
 	case <-ctx.Done():
@@ -31,6 +37,12 @@ func (e *XLSXExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 	default:
 	}
 
+	tableFormat, err := extract.ParseTableFormat(job.Options)
+	if err != nil {
+		msg := err.Error()
+		return extract.Result{Success: false, FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
+	}
+
 	f, err := excelize.OpenFile(job.LocalPath)
 	if err != nil {
 		msg := err.Error()
@@ -44,6 +56,8 @@ func (e *XLSXExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 	}
 
 	var sections []string
+	var blocks []types.Block
+	var tables []types.Table
 	totalRows := 0
 	for _, sheet := range sheets {
 		rows, err := f.GetRows(sheet)
@@ -53,7 +67,8 @@ func (e *XLSXExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 
 		// Skip entirely empty rows
 		filtered := make([][]string, 0, len(rows))
-		for _, row := range rows {
+		var kept []int
+		for i, row := range rows {
 			empty := true
 			for _, cell := range row {
 				if strings.TrimSpace(cell) != "" {
@@ -63,6 +78,7 @@ func (e *XLSXExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 			}
 			if !empty {
 				filtered = append(filtered, row)
+				kept = append(kept, i+1)
 			}
 		}
 		if len(filtered) == 0 {
@@ -70,8 +86,13 @@ func (e *XLSXExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 		}
 
 		totalRows += len(filtered)
-		table := xlsxRowsToMarkdown(filtered)
-		sections = append(sections, "## Sheet: "+sheet+"\n\n"+table)
+		table, truncated := sheetTable(filtered, sheet)
+		if merged, err := f.GetMergeCells(sheet); err == nil {
+			sheetMerges(&table, merged, kept)
+		}
+		blocks = append(blocks, types.Block{Type: types.BlockTable, Cells: table.Cells, Source: table.Source})
+		tables = append(tables, table)
+		sections = append(sections, sheetSection(table, truncated, tableFormat))
 	}
 
 	text := strings.Join(sections, "\n\n---\n\n")
@@ -82,48 +103,70 @@ func (e *XLSXExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 	meta["totalRows"] = fmt.Sprintf("%d", totalRows)
 
 	words, chars := extract.BuildCounts(text)
-	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: words, CharCount: chars}, nil
+	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: words, CharCount: chars, Blocks: blocks, Tables: tables}, nil
 }
 
-func xlsxRowsToMarkdown(rows [][]string) string {
-	maxCols := 0
-	for _, row := range rows {
-		if len(row) > maxCols {
-			maxCols = len(row)
-		}
-	}
-	if maxCols == 0 {
-		return ""
-	}
+// maxSheetRows caps the rows kept per sheet: a header and 1000 data rows.
+const maxSheetRows = 1001
 
-	// Pad rows to uniform column count and escape pipe chars
-	for i := range rows {
-		for len(rows[i]) < maxCols {
-			rows[i] = append(rows[i], "")
-		}
-		for j := range rows[i] {
-			rows[i][j] = strings.ReplaceAll(rows[i][j], "|", "\\|")
-		}
+// sheetTable builds a sheet's table from its non-empty rows, cut to
+// maxSheetRows. truncated reports whether rows were cut.
+func sheetTable(rows [][]string, sheet string) (t types.Table, truncated bool) {
+	if len(rows) > maxSheetRows {
+		rows = rows[:maxSheetRows]
+		truncated = true
 	}
+	return format.NewTable(rows, 1, types.Source{Sheet: sheet}), truncated
+}
 
-	truncated := false
-	if len(rows) > 1001 {
-		rows = rows[:1001]
-		truncated = true
+// sheetSection renders a sheet under its heading in tableFormat.
+func sheetSection(t types.Table, truncated bool, tableFormat string) string {
+	section := "## Sheet: " + t.Source.Sheet + "\n\n" + format.RenderTable(t, tableFormat)
+	if truncated {
+		section += "\n\n... truncated to first 1000 data rows"
 	}
+	return section
+}
 
-	var sb strings.Builder
-	sb.WriteString("| " + strings.Join(rows[0], " | ") + " |\n")
-	sep := make([]string, maxCols)
-	for i := range sep {
-		sep[i] = "---"
+// sheetMerges maps a sheet's merged ranges onto its table. kept holds the
+// 1-based sheet row of each table row; ranges starting on a dropped row or
+// outside the table are skipped, and the rest are clipped to it.
+func sheetMerges(t *types.Table, ranges []excelize.MergeCell, kept []int) {
+	index := make(map[int]int, len(kept))
+	for i, r := range kept {
+		index[r] = i
 	}
-	sb.WriteString("| " + strings.Join(sep, " | ") + " |\n")
-	for _, row := range rows[1:] {
-		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	width := 0
+	if len(t.Cells) > 0 {
+		width = len(t.Cells[0])
 	}
-	if truncated {
-		sb.WriteString("\n... truncated to first 1000 data rows\n")
+	for _, m := range ranges {
+		col, row, err := excelize.CellNameToCoordinates(m.GetStartAxis())
+		if err != nil {
+			continue
+		}
+		endCol, endRow, err := excelize.CellNameToCoordinates(m.GetEndAxis())
+		if err != nil {
+			continue
+		}
+		start, ok := index[row]
+		if !ok || col > width {
+			continue
+		}
+		merge := types.Merge{Row: start, Col: col - 1, RowSpan: 1, ColSpan: min(endCol, width) - col + 1}
+		for i := start + 1; i < len(kept) && kept[i] <= endRow; i++ {
+			merge.RowSpan++
+		}
+		if merge.RowSpan == 1 && merge.ColSpan == 1 {
+			continue
+		}
+		for dr := 0; dr < merge.RowSpan; dr++ {
+			for dc := 0; dc < merge.ColSpan; dc++ {
+				if dr > 0 || dc > 0 {
+					t.Cells[start+dr][merge.Col+dc] = ""
+				}
+			}
+		}
+		t.Merges = append(t.Merges, merge)
 	}
-	return sb.String()
 }
@@ -6,9 +6,18 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/format"
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+const (
+	nsText  = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	nsTable = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	nsDraw  = "urn:oasis:names:tc:opendocument:xmlns:drawing:1.0"
 )
 
 type Extractor struct {
@@ -24,6 +33,10 @@ func (e *Extractor) SupportedTypes() []string {
 }
 func (e *Extractor) SupportedExtensions() []string { return []string{".odt", ".ods", ".odp"} }
 
+func (e *Extractor) Capabilities() extract.Capabilities {
+	return extract.Capabilities{Options: []string{"tableFormat"}}
+}
+
 func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
 	select {
 	case <-ctx.Done():
@@ -31,6 +44,12 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 	default:
 	}
 
+	tableFormat, err := extract.ParseTableFormat(job.Options)
+	if err != nil {
+		msg := err.Error()
+		return extract.Result{Success: false, FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
+	}
+
 	zr, err := zip.OpenReader(job.LocalPath)
 	if err != nil {
 		msg := err.Error()
@@ -44,7 +63,7 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 		return extract.Result{Success: false, FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
 	}
 
-	text := odfToMarkdown(content)
+	text, blocks, tables := odfToMarkdown(content, tableFormat)
 	meta := odfParseMetadata(zr)
 
 	if len(meta) > 0 {
@@ -53,13 +72,24 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 
 	text = strings.TrimSpace(text)
 	words, chars := extract.BuildCounts(text)
-	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: words, CharCount: chars}, nil
+	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: words, CharCount: chars, Blocks: blocks, Tables: tables}, nil
 }
 
-// odfToMarkdown walks ODF content.xml and produces markdown.
-func odfToMarkdown(b []byte) string {
+// odfToMarkdown walks ODF content.xml and produces markdown, the matching
+// blocks and the tables, rendered in tableFormat. Presentation pages are
+// numbered in the blocks' sources and spreadsheet tables carry their sheet
+// name.
+func odfToMarkdown(b []byte, tableFormat string) (string, []types.Block, []types.Table) {
 	dec := xml.NewDecoder(strings.NewReader(string(b)))
-	var blocks []string
+	var mdBlocks []string
+	var blocks []types.Block
+	var tables []types.Table
+	page, element := 0, 0
+	var frame string
+	src := func() types.Source {
+		element++
+		return types.Source{Part: "content.xml", Page: page, Element: element}
+	}
 
 	for {
 		tok, err := dec.Token()
@@ -72,7 +102,7 @@ func odfToMarkdown(b []byte) string {
 		}
 
 		switch {
-		case se.Name.Local == "h" && se.Name.Space == "urn:oasis:names:tc:opendocument:xmlns:text:1.0":
+		case se.Name.Local == "h" && se.Name.Space == nsText:
 			// Heading element - extract outline level
 			level := 1
 			for _, a := range se.Attr {
@@ -82,37 +112,89 @@ func odfToMarkdown(b []byte) string {
 					}
 				}
 			}
-			text := odfCollectText(dec, "h")
+			at := src()
+			text, images := odfCollectParagraph(dec)
 			if text != "" {
-				blocks = append(blocks, strings.Repeat("#", level)+" "+text)
+				mdBlocks = append(mdBlocks, strings.Repeat("#", level)+" "+text)
+				blocks = append(blocks, types.Block{Type: types.BlockHeading, Level: level, Text: text, Source: at})
 			}
+			blocks = append(blocks, odfImageBlocks(images, at)...)
 
-		case se.Name.Local == "p" && se.Name.Space == "urn:oasis:names:tc:opendocument:xmlns:text:1.0":
-			text := odfCollectText(dec, "p")
+		case se.Name.Local == "p" && se.Name.Space == nsText:
+			at := src()
+			text, images := odfCollectParagraph(dec)
 			if text != "" {
-				blocks = append(blocks, text)
+				mdBlocks = append(mdBlocks, text)
+				blocks = append(blocks, types.Block{Type: types.BlockParagraph, Text: text, Source: at})
 			}
+			blocks = append(blocks, odfImageBlocks(images, at)...)
 
-		case se.Name.Local == "list" && se.Name.Space == "urn:oasis:names:tc:opendocument:xmlns:text:1.0":
+		case se.Name.Local == "list" && se.Name.Space == nsText:
+			at := src()
 			items := odfCollectList(dec, 0)
 			if len(items) > 0 {
-				blocks = append(blocks, strings.Join(items, "\n"))
+				lines := make([]string, len(items))
+				for i, it := range items {
+					lines[i] = strings.Repeat("  ", it.level) + "- " + it.text
+					blocks = append(blocks, types.Block{Type: types.BlockListItem, Level: it.level, Text: it.text, Source: at})
+				}
+				mdBlocks = append(mdBlocks, strings.Join(lines, "\n"))
+			}
+
+		case se.Name.Local == "table" && se.Name.Space == nsTable:
+			at := src()
+			at.Sheet = xmlAttr(se, "name")
+			table := odfCollectTable(dec, at)
+			if len(table.Cells) > 0 {
+				mdBlocks = append(mdBlocks, format.RenderTable(table, tableFormat))
+				blocks = append(blocks, types.Block{Type: types.BlockTable, Cells: table.Cells, Source: at})
+				tables = append(tables, table)
 			}
 
-		case se.Name.Local == "table" && se.Name.Space == "urn:oasis:names:tc:opendocument:xmlns:table:1.0":
-			table := odfCollectTable(dec)
-			if table != "" {
-				blocks = append(blocks, table)
+		case se.Name.Local == "soft-page-break" && se.Name.Space == nsText:
+			blocks = append(blocks, types.Block{Type: types.BlockPageBreak, Source: types.Source{Part: "content.xml", Page: page, Element: element}})
+
+		case se.Name.Local == "frame" && se.Name.Space == nsDraw:
+			frame = xmlAttr(se, "name")
+
+		case se.Name.Local == "image" && se.Name.Space == nsDraw:
+			// Frames outside paragraphs, as on presentation pages.
+			blocks = append(blocks, types.Block{Type: types.BlockImage, Text: frame, Source: src()})
+
+		case se.Name.Local == "page" && se.Name.Space == nsDraw:
+			if page > 0 {
+				blocks = append(blocks, types.Block{Type: types.BlockPageBreak, Source: types.Source{Part: "content.xml", Page: page}})
 			}
+			page++
+			element = 0
 		}
 	}
 
-	return strings.Join(blocks, "\n\n")
+	return strings.Join(mdBlocks, "\n\n"), blocks, tables
 }
 
-// odfCollectText reads all text inside an element until its closing tag.
-func odfCollectText(dec *xml.Decoder, endTag string) string {
-	var texts []string
+func odfImageBlocks(names []string, src types.Source) []types.Block {
+	var out []types.Block
+	for _, name := range names {
+		out = append(out, types.Block{Type: types.BlockImage, Text: name, Source: src})
+	}
+	return out
+}
+
+func xmlAttr(se xml.StartElement, local string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// odfCollectParagraph reads a paragraph or heading until its closing tag and
+// returns its text and the names of the frames holding images in it.
+func odfCollectParagraph(dec *xml.Decoder) (string, []string) {
+	var texts, images []string
+	var frame string
 	depth := 1
 	for depth > 0 {
 		tok, err := dec.Token()
@@ -122,10 +204,15 @@ func odfCollectText(dec *xml.Decoder, endTag string) string {
 		switch t := tok.(type) {
 		case xml.StartElement:
 			depth++
-			if t.Name.Local == "tab" {
+			switch t.Name.Local {
+			case "tab":
 				texts = append(texts, "\t")
-			} else if t.Name.Local == "line-break" {
+			case "line-break":
 				texts = append(texts, "\n")
+			case "frame":
+				frame = xmlAttr(t, "name")
+			case "image":
+				images = append(images, frame)
 			}
 		case xml.EndElement:
 			depth--
@@ -136,14 +223,25 @@ func odfCollectText(dec *xml.Decoder, endTag string) string {
 			}
 		}
 	}
-	return strings.TrimSpace(strings.Join(texts, ""))
+	return strings.TrimSpace(strings.Join(texts, "")), images
+}
+
+// odfCollectText reads all text inside an element until its closing tag.
+func odfCollectText(dec *xml.Decoder, endTag string) string {
+	text, _ := odfCollectParagraph(dec)
+	return text
 }
 
-// odfCollectList reads a text:list element and returns markdown list items.
-func odfCollectList(dec *xml.Decoder, indentLevel int) []string {
-	var items []string
+type odfListItem struct {
+	level int
+	text  string
+}
+
+// odfCollectList reads a text:list element and returns its items in order,
+// nested lists included.
+func odfCollectList(dec *xml.Decoder, indentLevel int) []odfListItem {
+	var items []odfListItem
 	depth := 1
-	indent := strings.Repeat("  ", indentLevel)
 
 	for depth > 0 {
 		tok, err := dec.Token()
@@ -157,7 +255,7 @@ func odfCollectList(dec *xml.Decoder, indentLevel int) []string {
 				text := odfCollectText(dec, "p")
 				depth-- // odfCollectText consumed the end tag
 				if text != "" {
-					items = append(items, indent+"- "+text)
+					items = append(items, odfListItem{level: indentLevel, text: text})
 				}
 			} else if t.Name.Local == "list" {
 				sub := odfCollectList(dec, indentLevel+1)
@@ -171,9 +269,12 @@ func odfCollectList(dec *xml.Decoder, indentLevel int) []string {
 	return items
 }
 
-// odfCollectTable reads a table:table element and returns a markdown table.
-func odfCollectTable(dec *xml.Decoder) string {
-	var rows [][]string
+// odfCollectTable reads a table:table element into a table with its merged
+// cells. Rows inside table:header-rows are the header rows.
+func odfCollectTable(dec *xml.Decoder, src types.Source) types.Table {
+	var rows [][]format.TableCell
+	headers := 0
+	inHeader := false
 	depth := 1
 
 	for depth > 0 {
@@ -184,50 +285,35 @@ func odfCollectTable(dec *xml.Decoder) string {
 		switch t := tok.(type) {
 		case xml.StartElement:
 			depth++
-			if t.Name.Local == "table-row" {
-				row := odfCollectTableRow(dec)
+			switch t.Name.Local {
+			case "table-header-rows":
+				inHeader = true
+			case "table-row":
+				row, covered := odfCollectTableRow(dec)
 				depth-- // consumed by recursive function
-				if len(row) > 0 {
+				// Rows of covered cells only are kept so spans from
+				// above still line up.
+				if len(row) > 0 || covered {
 					rows = append(rows, row)
+					if inHeader {
+						headers++
+					}
 				}
 			}
 		case xml.EndElement:
 			depth--
+			if t.Name.Local == "table-header-rows" {
+				inHeader = false
+			}
 		}
 	}
-
-	if len(rows) == 0 {
-		return ""
-	}
-
-	// Normalize column count
-	maxCols := 0
-	for _, row := range rows {
-		if len(row) > maxCols {
-			maxCols = len(row)
-		}
-	}
-	for i := range rows {
-		for len(rows[i]) < maxCols {
-			rows[i] = append(rows[i], "")
-		}
-	}
-
-	var sb strings.Builder
-	sb.WriteString("| " + strings.Join(rows[0], " | ") + " |\n")
-	sep := make([]string, maxCols)
-	for i := range sep {
-		sep[i] = "---"
-	}
-	sb.WriteString("| " + strings.Join(sep, " | ") + " |\n")
-	for _, row := range rows[1:] {
-		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
-	}
-	return sb.String()
+	return format.LayoutTable(rows, headers, src)
 }
 
-func odfCollectTableRow(dec *xml.Decoder) []string {
-	var cells []string
+// odfCollectTableRow reads a table:table-row element. Covered cells, the
+// slots under a spanning cell, are left to the layout; covered reports
+// whether the row had any.
+func odfCollectTableRow(dec *xml.Decoder) (cells []format.TableCell, covered bool) {
 	depth := 1
 
 	for depth > 0 {
@@ -238,16 +324,20 @@ func odfCollectTableRow(dec *xml.Decoder) []string {
 		switch t := tok.(type) {
 		case xml.StartElement:
 			depth++
-			if t.Name.Local == "table-cell" {
-				cell := odfCollectCellText(dec)
+			switch t.Name.Local {
+			case "table-cell":
+				cols, _ := strconv.Atoi(xmlAttr(t, "number-columns-spanned"))
+				rows, _ := strconv.Atoi(xmlAttr(t, "number-rows-spanned"))
+				cells = append(cells, format.TableCell{Text: odfCollectCellText(dec), ColSpan: cols, RowSpan: rows})
 				depth--
-				cells = append(cells, cell)
+			case "covered-table-cell":
+				covered = true
 			}
 		case xml.EndElement:
 			depth--
 		}
 	}
-	return cells
+	return cells, covered
 }
 
 func odfCollectCellText(dec *xml.Decoder) string {
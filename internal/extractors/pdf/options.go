@@ -0,0 +1,182 @@
+package pdf
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/ocr"
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+const (
+	maxSelectablePage   = 50000 // matches poppler's page count sanity bound
+	maxSelectedPages    = 10000
+	maxPageSeparatorLen = 64
+	maxMinWordsOption   = 10000
+)
+
+var ocrModelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:/-]{0,63}$`)
+
+// parseOptions maps the universal options map onto HybridProcessorOptions.
+// Unset fields are left at their zero value so Processor.ApplyDefaults can
+// fill them from server config. Keys not owned by the PDF pipeline are ignored.
+func parseOptions(options map[string]any) (types.HybridProcessorOptions, error) {
+	var opts types.HybridProcessorOptions
+
+	if raw, ok := options["pages"]; ok && raw != nil {
+		pages, err := parsePageSelection(raw)
+		if err != nil {
+			return opts, &extract.OptionError{Key: "pages", Reason: err.Error()}
+		}
+		opts.Pages = pages
+	}
+
+	if n, ok, err := extract.IntOption(options, "minWordsThreshold"); err != nil {
+		return opts, err
+	} else if ok {
+		if n < 1 || n > maxMinWordsOption {
+			return opts, &extract.OptionError{Key: "minWordsThreshold", Reason: fmt.Sprintf("must be between 1 and %d", maxMinWordsOption)}
+		}
+		opts.MinWordsThreshold = n
+	}
+
+	if f, ok, err := extract.FloatOption(options, "ocrTriggerRatio"); err != nil {
+		return opts, err
+	} else if ok {
+		if f <= 0 || f > 1 {
+			return opts, &extract.OptionError{Key: "ocrTriggerRatio", Reason: "must be greater than 0 and at most 1"}
+		}
+		opts.OCRTriggerRatio = f
+	}
+
+	if b, ok, err := extract.BoolOption(options, "includePageNumbers"); err != nil {
+		return opts, err
+	} else if ok {
+		opts.IncludePageNumbers = b
+	}
+
+	if s, ok, err := extract.StringOption(options, "pageSeparator"); err != nil {
+		return opts, err
+	} else if ok {
+		if s == "" || len(s) > maxPageSeparatorLen {
+			return opts, &extract.OptionError{Key: "pageSeparator", Reason: fmt.Sprintf("must be 1-%d bytes", maxPageSeparatorLen)}
+		}
+		opts.PageSeparator = s
+	}
+
+	if b, ok, err := extract.BoolOption(options, "extractHeader"); err != nil {
+		return opts, err
+	} else if ok {
+		opts.ExtractHeader = b
+	}
+
+	if b, ok, err := extract.BoolOption(options, "extractFooter"); err != nil {
+		return opts, err
+	} else if ok {
+		opts.ExtractFooter = b
+	}
+
+	if s, ok, err := extract.StringOption(options, "ocrModel"); err != nil {
+		return opts, err
+	} else if ok {
+		s = strings.TrimSpace(s)
+		if !ocrModelPattern.MatchString(s) {
+			return opts, &extract.OptionError{Key: "ocrModel", Reason: "must be a model identifier"}
+		}
+		opts.OCRModel = &s
+	}
+
+	if s, ok, err := extract.StringOption(options, "ocrProvider"); err != nil {
+		return opts, err
+	} else if ok {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if !ocr.IsProviderName(s) {
+			return opts, &extract.OptionError{Key: "ocrProvider", Reason: "must be one of " + strings.Join(ocr.ProviderNames, ", ")}
+		}
+		opts.OCRProvider = s
+	}
+
+	return opts, nil
+}
+
+// parsePageSelection accepts either a JSON array of 1-based page numbers or a
+// range string such as "1-5,8,10-12". The result is sorted and de-duplicated.
+func parsePageSelection(raw any) ([]int, error) {
+	var pages []int
+
+	switch v := raw.(type) {
+	case []any:
+		if len(v) > maxSelectedPages {
+			return nil, fmt.Errorf("at most %d pages may be selected", maxSelectedPages)
+		}
+		for _, item := range v {
+			n, ok, err := extract.IntOption(map[string]any{"page": item}, "page")
+			if err != nil || !ok {
+				return nil, fmt.Errorf("page numbers must be integers")
+			}
+			pages = append(pages, n)
+		}
+	case []int:
+		pages = append(pages, v...)
+	case string:
+		for _, part := range strings.Split(v, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			lo, hi, isRange := strings.Cut(part, "-")
+			start, err := strconv.Atoi(strings.TrimSpace(lo))
+			if err != nil {
+				return nil, fmt.Errorf("invalid page range %q", part)
+			}
+			end := start
+			if isRange {
+				end, err = strconv.Atoi(strings.TrimSpace(hi))
+				if err != nil {
+					return nil, fmt.Errorf("invalid page range %q", part)
+				}
+			}
+			if end < start {
+				return nil, fmt.Errorf("invalid page range %q", part)
+			}
+			if start < 1 || end > maxSelectablePage {
+				return nil, fmt.Errorf("pages must be between 1 and %d", maxSelectablePage)
+			}
+			if end-start+1 > maxSelectedPages {
+				return nil, fmt.Errorf("at most %d pages may be selected", maxSelectedPages)
+			}
+			for p := start; p <= end; p++ {
+				pages = append(pages, p)
+			}
+			if len(pages) > maxSelectedPages {
+				return nil, fmt.Errorf("at most %d pages may be selected", maxSelectedPages)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("must be an array of page numbers or a range string like \"1-5,8\"")
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("must select at least one page")
+	}
+
+	sort.Ints(pages)
+	out := pages[:0]
+	for _, p := range pages {
+		if p < 1 || p > maxSelectablePage {
+			return nil, fmt.Errorf("pages must be between 1 and %d", maxSelectablePage)
+		}
+		if len(out) > 0 && p == out[len(out)-1] {
+			continue
+		}
+		out = append(out, p)
+	}
+	if len(out) > maxSelectedPages {
+		return nil, fmt.Errorf("at most %d pages may be selected", maxSelectedPages)
+	}
+	return out, nil
+}
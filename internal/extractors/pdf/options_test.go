@@ -0,0 +1,113 @@
+package pdf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/toricodesthings/file-processing-service/internal/extract"
+)
+
+func TestParseOptionsMapsTypedFields(t *testing.T) {
+	opts, err := parseOptions(map[string]any{
+		"pages":              "3-5,1,4",
+		"minWordsThreshold":  float64(12),
+		"ocrTriggerRatio":    0.5,
+		"includePageNumbers": true,
+		"pageSeparator":      "\n\n***\n\n",
+		"extractHeader":      "true",
+		"extractFooter":      false,
+		"ocrModel":           "mistral-ocr-2512",
+		"ocrProvider":        "Tesseract",
+		"timestamps":         true, // not a PDF option; ignored
+	})
+	if err != nil {
+		t.Fatalf("parse options: %v", err)
+	}
+	if !reflect.DeepEqual(opts.Pages, []int{1, 3, 4, 5}) {
+		t.Fatalf("unexpected pages: %v", opts.Pages)
+	}
+	if opts.MinWordsThreshold != 12 || opts.OCRTriggerRatio != 0.5 {
+		t.Fatalf("unexpected numeric options: %+v", opts)
+	}
+	if !opts.IncludePageNumbers || !opts.ExtractHeader || opts.ExtractFooter {
+		t.Fatalf("unexpected bool options: %+v", opts)
+	}
+	if opts.PageSeparator != "\n\n***\n\n" {
+		t.Fatalf("unexpected separator: %q", opts.PageSeparator)
+	}
+	if opts.OCRModel == nil || *opts.OCRModel != "mistral-ocr-2512" {
+		t.Fatalf("unexpected ocr model: %v", opts.OCRModel)
+	}
+	if opts.OCRProvider != "tesseract" {
+		t.Fatalf("unexpected ocr provider: %q", opts.OCRProvider)
+	}
+}
+
+func TestParseOptionsAcceptsPageArray(t *testing.T) {
+	opts, err := parseOptions(map[string]any{"pages": []any{float64(2), float64(1), float64(2)}})
+	if err != nil {
+		t.Fatalf("parse options: %v", err)
+	}
+	if !reflect.DeepEqual(opts.Pages, []int{1, 2}) {
+		t.Fatalf("unexpected pages: %v", opts.Pages)
+	}
+}
+
+func TestParseOptionsEmptyLeavesDefaults(t *testing.T) {
+	opts, err := parseOptions(nil)
+	if err != nil {
+		t.Fatalf("parse options: %v", err)
+	}
+	if opts.Pages != nil || opts.OCRModel != nil || opts.MinWordsThreshold != 0 {
+		t.Fatalf("expected zero options, got %+v", opts)
+	}
+}
+
+func TestParseOptionsRejectsInvalidValues(t *testing.T) {
+	cases := []struct {
+		name string
+		opts map[string]any
+		key  string
+	}{
+		{"fractional page", map[string]any{"pages": []any{1.5}}, "pages"},
+		{"zero page", map[string]any{"pages": "0-3"}, "pages"},
+		{"reversed range", map[string]any{"pages": "5-2"}, "pages"},
+		{"empty pages", map[string]any{"pages": []any{}}, "pages"},
+		{"pages object", map[string]any{"pages": map[string]any{}}, "pages"},
+		{"min words negative", map[string]any{"minWordsThreshold": float64(-1)}, "minWordsThreshold"},
+		{"min words string", map[string]any{"minWordsThreshold": "many"}, "minWordsThreshold"},
+		{"ratio above one", map[string]any{"ocrTriggerRatio": 1.5}, "ocrTriggerRatio"},
+		{"ratio zero", map[string]any{"ocrTriggerRatio": float64(0)}, "ocrTriggerRatio"},
+		{"bool as number", map[string]any{"includePageNumbers": float64(1)}, "includePageNumbers"},
+		{"empty separator", map[string]any{"pageSeparator": ""}, "pageSeparator"},
+		{"model with spaces", map[string]any{"ocrModel": "bad model"}, "ocrModel"},
+		{"unknown provider", map[string]any{"ocrProvider": "textract"}, "ocrProvider"},
+	}
+
+	for _, tc := range cases {
+		_, err := parseOptions(tc.opts)
+		if err == nil {
+			t.Fatalf("%s: expected error", tc.name)
+		}
+		var optErr *extract.OptionError
+		if !errors.As(err, &optErr) {
+			t.Fatalf("%s: expected OptionError, got %T", tc.name, err)
+		}
+		if optErr.Key != tc.key {
+			t.Fatalf("%s: expected key %q, got %q", tc.name, tc.key, optErr.Key)
+		}
+	}
+}
+
+func TestParsePageSelectionRejectsOversizedArraysUpFront(t *testing.T) {
+	raw := make([]any, maxSelectedPages+1)
+	for i := range raw {
+		raw[i] = "not a page"
+	}
+	_, err := parsePageSelection(raw)
+	if err == nil || !strings.Contains(err.Error(), "at most") {
+		t.Fatalf("expected the size limit before any element is read, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package pdf
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
 	"github.com/toricodesthings/file-processing-service/internal/hybrid"
@@ -29,8 +30,26 @@ func (e *Extractor) SupportedExtensions() []string {
 	return []string{".pdf"}
 }
 
+func (e *Extractor) Capabilities() extract.Capabilities {
+	return extract.Capabilities{
+		PaidAPIs: []string{"mistral"},
+		Options: []string{
+			"pages", "minWordsThreshold", "ocrTriggerRatio", "includePageNumbers", "pageSeparator",
+			"extractHeader", "extractFooter", "ocrModel", "ocrProvider",
+		},
+		Binaries: []string{"pdfinfo", "pdftotext"},
+	}
+}
+
 func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
-	opts := e.processor.ApplyDefaults(types.HybridProcessorOptions{})
+	reqOpts, err := parseOptions(job.Options)
+	if err != nil {
+		msg := err.Error()
+		return extract.Result{Success: false, Method: "hybrid", FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
+	}
+	opts := e.processor.ApplyDefaults(reqOpts)
+	opts.DocumentHash = job.FileSHA256
+	opts.Progress = job.Progress
 	out, err := e.processor.ProcessHybrid(ctx, job.PresignedURL, job.LocalPath, opts)
 	if err != nil {
 		msg := err.Error()
@@ -47,6 +66,22 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 		})
 	}
 
+	meta := map[string]string{
+		"totalPages":         strconv.Itoa(out.TotalPages),
+		"ocrPages":           strconv.Itoa(out.OCRPages),
+		"ocrCachedPages":     strconv.Itoa(out.OCRCachedPages),
+		"costSavingsPercent": strconv.Itoa(out.CostSavingsPercent),
+	}
+	if out.NeedsOCR {
+		meta["needsOcr"] = "true"
+	}
+	if out.BudgetExceeded {
+		meta["budgetExceeded"] = "true"
+	}
+	if out.Error != nil {
+		meta[extract.MetaWarning] = *out.Error
+	}
+
 	words, chars := extract.BuildCounts(out.Text)
 	return extract.Result{
 		Success:   true,
@@ -55,7 +90,60 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 		FileType:  e.Name(),
 		MIMEType:  job.MIMEType,
 		Pages:     pages,
+		Metadata:  meta,
 		WordCount: words,
 		CharCount: chars,
 	}, nil
 }
+
+// ExtractPages streams the hybrid pipeline page by page; see
+// hybrid.Processor.StreamHybrid for how OCR decisions are windowed.
+func (e *Extractor) ExtractPages(ctx context.Context, job extract.Job, emit func(extract.PageResult)) (extract.Result, error) {
+	reqOpts, err := parseOptions(job.Options)
+	if err != nil {
+		msg := err.Error()
+		return extract.Result{Success: false, Method: "hybrid", FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
+	}
+	opts := e.processor.ApplyDefaults(reqOpts)
+	opts.DocumentHash = job.FileSHA256
+	opts.Progress = job.Progress
+	sum, err := e.processor.StreamHybrid(ctx, job.PresignedURL, job.LocalPath, opts, func(p types.PageExtractionResult) {
+		emit(extract.PageResult{
+			PageNumber: p.PageNumber,
+			Text:       p.Text,
+			Method:     p.Method,
+			WordCount:  p.WordCount,
+		})
+	})
+	if err != nil {
+		msg := err.Error()
+		return extract.Result{Success: false, Method: "hybrid", FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
+	}
+
+	meta := map[string]string{
+		"totalPages":         strconv.Itoa(sum.TotalPages),
+		"ocrPages":           strconv.Itoa(sum.OCRPages),
+		"ocrCachedPages":     strconv.Itoa(sum.OCRCachedPages),
+		"textLayerPages":     strconv.Itoa(sum.TextLayerPages),
+		"costSavingsPercent": strconv.Itoa(sum.CostSavingsPercent),
+	}
+	if sum.NeedsOCRPages > 0 {
+		meta["needsOcr"] = "true"
+		meta["needsOcrPages"] = strconv.Itoa(sum.NeedsOCRPages)
+	}
+	if sum.BudgetExceeded {
+		meta["budgetExceeded"] = "true"
+	}
+	if sum.Error != nil {
+		meta[extract.MetaWarning] = *sum.Error
+	}
+	return extract.Result{
+		Success:   true,
+		Method:    "hybrid",
+		FileType:  e.Name(),
+		MIMEType:  job.MIMEType,
+		Metadata:  meta,
+		WordCount: sum.WordCount,
+		CharCount: sum.CharCount,
+	}, nil
+}
@@ -0,0 +1,207 @@
+package plaintext
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/toricodesthings/file-processing-service/internal/format"
+	"github.com/toricodesthings/file-processing-service/internal/types"
+	"golang.org/x/net/html"
+)
+
+// htmlInline lists the elements whose text flows into the surrounding
+// paragraph instead of starting a block of their own.
+var htmlInline = map[string]bool{
+	"a": true, "abbr": true, "b": true, "bdi": true, "bdo": true, "br": true, "cite": true, "code": true,
+	"data": true, "dfn": true, "em": true, "font": true, "i": true, "kbd": true, "label": true, "mark": true,
+	"q": true, "s": true, "samp": true, "small": true, "span": true, "strong": true, "sub": true, "sup": true,
+	"time": true, "u": true, "var": true, "wbr": true,
+}
+
+// HTMLBlocks parses an HTML document into blocks, skipping the same
+// navigation, script and style elements as the extracted text. Each block's
+// source is part, numbered by position.
+func HTMLBlocks(b []byte, part string) []types.Block {
+	node, err := html.Parse(bytes.NewReader(b))
+	if err != nil {
+		return nil
+	}
+	blocks := htmlChildBlocks(node, 0, false)
+	for i := range blocks {
+		setSource(&blocks[i], types.Source{Part: part, Element: i + 1})
+	}
+	return blocks
+}
+
+// HTMLTables returns the tables HTMLBlocks would find, with their header
+// rows and merged cells. Each table's source is part.
+func HTMLTables(b []byte, part string) []types.Table {
+	node, err := html.Parse(bytes.NewReader(b))
+	if err != nil {
+		return nil
+	}
+	var tables []types.Table
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch strings.ToLower(c.Data) {
+			case "script", "style", "nav", "footer", "aside", "head", "template", "noscript":
+			case "table":
+				if t := format.HTMLTable(c, types.Source{Part: part}); len(t.Cells) > 0 {
+					tables = append(tables, t)
+				}
+			default:
+				walk(c)
+			}
+		}
+	}
+	walk(node)
+	return tables
+}
+
+func setSource(b *types.Block, src types.Source) {
+	b.Source = src
+	for i := range b.Children {
+		setSource(&b.Children[i], src)
+	}
+}
+
+// htmlChildBlocks returns the blocks of n's children. Runs of text and
+// inline elements between blocks become paragraphs. listDepth counts the
+// enclosing lists; ordered is whether the innermost one is numbered.
+func htmlChildBlocks(n *html.Node, listDepth int, ordered bool) []types.Block {
+	var out []types.Block
+	var run strings.Builder
+	flush := func() {
+		if text := collapseSpace(run.String()); text != "" {
+			out = append(out, types.Block{Type: types.BlockParagraph, Text: text})
+		}
+		run.Reset()
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.TextNode:
+			run.WriteString(c.Data)
+			continue
+		case html.ElementNode, html.DocumentNode:
+		default:
+			continue
+		}
+		tag := strings.ToLower(c.Data)
+		if htmlInline[tag] && !htmlHasImage(c) {
+			run.WriteString(htmlStripNodeText(c))
+			continue
+		}
+		flush()
+
+		switch tag {
+		case "script", "style", "nav", "footer", "aside", "head", "template", "noscript":
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			if text := collapseSpace(htmlStripNodeText(c)); text != "" {
+				out = append(out, types.Block{Type: types.BlockHeading, Level: int(tag[1] - '0'), Text: text})
+			}
+		case "ul", "ol", "menu":
+			out = append(out, htmlChildBlocks(c, listDepth+1, tag == "ol")...)
+		case "li":
+			out = append(out, htmlListItem(c, listDepth, ordered)...)
+		case "table":
+			if t := format.HTMLTable(c, types.Source{}); len(t.Cells) > 0 {
+				out = append(out, types.Block{Type: types.BlockTable, Cells: t.Cells})
+			}
+		case "pre":
+			text := strings.Trim(htmlStripNodeText(c), "\n")
+			if strings.TrimSpace(text) != "" {
+				out = append(out, types.Block{Type: types.BlockCode, Text: text, Language: htmlCodeLanguage(c)})
+			}
+		case "blockquote":
+			if children := htmlChildBlocks(c, 0, false); len(children) > 0 {
+				out = append(out, types.Block{Type: types.BlockQuote, Children: children})
+			}
+		case "img":
+			out = append(out, types.Block{Type: types.BlockImage, Text: htmlImageName(c)})
+		case "hr", "br":
+		default:
+			out = append(out, htmlChildBlocks(c, listDepth, ordered)...)
+		}
+	}
+	flush()
+	return out
+}
+
+// htmlListItem returns an <li> as a list item followed by its nested lists.
+func htmlListItem(li *html.Node, listDepth int, ordered bool) []types.Block {
+	level := max(listDepth-1, 0)
+	var text strings.Builder
+	var nested []types.Block
+	for c := li.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			switch strings.ToLower(c.Data) {
+			case "ul", "ol", "menu":
+				nested = append(nested, htmlChildBlocks(c, listDepth+1, strings.ToLower(c.Data) == "ol")...)
+				continue
+			}
+		}
+		text.WriteString(htmlStripNodeText(c))
+		text.WriteString(" ")
+	}
+	var out []types.Block
+	if t := collapseSpace(text.String()); t != "" {
+		out = append(out, types.Block{Type: types.BlockListItem, Text: t, Level: level, Ordered: ordered})
+	}
+	return append(out, nested...)
+}
+
+// htmlCodeLanguage reads a "language-x" or "lang-x" class from a <pre> or
+// the <code> inside it.
+func htmlCodeLanguage(pre *html.Node) string {
+	nodes := []*html.Node{pre}
+	if c := pre.FirstChild; c != nil && c.Type == html.ElementNode && c.Data == "code" {
+		nodes = append(nodes, c)
+	}
+	for _, n := range nodes {
+		for _, cls := range strings.Fields(htmlAttr(n, "class")) {
+			for _, prefix := range []string{"language-", "lang-"} {
+				if strings.HasPrefix(cls, prefix) {
+					return strings.TrimPrefix(cls, prefix)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func htmlImageName(img *html.Node) string {
+	if alt := strings.TrimSpace(htmlAttr(img, "alt")); alt != "" {
+		return alt
+	}
+	return strings.TrimSpace(htmlAttr(img, "title"))
+}
+
+func htmlHasImage(n *html.Node) bool {
+	if n.Type == html.ElementNode && n.Data == "img" {
+		return true
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if htmlHasImage(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
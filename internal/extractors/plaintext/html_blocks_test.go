@@ -0,0 +1,72 @@
+package plaintext
+
+import (
+	"testing"
+
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+func TestHTMLBlocks(t *testing.T) {
+	doc := `<html><head><title>T</title><style>p{}</style></head><body>
+		<nav><a href="/">Home</a></nav>
+		<h2>Title</h2>
+		<div>Loose <b>text</b> here<img src="x.png" alt="Logo"></div>
+		<ol><li>one<ul><li>inner</li></ul></li></ol>
+		<table><thead><tr><th>A</th><th>B</th></tr></thead><tbody><tr><td>1</td></tr></tbody></table>
+		<pre><code class="language-go">fmt.Println()</code></pre>
+		<blockquote><p>Said</p></blockquote>
+	</body></html>`
+
+	blocks := HTMLBlocks([]byte(doc), "index.html")
+	want := []types.Block{
+		{Type: types.BlockHeading, Level: 2, Text: "Title"},
+		{Type: types.BlockParagraph, Text: "Loose text here"},
+		{Type: types.BlockImage, Text: "Logo"},
+		{Type: types.BlockListItem, Text: "one", Ordered: true},
+		{Type: types.BlockListItem, Text: "inner", Level: 1},
+		{Type: types.BlockTable},
+		{Type: types.BlockCode, Text: "fmt.Println()", Language: "go"},
+		{Type: types.BlockQuote},
+	}
+	if len(blocks) != len(want) {
+		t.Fatalf("blocks = %+v", blocks)
+	}
+	for i, w := range want {
+		b := blocks[i]
+		if b.Type != w.Type || b.Text != w.Text || b.Level != w.Level || b.Ordered != w.Ordered || b.Language != w.Language {
+			t.Fatalf("block %d = %+v, want %+v", i, b, w)
+		}
+		if b.Source.Part != "index.html" || b.Source.Element != i+1 {
+			t.Fatalf("block %d source = %+v", i, b.Source)
+		}
+	}
+	if cells := blocks[5].Cells; len(cells) != 2 || cells[1][0] != "1" || cells[1][1] != "" {
+		t.Fatalf("table cells = %q", cells)
+	}
+	if q := blocks[7].Children; len(q) != 1 || q[0].Text != "Said" {
+		t.Fatalf("quote children = %+v", q)
+	}
+}
+
+func TestHTMLTablesKeepsSpans(t *testing.T) {
+	doc := `<body><nav><table><tr><td>menu</td></tr></table></nav>
+		<table><tr><th colspan="2">Region</th><th>Total</th></tr>
+		<tr><td rowspan="2">North</td><td>Q1</td><td>10</td></tr>
+		<tr><td>Q2</td><td>12</td></tr></table></body>`
+
+	tables := HTMLTables([]byte(doc), "index.html")
+	if len(tables) != 1 {
+		t.Fatalf("tables = %+v", tables)
+	}
+	got := tables[0]
+	if got.HeaderRows != 1 || got.Source.Part != "index.html" {
+		t.Fatalf("table = %+v", got)
+	}
+	if got.Cells[2][0] != "" || got.Cells[2][1] != "Q2" || got.Cells[2][2] != "12" {
+		t.Fatalf("cells = %q", got.Cells)
+	}
+	want := []types.Merge{{Row: 0, Col: 0, RowSpan: 1, ColSpan: 2}, {Row: 1, Col: 0, RowSpan: 2, ColSpan: 1}}
+	if len(got.Merges) != 2 || got.Merges[0] != want[0] || got.Merges[1] != want[1] {
+		t.Fatalf("merges = %+v, want %+v", got.Merges, want)
+	}
+}
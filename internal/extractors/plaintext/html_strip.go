@@ -36,7 +36,14 @@ func (e *HTMLExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 	}
 	text, meta := htmlStripToMarkdownLike(b)
 	w, c := extract.BuildCounts(text)
-	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: w, CharCount: c}, nil
+	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: w, CharCount: c, Blocks: HTMLBlocks(b, ""), Tables: HTMLTables(b, "")}, nil
+}
+
+// HTMLToText converts an HTML document into the same markdown-like text the
+// HTML extractor produces. Used for HTML bodies embedded in other formats.
+func HTMLToText(b []byte) string {
+	text, _ := htmlStripToMarkdownLike(b)
+	return text
 }
 
 func htmlStripToMarkdownLike(b []byte) (string, map[string]string) {
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/format"
 )
 
 // Extractor handles plain text, markdown, and config-file passthrough.
@@ -55,11 +56,13 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 	ext := strings.ToLower(filepath.Ext(job.FileName))
 	method := "native"
 	fileType := "text/plain"
+	blocksOf := format.TextBlocks
 
 	switch ext {
 	case ".md", ".mdx", ".markdown":
 		text = stripFrontMatter(text)
 		fileType = "text/markdown"
+		blocksOf = format.ParseMarkdown
 	}
 
 	text = normalizeText(text)
@@ -72,6 +75,7 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 		MIMEType:  job.MIMEType,
 		WordCount: words,
 		CharCount: chars,
+		Blocks:    blocksOf(text),
 	}, nil
 }
 
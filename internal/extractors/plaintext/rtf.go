@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/format"
 )
 
 type RTFExtractor struct {
@@ -20,6 +21,28 @@ func (e *RTFExtractor) MaxFileSize() int64            { return e.maxBytes }
 func (e *RTFExtractor) SupportedTypes() []string      { return []string{"application/rtf", "text/rtf"} }
 func (e *RTFExtractor) SupportedExtensions() []string { return []string{".rtf"} }
 
+var (
+	rtfParRe     = regexp.MustCompile(`\\par[d]?`)
+	rtfTabRe     = regexp.MustCompile(`\\tab`)
+	rtfHexRe     = regexp.MustCompile(`\\'[0-9a-fA-F]{2}`)
+	rtfControlRe = regexp.MustCompile(`\\[a-zA-Z]+-?\d* ?`)
+	rtfBlankRe   = regexp.MustCompile(`\n{3,}`)
+)
+
+// RTFToText strips RTF control words and groups, keeping paragraph breaks.
+// Also used for RTF bodies embedded in other formats (e.g. Outlook .msg).
+func RTFToText(b []byte) string {
+	s := string(b)
+	s = rtfParRe.ReplaceAllString(s, "\n")
+	s = rtfTabRe.ReplaceAllString(s, "\t")
+	s = rtfHexRe.ReplaceAllString(s, "")
+	s = rtfControlRe.ReplaceAllString(s, "")
+	s = strings.ReplaceAll(s, "{", "")
+	s = strings.ReplaceAll(s, "}", "")
+	s = rtfBlankRe.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
 func (e *RTFExtractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
 	select {
 	case <-ctx.Done():
@@ -32,16 +55,7 @@ func (e *RTFExtractor) Extract(ctx context.Context, job extract.Job) (extract.Re
 		return extract.Result{Success: false, FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
 	}
 
-	s := string(b)
-	s = regexp.MustCompile(`\\par[d]?`).ReplaceAllString(s, "\n")
-	s = regexp.MustCompile(`\\tab`).ReplaceAllString(s, "\t")
-	s = regexp.MustCompile(`\\'[0-9a-fA-F]{2}`).ReplaceAllString(s, "")
-	s = regexp.MustCompile(`\\[a-zA-Z]+-?\d* ?`).ReplaceAllString(s, "")
-	s = strings.ReplaceAll(s, "{", "")
-	s = strings.ReplaceAll(s, "}", "")
-	s = regexp.MustCompile(`\n{3,}`).ReplaceAllString(s, "\n\n")
-	s = strings.TrimSpace(s)
-
+	s := RTFToText(b)
 	w, c := extract.BuildCounts(s)
-	return extract.Result{Success: true, Text: s, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: w, CharCount: c}, nil
+	return extract.Result{Success: true, Text: s, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: w, CharCount: c, Blocks: format.TextBlocks(s)}, nil
 }
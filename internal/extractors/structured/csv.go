@@ -9,6 +9,8 @@ import (
 	"strings"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/format"
+	"github.com/toricodesthings/file-processing-service/internal/types"
 )
 
 type CSVExtractor struct {
@@ -24,6 +26,10 @@ func (e *CSVExtractor) SupportedTypes() []string {
 }
 func (e *CSVExtractor) SupportedExtensions() []string { return []string{".csv", ".tsv"} }
 
+func (e *CSVExtractor) Capabilities() extract.Capabilities {
+	return extract.Capabilities{Options: []string{"tableFormat"}}
+}
+
 func (e *CSVExtractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
 	select {
 	case <-ctx.Done():
@@ -31,6 +37,12 @@ func (e *CSVExtractor) Extract(ctx context.Context, job extract.Job) (extract.Re
 	default:
 	}
 
+	tableFormat, err := extract.ParseTableFormat(job.Options)
+	if err != nil {
+		msg := err.Error()
+		return extract.Result{Success: false, FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, err
+	}
+
 	b, err := os.ReadFile(job.LocalPath)
 	if err != nil {
 		msg := err.Error()
@@ -41,17 +53,18 @@ func (e *CSVExtractor) Extract(ctx context.Context, job extract.Job) (extract.Re
 	if err != nil || len(recs) == 0 {
 		text := strings.TrimSpace(string(b))
 		w, c := extract.BuildCounts(text)
-		return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: w, CharCount: c}, nil
+		return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: w, CharCount: c, Blocks: format.TextBlocks(text)}, nil
 	}
 
-	text := recordsToMarkdown(recs)
+	table := format.NewTable(recs[:min(len(recs), maxCSVRows)], 1, types.Source{Line: 1})
+	text := recordsText(table, len(recs), tableFormat)
 	w, c := extract.BuildCounts(text)
 	meta := map[string]string{
 		"rows":      fmt.Sprintf("%d", len(recs)),
 		"columns":   fmt.Sprintf("%d", maxCols(recs)),
 		"delimiter": string(delim),
 	}
-	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: w, CharCount: c}, nil
+	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, Metadata: meta, WordCount: w, CharCount: c, Blocks: []types.Block{{Type: types.BlockTable, Cells: table.Cells, Source: table.Source}}, Tables: []types.Table{table}}, nil
 }
 
 func readRecords(b []byte) ([][]string, rune, error) {
@@ -77,34 +90,15 @@ func maxCols(recs [][]string) int {
 	return m
 }
 
-func recordsToMarkdown(recs [][]string) string {
-	if len(recs) == 0 {
-		return ""
-	}
-	max := maxCols(recs)
-	for i := range recs {
-		for len(recs[i]) < max {
-			recs[i] = append(recs[i], "")
-		}
-	}
+// maxCSVRows caps the rows rendered: a header and 200 data rows.
+const maxCSVRows = 201
 
-	rows := recs
-	if len(rows) > 201 {
-		rows = rows[:201]
-	}
-
-	var sb strings.Builder
-	sb.WriteString("| " + strings.Join(rows[0], " | ") + " |\n")
-	sep := make([]string, max)
-	for i := range sep {
-		sep[i] = "---"
-	}
-	sb.WriteString("| " + strings.Join(sep, " | ") + " |\n")
-	for _, row := range rows[1:] {
-		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
-	}
-	if len(recs) > 201 {
-		sb.WriteString(fmt.Sprintf("\n... and %d more rows", len(recs)-201))
+// recordsText renders the first maxCSVRows records as a table in
+// tableFormat, noting how many rows were left out.
+func recordsText(t types.Table, total int, tableFormat string) string {
+	text := format.RenderTable(t, tableFormat)
+	if total > maxCSVRows {
+		text += fmt.Sprintf("\n\n... and %d more rows", total-maxCSVRows)
 	}
-	return strings.TrimSpace(sb.String())
+	return text
 }
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/types"
 )
 
 type JSONExtractor struct {
@@ -35,14 +36,16 @@ func (e *JSONExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 	}
 
 	var text string
+	var blocks []types.Block
 	if strings.HasSuffix(strings.ToLower(job.FileName), ".jsonl") {
-		text = formatJSONL(string(b))
+		text, blocks = formatJSONL(string(b))
 	} else {
-		text = prettyJSON(b)
+		text = strings.TrimSpace(prettyJSON(b))
+		blocks = []types.Block{{Type: types.BlockCode, Text: text, Language: "json", Source: types.Source{Line: 1}}}
 	}
 	text = strings.TrimSpace(text)
 	w, c := extract.BuildCounts(text)
-	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: w, CharCount: c}, nil
+	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: w, CharCount: c, Blocks: blocks}, nil
 }
 
 func prettyJSON(b []byte) string {
@@ -57,15 +60,20 @@ func prettyJSON(b []byte) string {
 	return string(out)
 }
 
-func formatJSONL(s string) string {
+// formatJSONL pretty-prints each record, returning one code block per record
+// with the line it came from.
+func formatJSONL(s string) (string, []types.Block) {
 	lines := strings.Split(s, "\n")
 	parts := make([]string, 0, len(lines))
-	for _, line := range lines {
+	var blocks []types.Block
+	for i, line := range lines {
 		trim := strings.TrimSpace(line)
 		if trim == "" {
 			continue
 		}
-		parts = append(parts, prettyJSON([]byte(trim)))
+		record := prettyJSON([]byte(trim))
+		parts = append(parts, record)
+		blocks = append(blocks, types.Block{Type: types.BlockCode, Text: record, Language: "json", Source: types.Source{Line: i + 1}})
 	}
-	return strings.Join(parts, "\n\n---\n\n")
+	return strings.Join(parts, "\n\n---\n\n"), blocks
 }
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/types"
 )
 
 type XMLExtractor struct {
@@ -39,6 +40,7 @@ func (e *XMLExtractor) Extract(ctx context.Context, job extract.Job) (extract.Re
 
 	d := xml.NewDecoder(bytes.NewReader(b))
 	out := make([]string, 0)
+	var blocks []types.Block
 	for {
 		tok, err := d.Token()
 		if err == io.EOF {
@@ -52,10 +54,12 @@ func (e *XMLExtractor) Extract(ctx context.Context, job extract.Job) (extract.Re
 			s := strings.TrimSpace(string(t))
 			if s != "" {
 				out = append(out, s)
+				line, _ := d.InputPos()
+				blocks = append(blocks, types.Block{Type: types.BlockParagraph, Text: s, Source: types.Source{Line: line}})
 			}
 		}
 	}
 	text := strings.Join(out, "\n")
 	w, c := extract.BuildCounts(text)
-	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: w, CharCount: c}, nil
+	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: w, CharCount: c, Blocks: blocks}, nil
 }
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/types"
 	"gopkg.in/yaml.v3"
 )
 
@@ -45,6 +46,11 @@ func (e *YAMLExtractor) Extract(ctx context.Context, job extract.Job) (extract.R
 		}
 	}
 
+	lang := "yaml"
+	if strings.HasSuffix(strings.ToLower(job.FileName), ".toml") {
+		lang = "toml"
+	}
+	blocks := []types.Block{{Type: types.BlockCode, Text: text, Language: lang, Source: types.Source{Line: 1}}}
 	w, c := extract.BuildCounts(text)
-	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: w, CharCount: c}, nil
+	return extract.Result{Success: true, Text: text, Method: "native", FileType: e.Name(), MIMEType: job.MIMEType, WordCount: w, CharCount: c, Blocks: blocks}, nil
 }
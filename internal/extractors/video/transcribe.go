@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/toricodesthings/file-processing-service/internal/budget"
 	"github.com/toricodesthings/file-processing-service/internal/extract"
 	audioextractor "github.com/toricodesthings/file-processing-service/internal/extractors/audio"
 )
@@ -39,6 +40,13 @@ func (e *Extractor) SupportedTypes() []string {
 func (e *Extractor) SupportedExtensions() []string {
 	return []string{".mp4", ".mkv", ".avi", ".mov", ".webm", ".m4v", ".flv", ".wmv"}
 }
+func (e *Extractor) Capabilities() extract.Capabilities {
+	caps := extract.Capabilities{PaidAPIs: []string{"groq"}, Binaries: []string{e.ffmpegBinary}}
+	if e.audio != nil {
+		caps.Options = e.audio.Capabilities().Options
+	}
+	return caps
+}
 
 func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Result, error) {
 	if e.audio == nil {
@@ -46,6 +54,11 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 		return extract.Result{Success: false, Method: "ffmpeg+groq", FileType: e.Name(), MIMEType: job.MIMEType, Error: &msg}, errors.New(msg)
 	}
 
+	// No point extracting the audio track if it cannot be transcribed.
+	if !budget.Allowed(ctx) {
+		return audioextractor.BudgetExceededResult(e.Name(), job.MIMEType), nil
+	}
+
 	outAudio := filepath.Join(filepath.Dir(job.LocalPath), "extracted.mp3")
 	localCtx, cancel := context.WithTimeout(ctx, e.ffmpegTO)
 	defer cancel()
@@ -71,7 +84,7 @@ func (e *Extractor) Extract(ctx context.Context, job extract.Job) (extract.Resul
 	audioJob.LocalPath = outAudio
 	audioJob.MIMEType = "audio/mpeg"
 	audioJob.FileSize = st.Size()
-	res, err := e.audio.Extract(ctx, audioJob)
+	res, err := extract.RunExtractor(ctx, e.audio, audioJob)
 	if err != nil {
 		return res, err
 	}
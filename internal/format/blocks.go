@@ -0,0 +1,181 @@
+package format
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+var (
+	headingPattern   = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	listItemPattern  = regexp.MustCompile(`^([ \t]*)([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	imageLinePattern = regexp.MustCompile(`^!\[([^\]]*)\]\([^)]*\)$`)
+	tableSepPattern  = regexp.MustCompile(`^\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?$`)
+	breakPattern     = regexp.MustCompile(`^(-{3,}|\*{3,}|_{3,})$`)
+)
+
+// ParseMarkdown splits markdown into blocks, for extractors that build
+// markdown rather than parse a structured format. It understands the subset
+// this service produces: ATX headings, lists, pipe tables, fenced code,
+// quotes and image lines. Thematic breaks, which separate pages, slides and
+// sheets in extracted text, become page breaks, and a leading YAML
+// frontmatter block is skipped. Source.Line is the line in md.
+func ParseMarkdown(md string) []types.Block {
+	md = strings.ReplaceAll(md, "\r\n", "\n")
+	lines := strings.Split(md, "\n")
+	start := 0
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				start = i + 1
+				break
+			}
+		}
+	}
+	return parseMarkdownLines(lines[start:], start)
+}
+
+// TextBlocks splits plain text into paragraphs at blank lines.
+func TextBlocks(text string) []types.Block {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var blocks []types.Block
+	var para []string
+	first := 0
+	flush := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, types.Block{Type: types.BlockParagraph, Text: strings.Join(para, "\n"), Source: types.Source{Line: first}})
+			para = nil
+		}
+	}
+	for i, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if len(para) == 0 {
+			first = i + 1
+		}
+		para = append(para, strings.TrimRight(line, " \t"))
+	}
+	flush()
+	return blocks
+}
+
+// parseMarkdownLines parses lines whose first is line offset+1 of the input.
+func parseMarkdownLines(lines []string, offset int) []types.Block {
+	var blocks []types.Block
+	var para []string
+	paraLine := 0
+	flush := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, types.Block{Type: types.BlockParagraph, Text: strings.Join(para, "\n"), Source: types.Source{Line: paraLine}})
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+		src := types.Source{Line: offset + i + 1}
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence := trimmed[:3]
+			var code []string
+			j := i + 1
+			for ; j < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[j]), fence); j++ {
+				code = append(code, lines[j])
+			}
+			blocks = append(blocks, types.Block{Type: types.BlockCode, Text: strings.Join(code, "\n"), Language: strings.TrimSpace(trimmed[3:]), Source: src})
+			i = j
+
+		case strings.HasPrefix(trimmed, "<!--") && strings.HasSuffix(trimmed, "-->"):
+			flush()
+
+		case headingPattern.MatchString(trimmed):
+			flush()
+			m := headingPattern.FindStringSubmatch(trimmed)
+			blocks = append(blocks, types.Block{Type: types.BlockHeading, Level: len(m[1]), Text: strings.TrimSpace(strings.TrimRight(m[2], "#")), Source: src})
+
+		case breakPattern.MatchString(trimmed):
+			flush()
+			blocks = append(blocks, types.Block{Type: types.BlockPageBreak, Source: src})
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quoted []string
+			j := i
+			for ; j < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[j]), ">"); j++ {
+				q := strings.TrimPrefix(strings.TrimSpace(lines[j]), ">")
+				quoted = append(quoted, strings.TrimPrefix(q, " "))
+			}
+			blocks = append(blocks, types.Block{Type: types.BlockQuote, Children: parseMarkdownLines(quoted, offset+i), Source: src})
+			i = j - 1
+
+		case strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && tableSepPattern.MatchString(strings.TrimSpace(lines[i+1])):
+			flush()
+			rows := [][]string{splitTableRow(trimmed)}
+			j := i + 2
+			for ; j < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[j]), "|"); j++ {
+				rows = append(rows, splitTableRow(strings.TrimSpace(lines[j])))
+			}
+			blocks = append(blocks, types.Block{Type: types.BlockTable, Cells: rows, Source: src})
+			i = j - 1
+
+		case listItemPattern.MatchString(line):
+			flush()
+			m := listItemPattern.FindStringSubmatch(line)
+			indent := len(strings.ReplaceAll(m[1], "\t", "  "))
+			blocks = append(blocks, types.Block{
+				Type:    types.BlockListItem,
+				Text:    strings.TrimSpace(m[3]),
+				Level:   indent / 2,
+				Ordered: m[2][0] >= '0' && m[2][0] <= '9',
+				Source:  src,
+			})
+
+		case imageLinePattern.MatchString(trimmed):
+			flush()
+			m := imageLinePattern.FindStringSubmatch(trimmed)
+			blocks = append(blocks, types.Block{Type: types.BlockImage, Text: m[1], Source: src})
+
+		default:
+			if len(para) == 0 {
+				paraLine = src.Line
+			}
+			para = append(para, trimmed)
+		}
+	}
+	flush()
+	return blocks
+}
+
+// splitTableRow splits a pipe table row into trimmed cells, honouring
+// escaped pipes.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var sb strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			sb.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(sb.String()))
+			sb.Reset()
+		default:
+			sb.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(sb.String()))
+}
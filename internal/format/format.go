@@ -20,17 +20,11 @@ func Combine(pages []types.PageExtractionResult, sep string, includePageNums boo
 	var parts []string
 
 	for _, p := range pages {
-		txt := normalizeMarkdown(p.Text)
+		txt := pageText(p.Text)
 		if txt == "" {
 			continue
 		}
 
-		// Strip image placeholders (not useful for RAG/vectorization)
-		txt = stripImages(txt)
-
-		// Convert HTML tables to markdown (better for chunking)
-		txt = convertHTMLTables(txt)
-
 		// Add page marker if requested (as plain text, not HTML)
 		if includePageNums {
 			parts = append(parts, fmt.Sprintf("[Page %d]\n\n%s", p.PageNumber, txt))
@@ -52,6 +46,29 @@ func Combine(pages []types.PageExtractionResult, sep string, includePageNums boo
 	return finalCleanup(combined)
 }
 
+// Page cleans a single page the way Combine does, for callers that emit
+// pages one at a time instead of combining them.
+func Page(text string) string {
+	txt := pageText(text)
+	if txt == "" {
+		return ""
+	}
+	return finalCleanup(txt)
+}
+
+func pageText(text string) string {
+	txt := normalizeMarkdown(text)
+	if txt == "" {
+		return ""
+	}
+
+	// Strip image placeholders (not useful for RAG/vectorization)
+	txt = stripImages(txt)
+
+	// Convert HTML tables to markdown (better for chunking)
+	return convertHTMLTables(txt)
+}
+
 // stripImages removes image placeholders which aren't useful for text search/RAG
 func stripImages(text string) string {
 	// Remove ![alt](url) patterns
@@ -63,146 +80,19 @@ func stripImages(text string) string {
 	return text
 }
 
-// convertHTMLTables converts HTML tables to markdown for better chunking
+// convertHTMLTables converts HTML tables to markdown for better chunking.
+// Merged cells keep their text in the first cell they cover.
 func convertHTMLTables(text string) string {
-	// Simple HTML table to markdown conversion
-	// This handles basic tables - complex tables with rowspan/colspan
-	// will be converted to simplified markdown
-
-	lines := strings.Split(text, "\n")
-	var result []string
-	inTable := false
-	var tableRows [][]string
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-
-		if strings.HasPrefix(trimmed, "<table") {
-			inTable = true
-			tableRows = [][]string{}
-			continue
-		}
-
-		if strings.HasPrefix(trimmed, "</table>") {
-			if len(tableRows) > 0 {
-				// Convert accumulated rows to markdown table
-				mdTable := buildMarkdownTable(tableRows)
-				result = append(result, mdTable)
-			}
-			inTable = false
-			tableRows = [][]string{}
-			continue
-		}
-
-		if inTable {
-			// Extract cells from <tr> rows
-			if strings.Contains(trimmed, "<tr>") || strings.Contains(trimmed, "<th>") || strings.Contains(trimmed, "<td>") {
-				cells := extractTableCells(line)
-				if len(cells) > 0 {
-					tableRows = append(tableRows, cells)
-				}
-			}
-			continue
-		}
-
-		result = append(result, line)
-	}
-
-	return strings.Join(result, "\n")
-}
-
-// extractTableCells extracts text from <th> and <td> tags
-func extractTableCells(line string) []string {
-	// Remove <tr> tags
-	line = strings.ReplaceAll(line, "<tr>", "")
-	line = strings.ReplaceAll(line, "</tr>", "")
-
-	var cells []string
-
-	// Extract from <th> tags (headers)
-	thPattern := regexp.MustCompile(`<th[^>]*>(.*?)</th>`)
-	matches := thPattern.FindAllStringSubmatch(line, -1)
-	for _, match := range matches {
-		if len(match) > 1 {
-			cell := strings.TrimSpace(stripHTMLTags(match[1]))
-			cells = append(cells, cell)
-		}
-	}
-
-	// Extract from <td> tags (data)
-	tdPattern := regexp.MustCompile(`<td[^>]*>(.*?)</td>`)
-	matches = tdPattern.FindAllStringSubmatch(line, -1)
-	for _, match := range matches {
-		if len(match) > 1 {
-			cell := strings.TrimSpace(stripHTMLTags(match[1]))
-			cells = append(cells, cell)
-		}
-	}
-
-	return cells
-}
-
-// stripHTMLTags removes HTML tags from text
-func stripHTMLTags(text string) string {
-	// Remove all HTML tags
-	text = regexp.MustCompile(`<[^>]+>`).ReplaceAllString(text, "")
-	// Decode common HTML entities
-	text = strings.ReplaceAll(text, "&nbsp;", " ")
-	text = strings.ReplaceAll(text, "&lt;", "<")
-	text = strings.ReplaceAll(text, "&gt;", ">")
-	text = strings.ReplaceAll(text, "&amp;", "&")
-	return text
-}
-
-// buildMarkdownTable creates a markdown table from rows
-func buildMarkdownTable(rows [][]string) string {
-	if len(rows) == 0 {
-		return ""
-	}
-
-	// Determine column count
-	maxCols := 0
-	for _, row := range rows {
-		if len(row) > maxCols {
-			maxCols = len(row)
+	return eachTable(text, func(line int, rows [][]string, raw string) string {
+		if rows != nil {
+			return raw
 		}
-	}
-
-	if maxCols == 0 {
-		return ""
-	}
-
-	// Normalize all rows to have same column count
-	normalizedRows := make([][]string, len(rows))
-	for i, row := range rows {
-		normalizedRows[i] = make([]string, maxCols)
-		for j := 0; j < maxCols; j++ {
-			if j < len(row) {
-				normalizedRows[i][j] = row[j]
-			} else {
-				normalizedRows[i][j] = ""
-			}
+		var out []string
+		for _, t := range parseHTMLTables(raw, line) {
+			out = append(out, RenderTable(t, TableMarkdown))
 		}
-	}
-
-	var result strings.Builder
-
-	// Header row
-	result.WriteString("| " + strings.Join(normalizedRows[0], " | ") + " |\n")
-
-	// Separator row
-	separators := make([]string, maxCols)
-	for i := 0; i < maxCols; i++ {
-		separators[i] = "---"
-	}
-	result.WriteString("| " + strings.Join(separators, " | ") + " |\n")
-
-	// Data rows
-	for i := 1; i < len(normalizedRows); i++ {
-		result.WriteString("| " + strings.Join(normalizedRows[i], " | ") + " |\n")
-	}
-
-	return result.String()
+		return strings.Join(out, "\n\n")
+	})
 }
 
 // normalizeMarkdown cleans and standardizes markdown while preserving structure
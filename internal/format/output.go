@@ -0,0 +1,225 @@
+package format
+
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+// Output formats accepted by the outputFormat option.
+const (
+	OutputMarkdown = "markdown"
+	OutputText     = "text"
+	OutputHTML     = "html"
+	OutputJSON     = "json"
+)
+
+// OutputFormats lists every output format, the default first.
+var OutputFormats = []string{OutputMarkdown, OutputText, OutputHTML, OutputJSON}
+
+var (
+	codeSpanPattern    = regexp.MustCompile("`([^`]+)`")
+	inlineImage        = regexp.MustCompile(`!\[([^\]]*)\]\((?:[^()\s]|\([^()\s]*\))*\)`)
+	inlineLink         = regexp.MustCompile(`\[([^\]]+)\]\(((?:[^()\s]|\([^()\s]*\))*)\)`)
+	strongPattern      = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	emphasisPattern    = regexp.MustCompile(`\*([^*\s][^*]*)\*|\b_([^_\s][^_]*)_\b`)
+	inlineTagPattern   = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	escapedMarkPattern = regexp.MustCompile("\\\\([\\\\`*_{}\\[\\]()#+\\-.!|>])")
+	safeURLPattern     = regexp.MustCompile(`(?i)^(https?:|mailto:|[^:]*$)`)
+)
+
+// Render converts extracted markdown into outputFormat. Markdown comes back
+// unchanged.
+func Render(md, outputFormat string) string {
+	if outputFormat == "" || outputFormat == OutputMarkdown {
+		return md
+	}
+	return RenderBlocks(ParseMarkdown(md), outputFormat)
+}
+
+// RenderBlocks renders blocks as plain text, sanitized HTML or a JSON AST.
+// Plain text drops all markup and flattens tables to tab-separated rows.
+// HTML is built from the blocks with every piece of text escaped, so markup
+// in the source never reaches it, and links keep only http, https, mailto
+// and relative URLs. The JSON AST is a document node whose children are the
+// blocks. Any other format renders plain text.
+func RenderBlocks(blocks []types.Block, outputFormat string) string {
+	switch outputFormat {
+	case OutputHTML:
+		return htmlBlocks(blocks)
+	case OutputJSON:
+		if blocks == nil {
+			blocks = []types.Block{}
+		}
+		b, _ := json.Marshal(struct {
+			Type     string        `json:"type"`
+			Children []types.Block `json:"children"`
+		}{"document", blocks})
+		return string(b)
+	}
+	return plainBlocks(blocks)
+}
+
+// plainBlocks joins blocks with blank lines, and consecutive list items
+// with single newlines.
+func plainBlocks(blocks []types.Block) string {
+	var sb strings.Builder
+	prev := ""
+	for _, b := range blocks {
+		text := plainBlock(b)
+		if text == "" {
+			continue
+		}
+		switch {
+		case sb.Len() == 0:
+		case prev == types.BlockListItem && b.Type == types.BlockListItem:
+			sb.WriteString("\n")
+		default:
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(text)
+		prev = b.Type
+	}
+	return sb.String()
+}
+
+func plainBlock(b types.Block) string {
+	switch b.Type {
+	case types.BlockHeading, types.BlockParagraph, types.BlockImage:
+		return StripInline(b.Text)
+	case types.BlockListItem:
+		return strings.Repeat("  ", b.Level) + StripInline(b.Text)
+	case types.BlockCode:
+		return b.Text
+	case types.BlockQuote:
+		return plainBlocks(b.Children)
+	case types.BlockTable:
+		lines := make([]string, 0, len(b.Cells))
+		for _, row := range b.Cells {
+			cells := make([]string, len(row))
+			for i, c := range row {
+				cells[i] = strings.Join(strings.Fields(StripInline(c)), " ")
+			}
+			if line := strings.TrimRight(strings.Join(cells, "\t"), "\t"); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	return ""
+}
+
+// StripInline removes inline markdown and HTML tags from text: emphasis,
+// code spans, links and images keep only their text.
+func StripInline(text string) string {
+	text = inlineImage.ReplaceAllString(text, "$1")
+	text = inlineLink.ReplaceAllString(text, "$1")
+	text = codeSpanPattern.ReplaceAllString(text, "$1")
+	text = strongPattern.ReplaceAllString(text, "$1$2")
+	text = emphasisPattern.ReplaceAllString(text, "$1$2")
+	text = inlineTagPattern.ReplaceAllString(text, "")
+	text = escapedMarkPattern.ReplaceAllString(text, "$1")
+	return html.UnescapeString(text)
+}
+
+func htmlBlocks(blocks []types.Block) string {
+	var sb strings.Builder
+	// lists holds the tag of each open list, outermost first. Each has an
+	// open <li> so deeper lists nest inside it.
+	var lists []string
+	closeLists := func(depth int) {
+		for len(lists) > depth {
+			sb.WriteString("</li></" + lists[len(lists)-1] + ">\n")
+			lists = lists[:len(lists)-1]
+		}
+	}
+
+	for _, b := range blocks {
+		if b.Type == types.BlockListItem {
+			tag := "ul"
+			if b.Ordered {
+				tag = "ol"
+			}
+			level := min(b.Level, len(lists))
+			closeLists(level + 1)
+			if len(lists) == level+1 {
+				if lists[level] == tag {
+					sb.WriteString("</li>\n")
+				} else {
+					closeLists(level)
+				}
+			}
+			if len(lists) == level {
+				sb.WriteString("<" + tag + ">\n")
+				lists = append(lists, tag)
+			}
+			sb.WriteString("<li>" + inlineHTML(b.Text))
+			continue
+		}
+		closeLists(0)
+
+		switch b.Type {
+		case types.BlockHeading:
+			level := strconv.Itoa(min(max(b.Level, 1), 6))
+			sb.WriteString("<h" + level + ">" + inlineHTML(b.Text) + "</h" + level + ">\n")
+		case types.BlockParagraph:
+			sb.WriteString("<p>" + strings.ReplaceAll(inlineHTML(b.Text), "\n", "<br>\n") + "</p>\n")
+		case types.BlockCode:
+			sb.WriteString("<pre><code")
+			if b.Language != "" {
+				sb.WriteString(` class="language-` + html.EscapeString(b.Language) + `"`)
+			}
+			sb.WriteString(">" + html.EscapeString(b.Text) + "</code></pre>\n")
+		case types.BlockQuote:
+			sb.WriteString("<blockquote>\n" + htmlBlocks(b.Children) + "</blockquote>\n")
+		case types.BlockTable:
+			if len(b.Cells) > 0 {
+				sb.WriteString(RenderTable(NewTable(b.Cells, 1, b.Source), TableHTML) + "\n")
+			}
+		case types.BlockImage:
+			if b.Text != "" {
+				sb.WriteString("<figure><figcaption>" + html.EscapeString(b.Text) + "</figcaption></figure>\n")
+			}
+		case types.BlockPageBreak:
+			sb.WriteString("<hr>\n")
+		}
+	}
+	closeLists(0)
+	return sb.String()
+}
+
+// inlineHTML escapes text and renders its inline markdown: code spans,
+// links, emphasis and images, which keep only their alt text. Tags in the
+// text are escaped, not kept.
+func inlineHTML(text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, m := range codeSpanPattern.FindAllStringSubmatchIndex(text, -1) {
+		sb.WriteString(inlineHTMLRun(text[last:m[0]]))
+		sb.WriteString("<code>" + html.EscapeString(text[m[2]:m[3]]) + "</code>")
+		last = m[1]
+	}
+	sb.WriteString(inlineHTMLRun(text[last:]))
+	return sb.String()
+}
+
+func inlineHTMLRun(text string) string {
+	text = inlineImage.ReplaceAllString(text, "$1")
+	text = html.EscapeString(text)
+	text = inlineLink.ReplaceAllStringFunc(text, func(s string) string {
+		m := inlineLink.FindStringSubmatch(s)
+		// The URL was escaped with the rest of the text, which is what an
+		// attribute needs; only the scheme is left to check.
+		if !safeURLPattern.MatchString(html.UnescapeString(m[2])) {
+			return m[1]
+		}
+		return `<a href="` + m[2] + `">` + m[1] + "</a>"
+	})
+	text = strongPattern.ReplaceAllString(text, "<strong>$1$2</strong>")
+	text = emphasisPattern.ReplaceAllString(text, "<em>$1$2</em>")
+	return escapedMarkPattern.ReplaceAllString(text, "$1")
+}
@@ -0,0 +1,377 @@
+package format
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/toricodesthings/file-processing-service/internal/types"
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Table formats accepted by the tableFormat option.
+const (
+	TableMarkdown = "markdown"
+	TableHTML     = "html"
+	TableCSV      = "csv"
+	TableJSON     = "json"
+)
+
+// TableFormats lists every table format, the default first.
+var TableFormats = []string{TableMarkdown, TableHTML, TableCSV, TableJSON}
+
+// maxColSpan caps declared column spans, as browsers do, so a hostile
+// colspan can't blow up the grid.
+const maxColSpan = 1000
+
+var (
+	htmlTableStart = regexp.MustCompile(`(?i)^<table[\s>]`)
+	htmlTableEnd   = regexp.MustCompile(`(?i)</table>`)
+)
+
+// TableCell is a cell as a format declares it, before merged cells are laid
+// out on the grid. Spans below 1 count as 1.
+type TableCell struct {
+	Text    string
+	ColSpan int
+	RowSpan int
+}
+
+// NewTable builds a table from rows of cell text, padding copies of them to
+// the same width.
+func NewTable(rows [][]string, headerRows int, src types.Source) types.Table {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		cells[i] = make([]string, width)
+		copy(cells[i], row)
+	}
+	return types.Table{Source: src, HeaderRows: headerCount(headerRows, len(rows)), Cells: cells}
+}
+
+// LayoutTable places rows of cells on a grid, skipping the slots covered by
+// cells spanning down from earlier rows, and records every span as a merge.
+func LayoutTable(rows [][]TableCell, headerRows int, src types.Source) types.Table {
+	grid := make([][]string, len(rows))
+	covered := map[[2]int]bool{}
+	var merges []types.Merge
+	width := 0
+	for r, row := range rows {
+		col := 0
+		for _, cell := range row {
+			for covered[[2]int{r, col}] {
+				col++
+			}
+			colSpan := min(max(cell.ColSpan, 1), maxColSpan)
+			rowSpan := min(max(cell.RowSpan, 1), len(rows)-r)
+			for len(grid[r]) <= col {
+				grid[r] = append(grid[r], "")
+			}
+			grid[r][col] = cell.Text
+			if colSpan > 1 || rowSpan > 1 {
+				merges = append(merges, types.Merge{Row: r, Col: col, RowSpan: rowSpan, ColSpan: colSpan})
+				for dr := 0; dr < rowSpan; dr++ {
+					for dc := 0; dc < colSpan; dc++ {
+						if dr > 0 || dc > 0 {
+							covered[[2]int{r + dr, col + dc}] = true
+						}
+					}
+				}
+			}
+			col += colSpan
+			width = max(width, col)
+		}
+	}
+	t := NewTable(grid, headerRows, src)
+	for i := range t.Cells {
+		for len(t.Cells[i]) < width {
+			t.Cells[i] = append(t.Cells[i], "")
+		}
+	}
+	t.Merges = merges
+	return t
+}
+
+func headerCount(headerRows, rows int) int {
+	if headerRows < 1 && rows > 0 {
+		return 1
+	}
+	return min(headerRows, rows)
+}
+
+// RenderTable renders t in one of TableFormats. CSV and JSON come out as
+// fenced code blocks so the surrounding markdown stays intact; unknown
+// formats render markdown.
+func RenderTable(t types.Table, tableFormat string) string {
+	if len(t.Cells) == 0 {
+		return ""
+	}
+	switch tableFormat {
+	case TableHTML:
+		return htmlTable(t)
+	case TableCSV:
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		_ = w.WriteAll(t.Cells) // writes to memory cannot fail
+		return "```csv\n" + buf.String() + "```"
+	case TableJSON:
+		b, _ := json.Marshal(struct {
+			HeaderRows int           `json:"headerRows"`
+			Cells      [][]string    `json:"cells"`
+			Merges     []types.Merge `json:"merges,omitempty"`
+		}{t.HeaderRows, t.Cells, t.Merges})
+		return "```json\n" + string(b) + "\n```"
+	}
+	return markdownTable(t.Cells)
+}
+
+// markdownTable renders rows as a pipe table with the first row as header.
+func markdownTable(rows [][]string) string {
+	cell := strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ")
+	line := func(row []string) string {
+		escaped := make([]string, len(row))
+		for i, c := range row {
+			escaped[i] = cell.Replace(c)
+		}
+		return "| " + strings.Join(escaped, " | ") + " |"
+	}
+	sep := make([]string, len(rows[0]))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	lines := []string{line(rows[0]), "| " + strings.Join(sep, " | ") + " |"}
+	for _, row := range rows[1:] {
+		lines = append(lines, line(row))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func htmlTable(t types.Table) string {
+	anchors := map[[2]int]types.Merge{}
+	covered := map[[2]int]bool{}
+	for _, m := range t.Merges {
+		anchors[[2]int{m.Row, m.Col}] = m
+		for dr := 0; dr < m.RowSpan; dr++ {
+			for dc := 0; dc < m.ColSpan; dc++ {
+				if dr > 0 || dc > 0 {
+					covered[[2]int{m.Row + dr, m.Col + dc}] = true
+				}
+			}
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<table>\n")
+	for r, row := range t.Cells {
+		switch r {
+		case 0:
+			if t.HeaderRows > 0 {
+				sb.WriteString("<thead>\n")
+			} else {
+				sb.WriteString("<tbody>\n")
+			}
+		case t.HeaderRows:
+			sb.WriteString("</thead>\n<tbody>\n")
+		}
+		tag := "td"
+		if r < t.HeaderRows {
+			tag = "th"
+		}
+		sb.WriteString("<tr>")
+		for c, text := range row {
+			if covered[[2]int{r, c}] {
+				continue
+			}
+			sb.WriteString("<" + tag)
+			if m, ok := anchors[[2]int{r, c}]; ok {
+				if m.ColSpan > 1 {
+					sb.WriteString(` colspan="` + strconv.Itoa(m.ColSpan) + `"`)
+				}
+				if m.RowSpan > 1 {
+					sb.WriteString(` rowspan="` + strconv.Itoa(m.RowSpan) + `"`)
+				}
+			}
+			sb.WriteString(">" + html.EscapeString(text) + "</" + tag + ">")
+		}
+		sb.WriteString("</tr>\n")
+	}
+	if t.HeaderRows >= len(t.Cells) {
+		sb.WriteString("</thead>\n</table>")
+	} else {
+		sb.WriteString("</tbody>\n</table>")
+	}
+	return sb.String()
+}
+
+// HTMLTable reads a parsed <table> element. Rows in <thead>, or leading
+// rows of <th> cells, are headers; nested tables are skipped.
+func HTMLTable(table *nethtml.Node, src types.Source) types.Table {
+	var rows [][]TableCell
+	headers, body := 0, false
+	var walk func(n *nethtml.Node, inHead bool)
+	walk = func(n *nethtml.Node, inHead bool) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != nethtml.ElementNode {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.Thead:
+				walk(c, true)
+			case atom.Tbody, atom.Tfoot:
+				walk(c, false)
+			case atom.Tr:
+				var row []TableCell
+				allTH := true
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type != nethtml.ElementNode || (cell.DataAtom != atom.Td && cell.DataAtom != atom.Th) {
+						continue
+					}
+					allTH = allTH && cell.DataAtom == atom.Th
+					row = append(row, TableCell{
+						Text:    strings.Join(strings.Fields(nodeText(cell)), " "),
+						ColSpan: spanAttr(cell, "colspan"),
+						RowSpan: spanAttr(cell, "rowspan"),
+					})
+				}
+				if len(row) == 0 {
+					continue
+				}
+				if !body && (inHead || allTH) {
+					headers++
+				} else {
+					body = true
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	walk(table, false)
+	return LayoutTable(rows, headers, src)
+}
+
+func spanAttr(n *nethtml.Node, key string) int {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			v, _ := strconv.Atoi(strings.TrimSpace(a.Val))
+			return v
+		}
+	}
+	return 1
+}
+
+func nodeText(n *nethtml.Node) string {
+	if n.Type == nethtml.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == nethtml.ElementNode && c.DataAtom == atom.Table {
+			continue
+		}
+		sb.WriteString(nodeText(c))
+		sb.WriteString(" ")
+	}
+	return sb.String()
+}
+
+// ParseTables finds the tables in extracted text: markdown pipe tables and
+// HTML tables, as OCR returns them, with their spans. Tables in code fences
+// are skipped. Source.Line is the table's first line in text.
+func ParseTables(text string) []types.Table {
+	var tables []types.Table
+	eachTable(text, func(line int, rows [][]string, raw string) string {
+		if rows == nil {
+			tables = append(tables, parseHTMLTables(raw, line)...)
+		} else {
+			tables = append(tables, NewTable(rows, 1, types.Source{Line: line}))
+		}
+		return raw
+	})
+	return tables
+}
+
+// RenderTables re-renders the markdown pipe tables in text in tableFormat,
+// for extractors that only produce markdown. Spans are unknown there.
+func RenderTables(text, tableFormat string) string {
+	if tableFormat == "" || tableFormat == TableMarkdown {
+		return text
+	}
+	return eachTable(text, func(line int, rows [][]string, raw string) string {
+		if rows == nil {
+			return raw
+		}
+		return RenderTable(NewTable(rows, 1, types.Source{}), tableFormat)
+	})
+}
+
+// eachTable calls fn for every table outside code fences with its first
+// line, its source text and, for pipe tables, its rows; rows is nil for HTML
+// tables. It returns text with each table replaced by what fn returned.
+func eachTable(text string, fn func(line int, rows [][]string, raw string) string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		case htmlTableStart.MatchString(trimmed):
+			j := i
+			for j < len(lines)-1 && !htmlTableEnd.MatchString(lines[j]) {
+				j++
+			}
+			out = append(out, fn(i+1, nil, strings.Join(lines[i:j+1], "\n")))
+			i = j
+			continue
+		case strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && tableSepPattern.MatchString(strings.TrimSpace(lines[i+1])):
+			rows := [][]string{splitTableRow(trimmed)}
+			j := i + 2
+			for ; j < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[j]), "|"); j++ {
+				rows = append(rows, splitTableRow(lines[j]))
+			}
+			out = append(out, fn(i+1, rows, strings.Join(lines[i:j], "\n")))
+			i = j - 1
+			continue
+		}
+		out = append(out, lines[i])
+	}
+	return strings.Join(out, "\n")
+}
+
+func parseHTMLTables(src string, line int) []types.Table {
+	body := &nethtml.Node{Type: nethtml.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := nethtml.ParseFragment(strings.NewReader(src), body)
+	if err != nil {
+		return nil
+	}
+	var tables []types.Table
+	var find func(*nethtml.Node)
+	find = func(n *nethtml.Node) {
+		if n.Type == nethtml.ElementNode && n.DataAtom == atom.Table {
+			if t := HTMLTable(n, types.Source{Line: line}); len(t.Cells) > 0 {
+				tables = append(tables, t)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	for _, n := range nodes {
+		find(n)
+	}
+	return tables
+}
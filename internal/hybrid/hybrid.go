@@ -9,9 +9,11 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/toricodesthings/file-processing-service/internal/budget"
 	"github.com/toricodesthings/file-processing-service/internal/config"
 	"github.com/toricodesthings/file-processing-service/internal/extractor"
 	"github.com/toricodesthings/file-processing-service/internal/format"
+	"github.com/toricodesthings/file-processing-service/internal/metrics"
 	"github.com/toricodesthings/file-processing-service/internal/ocr"
 	"github.com/toricodesthings/file-processing-service/internal/quality"
 	"github.com/toricodesthings/file-processing-service/internal/types"
@@ -23,6 +25,10 @@ type Processor struct {
 
 	// Extractor config (your PageCount signature requires this)
 	extractCfg extractor.ExtractorConfig
+
+	pageCache PageCache
+
+	ocrProviders *ocr.Providers
 }
 
 func New(cfg config.Config) *Processor {
@@ -33,7 +39,42 @@ func New(cfg config.Config) *Processor {
 			PDFToTextTimeout:    cfg.PDFToTextTimeout,
 			PDFToTextAllTimeout: cfg.PDFToTextAllTimeout,
 		},
+		ocrProviders: newOCRProviders(cfg),
+	}
+}
+
+// newOCRProviders registers every OCR backend; OCR_PROVIDER picks the default
+// and OCR_FALLBACK_PROVIDERS the failover order.
+func newOCRProviders(cfg config.Config) *ocr.Providers {
+	mistral := ocr.NewMistral(cfg.MistralAPIKey, cfg.OCRInlineMaxBytes)
+	tesseract := ocr.NewTesseract(ocr.TesseractConfig{
+		TesseractBinary: cfg.TesseractBinary,
+		PDFToPPMBinary:  cfg.PDFToPPMBinary,
+		Languages:       cfg.TesseractLanguage,
+		DPI:             cfg.TesseractDPI,
+		PageTimeout:     cfg.TesseractTimeout,
+	})
+	providers, err := ocr.NewProviders(cfg.OCRProvider, mistral, tesseract)
+	if err != nil {
+		// Config.Validate rejects unknown names before we get here.
+		providers, _ = ocr.NewProviders(ocr.ProviderMistral, mistral, tesseract)
 	}
+	if err := providers.SetFallbacks(cfg.OCRFallbackProviders); err != nil {
+		fmt.Fprintf(os.Stderr, "ignoring OCR fallbacks: %v\n", err)
+	}
+	providers.SetBreakers(cfg.OCRBreakerThreshold, cfg.OCRBreakerCooldown)
+	return providers
+}
+
+// OCRProviders exposes the registered OCR backends so other extractors
+// (images) share the same configuration.
+func (p *Processor) OCRProviders() *ocr.Providers {
+	return p.ocrProviders
+}
+
+// SetPageCache enables per-page OCR caching; nil disables it.
+func (p *Processor) SetPageCache(cache PageCache) {
+	p.pageCache = cache
 }
 
 // ApplyDefaults merges server defaults into request options without overwriting valid user choices.
@@ -51,6 +92,9 @@ func (p *Processor) ApplyDefaults(opts types.HybridProcessorOptions) types.Hybri
 		m := p.cfg.DefaultOCRModel
 		opts.OCRModel = &m
 	}
+	if opts.OCRProvider == "" {
+		opts.OCRProvider = p.ocrProviders.Default()
+	}
 	if opts.PreviewMaxPages <= 0 {
 		opts.PreviewMaxPages = p.cfg.DefaultPreviewMaxPages
 	}
@@ -85,17 +129,17 @@ func (p *Processor) ProcessHybrid(
 		return result, errors.New(msg)
 	}
 
-	// Determine pages to process
-	pages := opts.Pages
-	if len(pages) == 0 {
-		pages = make([]int, totalPages)
-		for i := range pages {
-			pages[i] = i + 1
-		}
+	pages, err := selectPages(opts.Pages, totalPages)
+	if err != nil {
+		msg := err.Error()
+		result.Error = &msg
+		return result, err
 	}
 
+	opts.Progress.Report(types.ProgressEvent{Stage: types.ProgressPageCount, TotalPages: totalPages, Pages: len(pages)})
+
 	// Phase 1: Extract text from all pages in parallel
-	pageResults := p.extractPagesParallel(ctx, pdfPath, pages, opts.MinWordsThreshold)
+	pageResults := p.extractPagesParallel(ctx, pdfPath, pages, opts.MinWordsThreshold, opts.Progress)
 
 	// Phase 2: Analyze quality
 	needsOCRPages := make([]int, 0)
@@ -122,20 +166,36 @@ func (p *Processor) ProcessHybrid(
 			ocrPages = needsOCRPages
 		}
 
-		ocrResults, err := runOCRBatch(ctx, presignedURL, ocrPages, opts)
+		opts.Progress.Report(types.ProgressEvent{Stage: types.ProgressOCRStarted, Pages: len(ocrPages), Provider: opts.OCRProvider})
+		// On failure ocrResults still holds the pages served from cache.
+		doc := newOCRDocument(presignedURL, pdfPath)
+		ocrResults, cachedPages, err := p.runOCRBatch(ctx, doc, ocrPages, opts)
+		doc.close()
+		done := types.ProgressEvent{Stage: types.ProgressOCRFinished, Pages: len(ocrResults), Cached: cachedPages}
 		if err != nil {
+			done.Error = err.Error()
+		}
+		opts.Progress.Report(done)
+		switch {
+		case errors.Is(err, budget.ErrExceeded):
+			msg := "OCR skipped: tenant budget exceeded"
+			result.Error = &msg
+			result.BudgetExceeded = true
+		case err != nil:
 			msg := fmt.Sprintf("OCR failed: %v", err)
 			result.Error = &msg
-		} else {
-			mergeOCRResults(&result, ocrResults, shouldDoFullOCR)
 		}
+		mergeOCRResults(&result, ocrResults, shouldDoFullOCR)
+		result.OCRCachedPages = cachedPages
+		result.NeedsOCR = countNeedsOCRPages(result.Pages) > 0
 	}
 
 	// Phase 4: Combine and format
 	result.Text = format.Combine(result.Pages, opts.PageSeparator, opts.IncludePageNumbers)
 	result.OCRPages = countOCRPages(result.Pages)
 	result.TextLayerPages = len(result.Pages) - result.OCRPages
-	result.CostSavingsPercent = calculateSavings(result.TextLayerPages, result.TotalPages)
+	// Pages served from the OCR cache were not billed either.
+	result.CostSavingsPercent = calculateSavings(result.TextLayerPages+result.OCRCachedPages, len(pages))
 	result.Success = true
 
 	return result, nil
@@ -166,7 +226,7 @@ func (p *Processor) ProcessPreview(ctx context.Context, pdfPath string, opts typ
 		pages[i] = i + 1
 	}
 
-	pageResults := p.extractPagesParallel(ctx, pdfPath, pages, opts.MinWordsThreshold)
+	pageResults := p.extractPagesParallel(ctx, pdfPath, pages, opts.MinWordsThreshold, nil)
 
 	needsOCR := 0
 	totalWords := 0
@@ -201,7 +261,27 @@ func (p *Processor) ProcessPreview(ctx context.Context, pdfPath string, opts typ
 
 // ---------- Internal ----------
 
-func (p *Processor) extractPagesParallel(ctx context.Context, pdfPath string, pages []int, minWords int) []types.PageExtractionResult {
+// selectPages returns the requested 1-based pages, or every page when none
+// were requested.
+func selectPages(requested []int, totalPages int) ([]int, error) {
+	pages := requested
+	if len(pages) == 0 {
+		pages = make([]int, totalPages)
+		for i := range pages {
+			pages[i] = i + 1
+		}
+	}
+	for _, pg := range pages {
+		if pg < 1 || pg > totalPages {
+			return nil, fmt.Errorf("page %d out of range (document has %d pages)", pg, totalPages)
+		}
+	}
+	return pages, nil
+}
+
+// extractPagesParallel reads each page's text layer; progress, if set, hears
+// about every page as soon as its worker finishes.
+func (p *Processor) extractPagesParallel(ctx context.Context, pdfPath string, pages []int, minWords int, progress types.ProgressFunc) []types.PageExtractionResult {
 	results := make([]types.PageExtractionResult, len(pages))
 
 	workers := runtime.NumCPU()
@@ -232,7 +312,9 @@ func (p *Processor) extractPagesParallel(ctx context.Context, pdfPath string, pa
 			}
 			defer sem.Release(1)
 
-			results[idx] = p.extractSinglePage(ctx, pdfPath, page, minWords)
+			res := p.extractSinglePage(ctx, pdfPath, page, minWords)
+			results[idx] = res
+			progress.Report(types.ProgressEvent{Stage: types.ProgressPage, Page: res.PageNumber, Method: res.Method, WordCount: res.WordCount})
 		}(i, pageNum)
 	}
 
@@ -272,51 +354,171 @@ func (p *Processor) extractSinglePage(ctx context.Context, pdfPath string, pageN
 	return result
 }
 
-func runOCRBatch(ctx context.Context, presignedURL string, pages []int, opts types.HybridProcessorOptions) (map[int]string, error) {
+// ocrPage is one page of OCR output and the provider that produced it.
+type ocrPage struct {
+	Markdown string
+	Provider string
+}
+
+// ocrDocument is the PDF handed to OCR providers. Without a presigned URL,
+// providers that need one for the local file (ocr.DocumentResolver) get it
+// resolved on first use and reuse it for later batches, so a streamed
+// document is uploaded once rather than once per window. It is not safe for
+// concurrent use; close releases anything resolved.
+type ocrDocument struct {
+	presignedURL string
+	path         string
+	resolved     map[string]string
+	cleanups     []func()
+}
+
+func newOCRDocument(presignedURL, path string) *ocrDocument {
+	return &ocrDocument{presignedURL: presignedURL, path: path, resolved: map[string]string{}}
+}
+
+// url returns the document URL to send to provider, which is empty when the
+// provider reads the local file itself.
+func (d *ocrDocument) url(ctx context.Context, provider ocr.Provider) (string, error) {
+	if d.presignedURL != "" || d.path == "" {
+		return d.presignedURL, nil
+	}
+	resolver, ok := provider.(ocr.DocumentResolver)
+	if !ok {
+		return "", nil
+	}
+	if u, ok := d.resolved[provider.Name()]; ok {
+		return u, nil
+	}
+	u, cleanup, err := resolver.ResolveDocument(ctx, d.path)
+	if err != nil {
+		return "", err
+	}
+	d.resolved[provider.Name()] = u
+	d.cleanups = append(d.cleanups, cleanup)
+	return u, nil
+}
+
+func (d *ocrDocument) close() {
+	for _, cleanup := range d.cleanups {
+		cleanup()
+	}
+	d.cleanups = nil
+	clear(d.resolved)
+}
+
+// runOCRBatch OCRs the given 1-based pages through the provider chain: the
+// requested provider first, then the configured fallbacks. Pages already in
+// the page cache under any provider in the chain are not sent again; the
+// second return value counts them. On failure the cached pages are still
+// returned.
+func (p *Processor) runOCRBatch(ctx context.Context, doc *ocrDocument, pages []int, opts types.HybridProcessorOptions) (map[int]ocrPage, int, error) {
 	if len(pages) == 0 {
-		return map[int]string{}, nil
+		return map[int]ocrPage{}, 0, nil
+	}
+	chain, err := p.ocrProviders.Chain(opts.OCRProvider)
+	if err != nil {
+		return map[int]ocrPage{}, 0, err
+	}
+
+	results := make(map[int]ocrPage, len(pages))
+	docHash := ""
+	if p.pageCache != nil {
+		docHash = opts.DocumentHash
+		if docHash == "" {
+			docHash, _ = hashFile(doc.path)
+		}
+	}
+	cacheKey := func(provider string, pg int) string {
+		return PageCacheKey(docHash, pg, provider, *opts.OCRModel, opts.ExtractHeader, opts.ExtractFooter)
+	}
+	if docHash != "" {
+		for _, pg := range pages {
+			for _, prov := range chain {
+				if md, ok := p.pageCache.Get(ctx, cacheKey(prov.Name(), pg)); ok {
+					results[pg] = ocrPage{Markdown: md, Provider: prov.Name()}
+					break
+				}
+			}
+			_, hit := results[pg]
+			metrics.ObserveCache(metrics.CacheOCRPage, hit)
+		}
+	}
+	cached := len(results)
+
+	missing := make([]int, 0, len(pages)-cached)
+	for _, pg := range pages {
+		if _, ok := results[pg]; !ok {
+			missing = append(missing, pg)
+		}
+	}
+	if len(missing) == 0 {
+		fmt.Fprintf(os.Stderr, "ocr cache: all %d pages cached model=%s\n", cached, *opts.OCRModel)
+		return results, cached, nil
+	}
+
+	// Over budget: cached pages are still served, but only free providers run.
+	if !budget.Allowed(ctx) {
+		chain = ocr.FreeProviders(chain)
+		if len(chain) == 0 {
+			fmt.Fprintf(os.Stderr, "ocr skipped: pages=%d tenant budget exceeded\n", len(missing))
+			return results, cached, budget.ErrExceeded
+		}
 	}
 
-	fmt.Fprintf(os.Stderr, "ocr start: pages=%d model=%s\n", len(pages), *opts.OCRModel)
+	fmt.Fprintf(os.Stderr, "ocr start: pages=%d cached=%d provider=%s model=%s\n", len(missing), cached, chain[0].Name(), *opts.OCRModel)
 
 	// Convert to 0-indexed
-	pages0 := make([]int, len(pages))
-	for i, p := range pages {
-		pages0[i] = p - 1
-	}
-
-	ocrResp, err := ocr.RunMistralOCR(
-		ctx,
-		presignedURL,
-		*opts.OCRModel,
-		pages0,
-		opts.ExtractHeader,
-		opts.ExtractFooter,
-	)
+	pages0 := make([]int, len(missing))
+	for i, pg := range missing {
+		pages0[i] = pg - 1
+	}
+
+	var ocrResp ocr.OCRResponse
+	used, err := p.ocrProviders.Do(ctx, chain, func(provider ocr.Provider) error {
+		documentURL, err := doc.url(ctx, provider)
+		if err != nil {
+			return err
+		}
+		resp, err := provider.OCRDocument(ctx, ocr.DocumentRequest{
+			URL:           documentURL,
+			LocalPath:     doc.path,
+			Pages0:        pages0,
+			Model:         *opts.OCRModel,
+			ExtractHeader: opts.ExtractHeader,
+			ExtractFooter: opts.ExtractFooter,
+		})
+		ocrResp = resp
+		return err
+	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ocr failed: %v\n", err)
-		return nil, err
+		return results, cached, err
 	}
 
-	fmt.Fprintf(os.Stderr, "ocr done: pages=%d model=%s\n", len(ocrResp.Pages), *opts.OCRModel)
+	fmt.Fprintf(os.Stderr, "ocr done: pages=%d provider=%s model=%s\n", len(ocrResp.Pages), used.Name(), *opts.OCRModel)
 
-	results := make(map[int]string, len(ocrResp.Pages))
 	for _, page := range ocrResp.Pages {
 		pageNum := page.Index + 1
-		results[pageNum] = cleanText(page.Markdown)
+		md := cleanText(page.Markdown)
+		results[pageNum] = ocrPage{Markdown: md, Provider: used.Name()}
+		if docHash != "" {
+			p.pageCache.Set(ctx, cacheKey(used.Name(), pageNum), md)
+		}
 	}
 
-	return results, nil
+	return results, cached, nil
 }
 
-func mergeOCRResults(result *types.HybridExtractionResult, ocrResults map[int]string, fullOCR bool) {
+// mergeOCRResults replaces page text with OCR output. The method records the
+// provider that served the page, e.g. "ocr:mistral".
+func mergeOCRResults(result *types.HybridExtractionResult, ocrResults map[int]ocrPage, fullOCR bool) {
 	for i := range result.Pages {
 		pageNum := result.Pages[i].PageNumber
-		if ocrText, exists := ocrResults[pageNum]; exists {
+		if page, exists := ocrResults[pageNum]; exists {
 			if fullOCR || result.Pages[i].Method == "needs-ocr" {
-				result.Pages[i].Text = ocrText
-				result.Pages[i].Method = "ocr"
-				result.Pages[i].WordCount = quality.CountWords(ocrText)
+				result.Pages[i].Text = page.Markdown
+				result.Pages[i].Method = "ocr:" + page.Provider
+				result.Pages[i].WordCount = quality.CountWords(page.Markdown)
 			}
 		}
 	}
@@ -377,18 +579,30 @@ func cleanText(text string) string {
 func countOCRPages(pages []types.PageExtractionResult) int {
 	count := 0
 	for _, p := range pages {
-		if p.Method == "ocr" {
+		if strings.HasPrefix(p.Method, "ocr:") {
 			count++
 		}
 	}
 	return count
 }
 
-func calculateSavings(textLayerPages, totalPages int) int {
-	if totalPages == 0 {
+func countNeedsOCRPages(pages []types.PageExtractionResult) int {
+	count := 0
+	for _, p := range pages {
+		if p.Method == "needs-ocr" {
+			count++
+		}
+	}
+	return count
+}
+
+// calculateSavings returns the share of the selected pages that needed no
+// paid OCR call, as a percentage.
+func calculateSavings(freePages, selectedPages int) int {
+	if selectedPages == 0 {
 		return 0
 	}
-	return int(float64(textLayerPages) / float64(totalPages) * 100)
+	return int(float64(freePages) / float64(selectedPages) * 100)
 }
 
 func isPasswordProtectedErr(err error) bool {
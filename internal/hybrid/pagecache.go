@@ -0,0 +1,208 @@
+package hybrid
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PageCache stores OCR markdown per document page so overlapping requests
+// only pay for pages that have not been OCR'd before. Implementations must
+// be safe for concurrent use; failures behave as misses.
+type PageCache interface {
+	Get(ctx context.Context, key string) (string, bool)
+	Set(ctx context.Context, key, markdown string)
+}
+
+// PageCacheKey identifies one page's OCR output. The provider, model and
+// header/footer extraction all change the markdown, so they are part of the key.
+func PageCacheKey(documentHash string, page int, provider, model string, header, footer bool) string {
+	h := sha256.New()
+	for _, part := range []string{documentHash, strconv.Itoa(page), provider, model, strconv.FormatBool(header), strconv.FormatBool(footer)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashFile is the fallback when the caller did not hash the PDF while downloading it.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ---------- In-memory LRU ----------
+
+type pageEntry struct {
+	key      string
+	markdown string
+	storedAt time.Time
+}
+
+type MemoryPageCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	ll         *list.List
+	items      map[string]*list.Element
+	now        func() time.Time
+}
+
+func NewMemoryPageCache(maxEntries int, ttl time.Duration) *MemoryPageCache {
+	if maxEntries <= 0 {
+		maxEntries = 5000
+	}
+	return &MemoryPageCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+func (c *MemoryPageCache) Get(_ context.Context, key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+	ent := el.Value.(*pageEntry)
+	if c.ttl > 0 && c.now().Sub(ent.storedAt) > c.ttl {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return "", false
+	}
+	c.ll.MoveToFront(el)
+	return ent.markdown, true
+}
+
+func (c *MemoryPageCache) Set(_ context.Context, key, markdown string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value = &pageEntry{key: key, markdown: markdown, storedAt: c.now()}
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&pageEntry{key: key, markdown: markdown, storedAt: c.now()})
+	for c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*pageEntry).key)
+	}
+}
+
+// ---------- File-backed ----------
+
+var validPageKeyPattern = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+// FilePageCache writes one markdown file per page. Entry age is taken from
+// the file's modification time.
+type FilePageCache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+func NewFilePageCache(dir string, ttl time.Duration) (*FilePageCache, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, fmt.Errorf("ocr page cache dir required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("ocr page cache dir: %w", err)
+	}
+	return &FilePageCache{dir: dir, ttl: ttl, now: time.Now}, nil
+}
+
+func (c *FilePageCache) path(key string) (string, bool) {
+	if !validPageKeyPattern.MatchString(key) {
+		return "", false
+	}
+	return filepath.Join(c.dir, key+".md"), true
+}
+
+func (c *FilePageCache) Get(_ context.Context, key string) (string, bool) {
+	p, ok := c.path(key)
+	if !ok {
+		return "", false
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return "", false
+	}
+	if c.expired(info.ModTime()) {
+		_ = os.Remove(p)
+		return "", false
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+func (c *FilePageCache) Set(_ context.Context, key, markdown string) {
+	p, ok := c.path(key)
+	if !ok {
+		return
+	}
+	tmp, err := os.CreateTemp(c.dir, key+"-*.tmp")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.WriteString(markdown)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil || os.Rename(tmp.Name(), p) != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// Prune deletes expired pages and returns how many were removed.
+func (c *FilePageCache) Prune(_ context.Context) int {
+	if c.ttl <= 0 {
+		return 0
+	}
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, de := range entries {
+		if de.IsDir() || !(strings.HasSuffix(de.Name(), ".md") || strings.HasSuffix(de.Name(), ".tmp")) {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil || !c.expired(info.ModTime()) {
+			continue
+		}
+		if os.Remove(filepath.Join(c.dir, de.Name())) == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+func (c *FilePageCache) expired(storedAt time.Time) bool {
+	return c.ttl > 0 && c.now().Sub(storedAt) > c.ttl
+}
@@ -0,0 +1,218 @@
+package hybrid
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/budget"
+	"github.com/toricodesthings/file-processing-service/internal/config"
+	"github.com/toricodesthings/file-processing-service/internal/ocr"
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+func TestPageCacheKeyVariesByInputs(t *testing.T) {
+	base := PageCacheKey("doc", 1, "mistral", "mistral-ocr-latest", false, false)
+	for name, other := range map[string]string{
+		"page":     PageCacheKey("doc", 2, "mistral", "mistral-ocr-latest", false, false),
+		"provider": PageCacheKey("doc", 1, "tesseract", "mistral-ocr-latest", false, false),
+		"model":    PageCacheKey("doc", 1, "mistral", "other-model", false, false),
+		"doc":      PageCacheKey("doc2", 1, "mistral", "mistral-ocr-latest", false, false),
+		"header":   PageCacheKey("doc", 1, "mistral", "mistral-ocr-latest", true, false),
+	} {
+		if other == base {
+			t.Fatalf("key should change with %s", name)
+		}
+	}
+	if PageCacheKey("doc", 1, "mistral", "mistral-ocr-latest", false, false) != base {
+		t.Fatalf("key should be deterministic")
+	}
+}
+
+func TestMemoryPageCacheEvictsAndExpires(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryPageCache(2, time.Hour)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "a", "page a")
+	c.Set(ctx, "b", "page b")
+	c.Get(ctx, "a")
+	c.Set(ctx, "c", "page c")
+	if _, ok := c.Get(ctx, "b"); ok {
+		t.Fatalf("expected b to be evicted")
+	}
+	if md, ok := c.Get(ctx, "a"); !ok || md != "page a" {
+		t.Fatalf("expected a to survive, got %q %v", md, ok)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := c.Get(ctx, "a"); ok {
+		t.Fatalf("expected a to expire")
+	}
+}
+
+func TestFilePageCacheRoundTripAndPrune(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewFilePageCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	key := PageCacheKey("doc", 1, "mistral", "m", false, false)
+	c.Set(ctx, key, "# Page one")
+	if md, ok := c.Get(ctx, key); !ok || md != "# Page one" {
+		t.Fatalf("round trip failed: %q %v", md, ok)
+	}
+	if _, ok := c.Get(ctx, "not-a-key"); ok {
+		t.Fatalf("invalid keys must miss")
+	}
+
+	c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if n := c.Prune(ctx); n != 1 {
+		t.Fatalf("expected 1 pruned page, got %d", n)
+	}
+}
+
+func TestRunOCRBatchServesCachedPagesWithoutCallingProvider(t *testing.T) {
+	ctx := context.Background()
+	p := New(config.Config{})
+	cache := NewMemoryPageCache(10, time.Hour)
+	p.SetPageCache(cache)
+
+	model := "mistral-ocr-latest"
+	opts := p.ApplyDefaults(types.HybridProcessorOptions{OCRModel: &model, DocumentHash: "abc"})
+	for _, pg := range []int{1, 2, 3} {
+		cache.Set(ctx, PageCacheKey("abc", pg, "mistral", model, false, false), "cached page")
+	}
+
+	// No presigned URL and no file on disk: any provider call would fail.
+	results, cached, err := p.runOCRBatch(ctx, newOCRDocument("", "/nonexistent.pdf"), []int{1, 2, 3}, opts)
+	if err != nil {
+		t.Fatalf("expected cached pages only, got error %v", err)
+	}
+	if cached != 3 || len(results) != 3 || results[2].Markdown != "cached page" {
+		t.Fatalf("unexpected results: cached=%d results=%v", cached, results)
+	}
+
+	// A page outside the cache forces a provider call, which fails here, but
+	// cached pages are still returned.
+	results, cached, err = p.runOCRBatch(ctx, newOCRDocument("", "/nonexistent.pdf"), []int{1, 4}, opts)
+	if err == nil {
+		t.Fatalf("expected provider error for uncached page")
+	}
+	if cached != 1 || results[1].Markdown != "cached page" {
+		t.Fatalf("cached page should survive provider failure: cached=%d results=%v", cached, results)
+	}
+}
+
+func TestRunOCRBatchSkipsPaidProvidersOverBudget(t *testing.T) {
+	store := budget.NewMemoryStore()
+	budget.SetGuard(budget.NewGuard(store, budget.Limits{DailyUSD: 1}))
+	defer budget.SetGuard(nil)
+	ctx := budget.WithTenant(context.Background(), "acme")
+	_ = store.Add(ctx, "acme", time.Now().UTC().Format("2006-01-02"), 5)
+
+	p := New(config.Config{})
+	cache := NewMemoryPageCache(10, time.Hour)
+	p.SetPageCache(cache)
+	model := "mistral-ocr-latest"
+	opts := p.ApplyDefaults(types.HybridProcessorOptions{OCRModel: &model, DocumentHash: "abc"})
+	cache.Set(ctx, PageCacheKey("abc", 1, "mistral", model, false, false), "cached page")
+
+	// Mistral is the only provider in the chain; over budget it is never called.
+	results, cached, err := p.runOCRBatch(ctx, newOCRDocument("", "/nonexistent.pdf"), []int{1, 2}, opts)
+	if !errors.Is(err, budget.ErrExceeded) {
+		t.Fatalf("expected budget error, got %v", err)
+	}
+	if cached != 1 || results[1].Markdown != "cached page" {
+		t.Fatalf("cached pages are free and should still be served: cached=%d results=%v", cached, results)
+	}
+}
+
+func TestMergeOCRResultsRecordsProvider(t *testing.T) {
+	result := types.HybridExtractionResult{Pages: []types.PageExtractionResult{
+		{PageNumber: 1, Method: "text-layer", Text: "native"},
+		{PageNumber: 2, Method: "needs-ocr"},
+	}}
+	mergeOCRResults(&result, map[int]ocrPage{
+		1: {Markdown: "ocr one", Provider: "mistral"},
+		2: {Markdown: "ocr two", Provider: "tesseract"},
+	}, false)
+
+	if result.Pages[0].Method != "text-layer" || result.Pages[0].Text != "native" {
+		t.Fatalf("text-layer page should be kept without full OCR: %+v", result.Pages[0])
+	}
+	if result.Pages[1].Method != "ocr:tesseract" || result.Pages[1].Text != "ocr two" {
+		t.Fatalf("unexpected OCR page: %+v", result.Pages[1])
+	}
+	if countOCRPages(result.Pages) != 1 {
+		t.Fatalf("expected 1 OCR page, got %d", countOCRPages(result.Pages))
+	}
+}
+
+func TestSavingsCountOnlySelectedPages(t *testing.T) {
+	requested := make([]int, 60)
+	for i := range requested {
+		requested[i] = i + 1
+	}
+	pages, err := selectPages(requested, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10 pages had a text layer and 40 of the 50 OCR pages were cached.
+	if got := calculateSavings(10+40, len(pages)); got != 83 {
+		t.Fatalf("expected 83%% savings over 60 selected pages, got %d", got)
+	}
+}
+
+// resolvingProvider records the URLs it is asked to OCR and how often the
+// local file was resolved to one.
+type resolvingProvider struct {
+	resolves, cleanups int
+	urls               []string
+}
+
+func (r *resolvingProvider) Name() string { return "resolving" }
+
+func (r *resolvingProvider) ResolveDocument(context.Context, string) (string, func(), error) {
+	r.resolves++
+	return "https://files.example/doc.pdf", func() { r.cleanups++ }, nil
+}
+
+func (r *resolvingProvider) OCRDocument(_ context.Context, req ocr.DocumentRequest) (ocr.OCRResponse, error) {
+	r.urls = append(r.urls, req.URL)
+	var resp ocr.OCRResponse
+	for _, p0 := range req.Pages0 {
+		resp.Pages = append(resp.Pages, ocr.OCRPage{Index: p0, Markdown: "page"})
+	}
+	return resp, nil
+}
+
+func (r *resolvingProvider) OCRImage(context.Context, ocr.ImageRequest) (ocr.OCRResponse, error) {
+	return ocr.OCRResponse{}, errors.New("not supported")
+}
+
+func TestRunOCRBatchResolvesLocalDocumentOnce(t *testing.T) {
+	ctx := context.Background()
+	prov := &resolvingProvider{}
+	p := New(config.Config{})
+	p.ocrProviders, _ = ocr.NewProviders(prov.Name(), prov)
+	model := "mistral-ocr-latest"
+	opts := p.ApplyDefaults(types.HybridProcessorOptions{OCRModel: &model})
+
+	doc := newOCRDocument("", "/local.pdf")
+	for _, window := range [][]int{{1, 2}, {3, 4}} {
+		if _, _, err := p.runOCRBatch(ctx, doc, window, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	doc.close()
+
+	if prov.resolves != 1 || prov.cleanups != 1 {
+		t.Fatalf("expected one resolve and one cleanup, got %d and %d", prov.resolves, prov.cleanups)
+	}
+	if len(prov.urls) != 2 || prov.urls[0] != prov.urls[1] || prov.urls[0] == "" {
+		t.Fatalf("every batch should reuse the resolved URL: %q", prov.urls)
+	}
+}
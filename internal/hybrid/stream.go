@@ -0,0 +1,147 @@
+package hybrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/toricodesthings/file-processing-service/internal/budget"
+	"github.com/toricodesthings/file-processing-service/internal/extractor"
+	"github.com/toricodesthings/file-processing-service/internal/format"
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+const defaultStreamWindow = 32
+
+// StreamHybrid is ProcessHybrid for documents too large to buffer. Pages are
+// handled in windows of PAGE_STREAM_WINDOW pages: each window's text layer is
+// read in parallel, its OCR ratio decides between OCRing the pages that need
+// it and OCRing the whole window, and every page is passed to emit once its
+// text is final. Text-layer pages are emitted before the window's OCR call,
+// so page numbers are not strictly increasing. Only one window is held in
+// memory at a time.
+//
+// An OCR failure is recorded in the summary and the affected pages are
+// emitted as "needs-ocr"; the stream carries on with the next window.
+func (p *Processor) StreamHybrid(
+	ctx context.Context,
+	presignedURL, pdfPath string,
+	opts types.HybridProcessorOptions,
+	emit func(types.PageExtractionResult),
+) (types.HybridStreamSummary, error) {
+	var sum types.HybridStreamSummary
+
+	totalPages, err := extractor.PageCount(ctx, pdfPath, p.extractCfg)
+	if err != nil {
+		return sum, fmt.Errorf("page count failed: %w", err)
+	}
+	if totalPages == 0 {
+		return sum, errors.New("PDF has no pages")
+	}
+	sum.TotalPages = totalPages
+
+	pages, err := selectPages(opts.Pages, totalPages)
+	if err != nil {
+		return sum, err
+	}
+	opts.Progress.Report(types.ProgressEvent{Stage: types.ProgressPageCount, TotalPages: totalPages, Pages: len(pages)})
+
+	window := p.cfg.PageStreamWindow
+	if window <= 0 {
+		window = defaultStreamWindow
+	}
+
+	// Every window OCRs the same file; a local copy is uploaded at most once.
+	doc := newOCRDocument(presignedURL, pdfPath)
+	defer doc.close()
+
+	var ocrErrs []string
+	send := func(pr types.PageExtractionResult) {
+		pr.Text = format.Page(pr.Text)
+		switch {
+		case pr.Method == "needs-ocr":
+			sum.NeedsOCRPages++
+		case strings.HasPrefix(pr.Method, "ocr:"):
+			sum.OCRPages++
+		default:
+			sum.TextLayerPages++
+		}
+		if pr.Text != "" {
+			sum.WordCount += pr.WordCount
+		}
+		sum.CharCount += utf8.RuneCountInString(pr.Text)
+		emit(pr)
+	}
+
+	for start := 0; start < len(pages); start += window {
+		if err := ctx.Err(); err != nil {
+			return sum, err
+		}
+		batch := pages[start:min(start+window, len(pages))]
+		results := p.extractPagesParallel(ctx, pdfPath, batch, opts.MinWordsThreshold, opts.Progress)
+
+		var needsOCR []int
+		for _, pr := range results {
+			if pr.Method != "text-layer" {
+				needsOCR = append(needsOCR, pr.PageNumber)
+			}
+		}
+		fullOCR := len(needsOCR) > 0 && float64(len(needsOCR))/float64(len(batch)) >= opts.OCRTriggerRatio
+
+		// Text-layer pages are final unless the whole window goes to OCR.
+		var pending []types.PageExtractionResult
+		for _, pr := range results {
+			if fullOCR || pr.Method != "text-layer" {
+				pending = append(pending, pr)
+				continue
+			}
+			send(pr)
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		ocrPages := needsOCR
+		if fullOCR {
+			ocrPages = batch
+		}
+		opts.Progress.Report(types.ProgressEvent{Stage: types.ProgressOCRStarted, Pages: len(ocrPages), Provider: opts.OCRProvider})
+		ocrResults, cached, err := p.runOCRBatch(ctx, doc, ocrPages, opts)
+		done := types.ProgressEvent{Stage: types.ProgressOCRFinished, Pages: len(ocrResults), Cached: cached}
+		switch {
+		case errors.Is(err, budget.ErrExceeded):
+			sum.BudgetExceeded = true
+			done.Error = err.Error()
+		case err != nil:
+			if ctx.Err() != nil {
+				return sum, ctx.Err()
+			}
+			ocrErrs = append(ocrErrs, err.Error())
+			done.Error = err.Error()
+		}
+		opts.Progress.Report(done)
+		sum.OCRCachedPages += cached
+
+		merged := types.HybridExtractionResult{Pages: pending}
+		mergeOCRResults(&merged, ocrResults, fullOCR)
+		for _, pr := range merged.Pages {
+			send(pr)
+		}
+	}
+
+	switch {
+	case sum.BudgetExceeded:
+		msg := "OCR skipped: tenant budget exceeded"
+		sum.Error = &msg
+	case len(ocrErrs) > 0:
+		msg := fmt.Sprintf("OCR failed: %s", ocrErrs[0])
+		if len(ocrErrs) > 1 {
+			msg += fmt.Sprintf(" (and %d more batches)", len(ocrErrs)-1)
+		}
+		sum.Error = &msg
+	}
+	sum.CostSavingsPercent = calculateSavings(sum.TextLayerPages+sum.OCRCachedPages, len(pages))
+	return sum, nil
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/toricodesthings/file-processing-service/internal/budget"
 	"github.com/toricodesthings/file-processing-service/internal/ocr"
 	"github.com/toricodesthings/file-processing-service/internal/types"
 	"github.com/toricodesthings/file-processing-service/internal/vision"
@@ -107,12 +108,15 @@ func combineOCRPages(ocrResp ocr.OCRResponse) string {
 // ProcessImage classifies an image via a cheap vision model (OpenRouter) and
 // routes to the appropriate extraction method:
 //
-//   - contentType "text"  → Mistral OCR (handwriting, documents, screenshots, …)
+//   - contentType "text"  → OCR (handwriting, documents, screenshots, …)
 //   - contentType "visual"→ vision description only (photos, artwork, …)
 //   - contentType "mixed" → OCR + vision description (diagrams, charts, …)
 //
 // If the vision classifier is unavailable, we fall back to OCR-only (current behaviour).
-func ProcessImage(ctx context.Context, imageURL, ocrModel, visionModel string, visionTimeout time.Duration) (types.ImageExtractionResult, error) {
+// OCR runs through the provider chain starting at ocrProvider (empty = server
+// default). localPath is the downloaded image, if any; local OCR providers
+// read it instead of the URL.
+func ProcessImage(ctx context.Context, providers *ocr.Providers, ocrProvider, imageURL, localPath, ocrModel, visionModel string, visionTimeout time.Duration) (types.ImageExtractionResult, error) {
 	// ── Validate ─────────────────────────────────────────────────────────────
 	if strings.TrimSpace(imageURL) == "" {
 		msg := "imageUrl required"
@@ -135,13 +139,27 @@ func ProcessImage(ctx context.Context, imageURL, ocrModel, visionModel string, v
 	if ocrModel == "" {
 		ocrModel = "mistral-ocr-latest"
 	}
+	chain, err := providers.Chain(ocrProvider)
+	if err != nil {
+		msg := err.Error()
+		return types.ImageExtractionResult{Error: &msg}, err
+	}
+	ocrReq := ocr.ImageRequest{URL: imageURL, LocalPath: localPath, Model: ocrModel}
+	runOCR := func(ctx context.Context) (string, string, error) {
+		return runOCRChain(ctx, providers, chain, ocrReq)
+	}
+
+	// Over budget: vision is paid, so only free OCR providers may run.
+	if !budget.Allowed(ctx) {
+		return processWithinBudget(ctx, providers, ocr.FreeProviders(chain), ocrReq), nil
+	}
 
 	// ── Step 1: Vision classification (cheap, ~$0.0001) ──────────────────────
 	visionResult, visionErr := vision.RunVisionClassification(ctx, imageURL, visionModel, visionTimeout)
 	if visionErr != nil {
 		// Vision unavailable — fall back to OCR-only (preserves current behaviour)
 		fmt.Printf("[image] vision classification failed, falling back to OCR-only: %v\n", visionErr)
-		return processOCROnly(ctx, imageURL, ocrModel)
+		return processOCROnly(ctx, runOCR)
 	}
 
 	// ── Step 2: Route based on content type ──────────────────────────────────
@@ -149,7 +167,7 @@ func ProcessImage(ctx context.Context, imageURL, ocrModel, visionModel string, v
 	case "text":
 		// Text-heavy content (handwriting, docs, screenshots, whiteboards)
 		// → OCR provides the primary text; vision description is supplementary
-		ocrResult, err := runOCR(ctx, imageURL, ocrModel)
+		ocrResult, usedProvider, err := runOCR(ctx)
 		if err != nil || !isOCRMeaningful(ocrResult) {
 			// OCR failed or produced garbage — fall back to vision description
 			if err != nil {
@@ -172,12 +190,13 @@ func ProcessImage(ctx context.Context, imageURL, ocrModel, visionModel string, v
 			Method:      "ocr",
 			ImageType:   visionResult.ImageType,
 			Description: visionResult.Description,
+			OCRProvider: usedProvider,
 		}, nil
 
 	case "mixed":
 		// Significant text AND visual content (diagrams, charts, infographics)
 		// → OCR for text extraction + vision description for visual context
-		ocrResult, err := runOCR(ctx, imageURL, ocrModel)
+		ocrResult, usedProvider, err := runOCR(ctx)
 		if err != nil || !isOCRMeaningful(ocrResult) {
 			if err != nil {
 				fmt.Printf("[image] OCR failed for mixed content, using vision description: %v\n", err)
@@ -199,6 +218,7 @@ func ProcessImage(ctx context.Context, imageURL, ocrModel, visionModel string, v
 			Method:      "ocr+vision",
 			ImageType:   visionResult.ImageType,
 			Description: visionResult.Description,
+			OCRProvider: usedProvider,
 		}, nil
 
 	default:
@@ -214,32 +234,63 @@ func ProcessImage(ctx context.Context, imageURL, ocrModel, visionModel string, v
 	}
 }
 
-// runOCR calls Mistral OCR and returns cleaned text.
-func runOCR(ctx context.Context, imageURL, model string) (string, error) {
-	ocrResp, err := ocr.RunMistralImageOCR(ctx, imageURL, model)
+// runOCRChain OCRs the image through the provider chain and returns cleaned
+// text plus the name of the provider that served it.
+func runOCRChain(ctx context.Context, providers *ocr.Providers, chain []ocr.Provider, req ocr.ImageRequest) (string, string, error) {
+	var ocrResp ocr.OCRResponse
+	used, err := providers.Do(ctx, chain, func(provider ocr.Provider) error {
+		resp, err := provider.OCRImage(ctx, req)
+		ocrResp = resp
+		return err
+	})
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	if len(ocrResp.Pages) == 0 {
-		return "", errors.New("OCR returned no pages")
+		return "", "", errors.New("OCR returned no pages")
 	}
 
 	raw := combineOCRPages(ocrResp)
 	cleaned := cleanOCRText(raw)
 	if cleaned == "" {
-		return "", errors.New("OCR produced empty text")
+		return "", "", errors.New("OCR produced empty text")
 	}
 
-	return cleaned, nil
+	return cleaned, used.Name(), nil
+}
+
+// processWithinBudget runs the free OCR providers only. It never fails: when
+// none is configured or none finds text, the result is empty and flagged so
+// the caller can retry once budget is available.
+func processWithinBudget(ctx context.Context, providers *ocr.Providers, free []ocr.Provider, req ocr.ImageRequest) types.ImageExtractionResult {
+	skipped := types.ImageExtractionResult{Success: true, Method: "none", BudgetExceeded: true, NeedsOCR: true}
+	if len(free) == 0 {
+		fmt.Printf("[image] tenant budget exceeded and no free OCR provider, skipping extraction\n")
+		return skipped
+	}
+	text, usedProvider, err := runOCRChain(ctx, providers, free, req)
+	if err != nil || !isOCRMeaningful(text) {
+		if err != nil {
+			fmt.Printf("[image] free OCR failed within budget: %v\n", err)
+		}
+		return skipped
+	}
+	return types.ImageExtractionResult{
+		Success:        true,
+		Text:           text,
+		Method:         "ocr",
+		OCRProvider:    usedProvider,
+		BudgetExceeded: true,
+	}
 }
 
 // processOCROnly is the fallback path when vision is unavailable.
 // Applies the same quality gate as the vision-routed branches:
 // if OCR produces garbage (emoji, stray symbols, etc.) we fail
 // explicitly rather than returning meaningless text.
-func processOCROnly(ctx context.Context, imageURL, model string) (types.ImageExtractionResult, error) {
-	ocrText, err := runOCR(ctx, imageURL, model)
+func processOCROnly(ctx context.Context, runOCR func(context.Context) (string, string, error)) (types.ImageExtractionResult, error) {
+	ocrText, usedProvider, err := runOCR(ctx)
 	if err != nil {
 		msg := sanitiseOCRError(err)
 		return types.ImageExtractionResult{Error: &msg}, err
@@ -256,9 +307,10 @@ func processOCROnly(ctx context.Context, imageURL, model string) (types.ImageExt
 	}
 
 	return types.ImageExtractionResult{
-		Success: true,
-		Text:    ocrText,
-		Method:  "ocr",
+		Success:     true,
+		Text:        ocrText,
+		Method:      "ocr",
+		OCRProvider: usedProvider,
 	}, nil
 }
 
@@ -0,0 +1,298 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+// ErrQueueFull is returned by Submit when the pending-job queue is at capacity.
+var ErrQueueFull = errors.New("job queue is full")
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Terminal reports whether the job has finished (successfully or not).
+func (s Status) Terminal() bool {
+	return s == StatusSucceeded || s == StatusFailed
+}
+
+// Progress reports how far a job has got. Paged documents report the pages
+// done out of those selected; other files only move between stages.
+type Progress struct {
+	Stage      string `json:"stage"`
+	Percent    int    `json:"percent"`
+	PagesDone  int    `json:"pagesDone,omitempty"`
+	TotalPages int    `json:"totalPages,omitempty"`
+}
+
+type WebhookDelivery struct {
+	Delivered  bool   `json:"delivered"`
+	Attempts   int    `json:"attempts"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	LastError  string `json:"lastError,omitempty"`
+}
+
+// Record is the externally visible state of an async extraction job.
+// The request itself (presigned URL, webhook URL) is deliberately not stored.
+type Record struct {
+	ID         string           `json:"id"`
+	Status     Status           `json:"status"`
+	Progress   Progress         `json:"progress"`
+	FileName   string           `json:"fileName,omitempty"`
+	CreatedAt  time.Time        `json:"createdAt"`
+	StartedAt  *time.Time       `json:"startedAt,omitempty"`
+	FinishedAt *time.Time       `json:"finishedAt,omitempty"`
+	Result     *extract.Result  `json:"result,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Webhook    *WebhookDelivery `json:"webhook,omitempty"`
+}
+
+// Semaphore is the part of semaphore.Weighted that job workers use.
+type Semaphore interface {
+	Acquire(ctx context.Context, n int64) error
+	Release(n int64)
+}
+
+// RunFunc performs the extraction for one job, reporting its progress.
+type RunFunc func(ctx context.Context, req extract.UniversalExtractRequest, progress types.ProgressFunc) (extract.Result, error)
+
+type Config struct {
+	Workers   int
+	QueueSize int
+	Timeout   time.Duration // per-job extraction budget
+	Retention time.Duration // how long finished jobs stay readable
+
+	WebhookSecret       string
+	WebhookTimeout      time.Duration
+	AllowPrivateWebhook bool
+}
+
+type task struct {
+	id         string
+	req        extract.UniversalExtractRequest
+	webhookURL string
+}
+
+// Manager owns the job queue and a bounded pool of workers. Each worker also
+// acquires the server-wide request semaphore so async jobs share capacity
+// with synchronous /extract calls instead of oversubscribing the container.
+type Manager struct {
+	store   Store
+	sem     Semaphore
+	run     RunFunc
+	cfg     Config
+	queue   chan task
+	webhook *webhookSender
+}
+
+func NewManager(store Store, sem Semaphore, run RunFunc, cfg Config) *Manager {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 2
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Minute
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 24 * time.Hour
+	}
+	return &Manager{
+		store:   store,
+		sem:     sem,
+		run:     run,
+		cfg:     cfg,
+		queue:   make(chan task, cfg.QueueSize),
+		webhook: newWebhookSender(cfg.WebhookSecret, cfg.WebhookTimeout, cfg.AllowPrivateWebhook),
+	}
+}
+
+// WebhooksEnabled reports whether a signing secret is configured.
+func (m *Manager) WebhooksEnabled() bool {
+	return m.webhook.enabled()
+}
+
+// Start marks jobs left unfinished by a previous process as failed and
+// launches the worker pool. Workers exit when ctx is cancelled.
+func (m *Manager) Start(ctx context.Context) {
+	m.failInterrupted(ctx)
+	for i := 0; i < m.cfg.Workers; i++ {
+		go m.worker(ctx)
+	}
+}
+
+// Submit records a new queued job and enqueues it without blocking.
+func (m *Manager) Submit(ctx context.Context, req extract.UniversalExtractRequest, webhookURL string) (Record, error) {
+	if webhookURL != "" && !m.webhook.enabled() {
+		return Record{}, fmt.Errorf("webhooks are not configured on this server")
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return Record{}, err
+	}
+	rec := Record{
+		ID:        id,
+		Status:    StatusQueued,
+		Progress:  Progress{Stage: StageQueued},
+		FileName:  req.FileName,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := m.store.Save(ctx, rec); err != nil {
+		return Record{}, err
+	}
+
+	select {
+	case m.queue <- task{id: id, req: req, webhookURL: webhookURL}:
+		return rec, nil
+	default:
+		_ = m.store.Delete(ctx, id)
+		return Record{}, ErrQueueFull
+	}
+}
+
+func (m *Manager) Get(ctx context.Context, id string) (Record, error) {
+	return m.store.Get(ctx, id)
+}
+
+// QueueDepth returns the number of jobs waiting for a worker.
+func (m *Manager) QueueDepth() int {
+	return len(m.queue)
+}
+
+// Prune deletes finished jobs older than the retention window.
+func (m *Manager) Prune(ctx context.Context) int {
+	recs, err := m.store.List(ctx)
+	if err != nil {
+		return 0
+	}
+	cutoff := time.Now().Add(-m.cfg.Retention)
+	removed := 0
+	for _, rec := range recs {
+		if !rec.Status.Terminal() || rec.FinishedAt == nil || rec.FinishedAt.After(cutoff) {
+			continue
+		}
+		if m.store.Delete(ctx, rec.ID) == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+func (m *Manager) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-m.queue:
+			m.process(ctx, t)
+		}
+	}
+}
+
+func (m *Manager) process(ctx context.Context, t task) {
+	rec, err := m.store.Get(ctx, t.id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[jobs] load %s failed: %v\n", t.id, err)
+		return
+	}
+
+	if m.sem != nil {
+		if err := m.sem.Acquire(ctx, 1); err != nil {
+			m.finish(ctx, &rec, extract.Result{}, err, t.webhookURL)
+			return
+		}
+	}
+
+	started := time.Now().UTC()
+	rec.Status = StatusRunning
+	rec.StartedAt = &started
+	rec.Progress = Progress{Stage: StageExtracting}
+	m.save(ctx, rec)
+
+	tracker := &progressTracker{rec: rec, save: func(r Record) { m.save(ctx, r) }}
+	jobCtx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	res, runErr := m.run(jobCtx, t.req, tracker.report)
+	cancel()
+	if m.sem != nil {
+		// Webhook delivery can retry for most of a minute; it must not hold
+		// an extraction slot that /extract also needs.
+		m.sem.Release(1)
+	}
+	rec = tracker.close()
+
+	m.finish(ctx, &rec, res, runErr, t.webhookURL)
+}
+
+func (m *Manager) finish(ctx context.Context, rec *Record, res extract.Result, runErr error, webhookURL string) {
+	finished := time.Now().UTC()
+	rec.FinishedAt = &finished
+	rec.Result = &res
+	if runErr != nil {
+		rec.Status = StatusFailed
+		rec.Progress = Progress{Stage: StageFailed, Percent: 100}
+		rec.Error = runErr.Error()
+		if res.Error != nil {
+			rec.Error = *res.Error
+		}
+	} else {
+		rec.Status = StatusSucceeded
+		rec.Progress = Progress{Stage: StageCompleted, Percent: 100}
+	}
+	m.save(ctx, *rec)
+
+	if webhookURL != "" {
+		rec.Webhook = m.webhook.deliver(ctx, webhookURL, *rec)
+		m.save(ctx, *rec)
+	}
+}
+
+func (m *Manager) save(ctx context.Context, rec Record) {
+	// Use a detached context so a cancelled worker still records its outcome.
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := m.store.Save(saveCtx, rec); err != nil {
+		fmt.Fprintf(os.Stderr, "[jobs] save %s failed: %v\n", rec.ID, err)
+	}
+}
+
+func (m *Manager) failInterrupted(ctx context.Context) {
+	recs, err := m.store.List(ctx)
+	if err != nil {
+		return
+	}
+	for _, rec := range recs {
+		if rec.Status.Terminal() {
+			continue
+		}
+		finished := time.Now().UTC()
+		rec.Status = StatusFailed
+		rec.FinishedAt = &finished
+		rec.Progress = Progress{Stage: StageFailed, Percent: 100}
+		rec.Error = "job interrupted by server restart"
+		m.save(ctx, rec)
+	}
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("job id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
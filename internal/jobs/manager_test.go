@@ -0,0 +1,238 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/extract"
+	"github.com/toricodesthings/file-processing-service/internal/types"
+	"golang.org/x/sync/semaphore"
+)
+
+func waitForStatus(t *testing.T, m *Manager, id string, want Status) Record {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		rec, err := m.Get(context.Background(), id)
+		if err == nil && rec.Status == want {
+			return rec
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	rec, _ := m.Get(context.Background(), id)
+	t.Fatalf("job %s did not reach %q (last: %+v)", id, want, rec)
+	return Record{}
+}
+
+func TestManagerRunsJobAndSignsWebhook(t *testing.T) {
+	secret := "test-secret"
+	got := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got <- r
+		bodies <- b
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	run := func(ctx context.Context, req extract.UniversalExtractRequest, progress types.ProgressFunc) (extract.Result, error) {
+		return extract.Result{Success: true, Text: "hello " + req.FileName, FileType: "text"}, nil
+	}
+	m := NewManager(NewMemoryStore(), semaphore.NewWeighted(1), run, Config{
+		Workers:             1,
+		WebhookSecret:       secret,
+		AllowPrivateWebhook: true,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+
+	rec, err := m.Submit(ctx, extract.UniversalExtractRequest{FileName: "a.txt"}, srv.URL)
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if rec.Status != StatusQueued || len(rec.ID) != 32 {
+		t.Fatalf("unexpected submitted record: %+v", rec)
+	}
+
+	select {
+	case r := <-got:
+		body := <-bodies
+		ts := r.Header.Get("X-Fileproc-Timestamp")
+		if sig := r.Header.Get("X-Fileproc-Signature"); sig != Sign(secret, ts, body) {
+			t.Fatalf("signature mismatch: %q", sig)
+		}
+		if r.Header.Get("X-Fileproc-Job-Id") != rec.ID {
+			t.Fatalf("unexpected job id header %q", r.Header.Get("X-Fileproc-Job-Id"))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("webhook was not delivered")
+	}
+
+	final := waitForStatus(t, m, rec.ID, StatusSucceeded)
+	if final.Result == nil || final.Result.Text != "hello a.txt" {
+		t.Fatalf("unexpected result: %+v", final.Result)
+	}
+	if final.Progress.Percent != 100 || final.FinishedAt == nil {
+		t.Fatalf("expected completed progress, got %+v", final)
+	}
+}
+
+func TestManagerReleasesSlotBeforeWebhook(t *testing.T) {
+	sem := semaphore.NewWeighted(1)
+	acquired := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// An /extract call needs the same slot while the webhook is pending.
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		err := sem.Acquire(ctx, 1)
+		if err == nil {
+			sem.Release(1)
+		}
+		acquired <- err
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	run := func(ctx context.Context, req extract.UniversalExtractRequest, progress types.ProgressFunc) (extract.Result, error) {
+		return extract.Result{Success: true}, nil
+	}
+	m := NewManager(NewMemoryStore(), sem, run, Config{Workers: 1, WebhookSecret: "s", AllowPrivateWebhook: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+
+	if _, err := m.Submit(ctx, extract.UniversalExtractRequest{}, srv.URL); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("extraction slot still held during webhook delivery: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("webhook was not delivered")
+	}
+}
+
+func TestManagerRecordsFailure(t *testing.T) {
+	run := func(ctx context.Context, req extract.UniversalExtractRequest, progress types.ProgressFunc) (extract.Result, error) {
+		return extract.Result{}, errors.New("download failed")
+	}
+	m := NewManager(NewMemoryStore(), nil, run, Config{Workers: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+
+	rec, err := m.Submit(ctx, extract.UniversalExtractRequest{}, "")
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	final := waitForStatus(t, m, rec.ID, StatusFailed)
+	if final.Error != "download failed" {
+		t.Fatalf("unexpected error %q", final.Error)
+	}
+}
+
+func TestManagerReportsProgressWhileRunning(t *testing.T) {
+	release := make(chan struct{})
+	run := func(ctx context.Context, req extract.UniversalExtractRequest, progress types.ProgressFunc) (extract.Result, error) {
+		progress(types.ProgressEvent{Stage: types.ProgressPageCount, TotalPages: 10, Pages: 4})
+		progress(types.ProgressEvent{Stage: types.ProgressPage, Page: 1, Method: "text-layer"})
+		progress(types.ProgressEvent{Stage: types.ProgressPage, Page: 2, Method: "needs-ocr"})
+		progress(types.ProgressEvent{Stage: types.ProgressOCRStarted, Pages: 1})
+		<-release
+		return extract.Result{Success: true}, nil
+	}
+	m := NewManager(NewMemoryStore(), nil, run, Config{Workers: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+
+	rec, err := m.Submit(ctx, extract.UniversalExtractRequest{}, "")
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	want := Progress{Stage: StageOCR, Percent: 25, PagesDone: 1, TotalPages: 4}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, _ := m.Get(ctx, rec.ID)
+		if got.Status == StatusRunning && got.Progress == want {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("progress = %+v (%s), want %+v", got.Progress, got.Status, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(release)
+	final := waitForStatus(t, m, rec.ID, StatusSucceeded)
+	if final.Progress.Stage != StageCompleted || final.Progress.Percent != 100 {
+		t.Fatalf("final progress = %+v", final.Progress)
+	}
+}
+
+func TestManagerRejectsWhenQueueFull(t *testing.T) {
+	run := func(ctx context.Context, req extract.UniversalExtractRequest, progress types.ProgressFunc) (extract.Result, error) {
+		return extract.Result{}, nil
+	}
+	// Workers are never started, so the single queue slot stays occupied.
+	m := NewManager(NewMemoryStore(), nil, run, Config{Workers: 1, QueueSize: 1})
+
+	if _, err := m.Submit(context.Background(), extract.UniversalExtractRequest{}, ""); err != nil {
+		t.Fatalf("first submit: %v", err)
+	}
+	if _, err := m.Submit(context.Background(), extract.UniversalExtractRequest{}, ""); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+}
+
+func TestManagerRejectsWebhookWithoutSecret(t *testing.T) {
+	m := NewManager(NewMemoryStore(), nil, nil, Config{})
+	if _, err := m.Submit(context.Background(), extract.UniversalExtractRequest{}, "https://hooks.example.com"); err == nil {
+		t.Fatalf("expected webhook submission to fail without a secret")
+	}
+}
+
+func TestManagerFailsInterruptedJobsOnStart(t *testing.T) {
+	store := NewMemoryStore()
+	_ = store.Save(context.Background(), testRecord("0123456789abcdef0123456789abcdef", time.Now()))
+
+	m := NewManager(store, nil, nil, Config{Workers: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+
+	rec, err := store.Get(ctx, "0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if rec.Status != StatusFailed || rec.FinishedAt == nil {
+		t.Fatalf("expected interrupted job to be failed, got %+v", rec)
+	}
+}
+
+func TestPruneRemovesExpiredJobs(t *testing.T) {
+	store := NewMemoryStore()
+	old := time.Now().Add(-2 * time.Hour)
+	expired := testRecord("0123456789abcdef0123456789abcdef", old)
+	expired.Status = StatusSucceeded
+	expired.FinishedAt = &old
+	_ = store.Save(context.Background(), expired)
+	_ = store.Save(context.Background(), testRecord("fedcba9876543210fedcba9876543210", old))
+
+	m := NewManager(store, nil, nil, Config{Retention: time.Hour})
+	if n := m.Prune(context.Background()); n != 1 {
+		t.Fatalf("expected 1 pruned job, got %d", n)
+	}
+	if _, err := store.Get(context.Background(), expired.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected expired job to be removed")
+	}
+}
@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"sync"
+
+	"github.com/toricodesthings/file-processing-service/internal/types"
+)
+
+// Progress stages of a job. Extraction events move a running job between
+// the extracting, ocr and transcribing stages.
+const (
+	StageQueued       = "queued"
+	StageExtracting   = "extracting"
+	StageOCR          = "ocr"
+	StageTranscribing = "transcribing"
+	StageCompleted    = "completed"
+	StageFailed       = "failed"
+)
+
+// progressTracker folds the extraction events of a running job into its
+// Progress and saves the record whenever the stage or percentage changes.
+// Events may arrive from several goroutines.
+type progressTracker struct {
+	mu     sync.Mutex
+	rec    Record
+	save   func(Record)
+	closed bool
+}
+
+func (p *progressTracker) report(ev types.ProgressEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+
+	prog := p.rec.Progress
+	switch ev.Stage {
+	case types.ProgressPageCount:
+		prog.TotalPages = ev.Pages
+	case types.ProgressPage:
+		// Pages that need OCR are done when their batch finishes.
+		if ev.Method == "text-layer" {
+			prog.PagesDone++
+		}
+	case types.ProgressOCRStarted:
+		prog.Stage = StageOCR
+	case types.ProgressOCRFinished:
+		prog.Stage = StageExtracting
+		prog.PagesDone += ev.Pages
+	case types.ProgressTranscriptionStarted:
+		prog.Stage = StageTranscribing
+	}
+	if prog.TotalPages > 0 {
+		prog.PagesDone = min(prog.PagesDone, prog.TotalPages)
+		// 100 is reserved for the finished job.
+		prog.Percent = min(prog.PagesDone*100/prog.TotalPages, 99)
+	}
+
+	changed := prog.Stage != p.rec.Progress.Stage || prog.Percent != p.rec.Progress.Percent
+	p.rec.Progress = prog
+	if changed {
+		p.save(p.rec)
+	}
+}
+
+// close stops recording events and returns the record with the last
+// progress reported, so a straggling event cannot overwrite the outcome.
+func (p *progressTracker) close() Record {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return p.rec
+}
@@ -0,0 +1,185 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned by Store.Get when no job has the given ID.
+var ErrNotFound = errors.New("job not found")
+
+// Store persists job records. Implementations must be safe for concurrent use.
+type Store interface {
+	Save(ctx context.Context, rec Record) error
+	Get(ctx context.Context, id string) (Record, error)
+	List(ctx context.Context) ([]Record, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// ---------- In-memory ----------
+
+// MemoryStore keeps records in process memory. Jobs are lost on restart.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	recs map[string]Record
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{recs: make(map[string]Record)}
+}
+
+func (s *MemoryStore) Save(_ context.Context, rec Record) error {
+	s.mu.Lock()
+	s.recs[rec.ID] = rec
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, id string) (Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rec, ok := s.recs[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	return rec, nil
+}
+
+func (s *MemoryStore) List(_ context.Context) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Record, 0, len(s.recs))
+	for _, rec := range s.recs {
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.recs, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// ---------- File-backed ----------
+
+var validIDPattern = regexp.MustCompile(`^[a-f0-9]{32}$`)
+
+// FileStore writes one JSON document per job into a directory so records
+// survive restarts. Writes go through a temp file + rename to stay atomic.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, fmt.Errorf("job store dir required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("job store dir: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(id string) (string, error) {
+	if !validIDPattern.MatchString(id) {
+		return "", ErrNotFound
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+func (s *FileStore) Save(_ context.Context, rec Record) error {
+	p, err := s.path(rec.ID)
+	if err != nil {
+		return fmt.Errorf("invalid job id %q", rec.ID)
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal job: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := os.CreateTemp(s.dir, rec.ID+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("close: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("rename: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) Get(_ context.Context, id string) (Record, error) {
+	p, err := s.path(id)
+	if err != nil {
+		return Record{}, err
+	}
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return Record{}, ErrNotFound
+	}
+	if err != nil {
+		return Record{}, fmt.Errorf("read job: %w", err)
+	}
+	var rec Record
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return Record{}, fmt.Errorf("decode job: %w", err)
+	}
+	return rec, nil
+}
+
+func (s *FileStore) List(ctx context.Context) ([]Record, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("list jobs: %w", err)
+	}
+	out := make([]Record, 0, len(entries))
+	for _, ent := range entries {
+		name := ent.Name()
+		if ent.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		rec, err := s.Get(ctx, strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (s *FileStore) Delete(_ context.Context, id string) error {
+	p, err := s.path(id)
+	if err != nil {
+		return nil
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete job: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testRecord(id string, created time.Time) Record {
+	return Record{ID: id, Status: StatusQueued, Progress: Progress{Stage: "queued"}, CreatedAt: created}
+}
+
+func exerciseStore(t *testing.T, s Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	a := testRecord("0123456789abcdef0123456789abcdef", now.Add(-time.Minute))
+	b := testRecord("fedcba9876543210fedcba9876543210", now)
+	for _, rec := range []Record{b, a} {
+		if err := s.Save(ctx, rec); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	got, err := s.Get(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.ID != a.ID || got.Status != StatusQueued {
+		t.Fatalf("unexpected record: %+v", got)
+	}
+
+	a.Status = StatusSucceeded
+	if err := s.Save(ctx, a); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got, _ := s.Get(ctx, a.ID); got.Status != StatusSucceeded {
+		t.Fatalf("expected updated status, got %q", got.Status)
+	}
+
+	list, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != a.ID || list[1].ID != b.ID {
+		t.Fatalf("expected records ordered by creation, got %+v", list)
+	}
+
+	if err := s.Delete(ctx, a.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.Get(ctx, a.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	exerciseStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	exerciseStore(t, s)
+}
+
+func TestFileStoreRejectsPathLikeIDs(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	if _, err := s.Get(context.Background(), "../../etc/passwd"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for traversal id, got %v", err)
+	}
+	if err := s.Save(context.Background(), Record{ID: "../escape"}); err == nil {
+		t.Fatalf("expected invalid id to be rejected on save")
+	}
+}
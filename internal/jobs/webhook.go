@@ -0,0 +1,154 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	webhookMaxAttempts = 4
+	webhookBaseDelay   = 2 * time.Second
+)
+
+// Sign computes the X-Fileproc-Signature value for a webhook payload.
+// Receivers recompute HMAC-SHA256(secret, timestamp + "." + body) and compare
+// in constant time, rejecting timestamps outside their tolerance window.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type webhookSender struct {
+	secret string
+	client *http.Client
+	delay  time.Duration
+}
+
+func newWebhookSender(secret string, timeout time.Duration, allowPrivate bool) *webhookSender {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		// Check the resolved address at dial time so DNS rebinding cannot
+		// redirect a webhook to an internal service after URL validation.
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || isPrivateIP(ip) {
+				return fmt.Errorf("webhook target %s is not a public address", host)
+			}
+			return nil
+		}
+	}
+	return &webhookSender{
+		secret: secret,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext, Proxy: nil},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		delay: webhookBaseDelay,
+	}
+}
+
+func (s *webhookSender) enabled() bool {
+	return s.secret != ""
+}
+
+// deliver POSTs the final record to the webhook URL, retrying transient
+// failures with exponential backoff. 4xx responses other than 408/429 are
+// treated as permanent.
+func (s *webhookSender) deliver(ctx context.Context, url string, rec Record) *WebhookDelivery {
+	out := &WebhookDelivery{}
+	body, err := json.Marshal(rec)
+	if err != nil {
+		out.LastError = err.Error()
+		return out
+	}
+
+	delay := s.delay
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		out.Attempts = attempt
+		status, err := s.post(ctx, url, rec.ID, body)
+		out.StatusCode = status
+		if err == nil {
+			out.Delivered = true
+			out.LastError = ""
+			return out
+		}
+		out.LastError = err.Error()
+		if status >= 400 && status < 500 && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests {
+			return out
+		}
+		if attempt == webhookMaxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return out
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return out
+}
+
+func (s *webhookSender) post(ctx context.Context, url, jobID string, body []byte) (int, error) {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "fileproc/1.0")
+	req.Header.Set("X-Fileproc-Event", "job.completed")
+	req.Header.Set("X-Fileproc-Job-Id", jobID)
+	req.Header.Set("X-Fileproc-Timestamp", ts)
+	req.Header.Set("X-Fileproc-Signature", Sign(s.secret, ts, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func isPrivateIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalMulticast() || ip.IsLinkLocalUnicast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	if ip.IsPrivate() {
+		return true
+	}
+	if v4 := ip.To4(); v4 != nil && v4[0] == 100 && v4[1] >= 64 && v4[1] <= 127 {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/toricodesthings/file-processing-service/internal/usage"
+)
+
+var usageDesc = prometheus.NewDesc(
+	namespace+"_upstream_usage_total",
+	"Billable upstream usage since start by provider and unit.",
+	[]string{"provider", "unit"}, nil,
+)
+
+var usageCostDesc = prometheus.NewDesc(
+	namespace+"_upstream_estimated_cost_usd_total",
+	"Estimated upstream cost since start by provider.",
+	[]string{"provider"}, nil,
+)
+
+// usageCollector exports the process-wide totals kept by the usage package.
+type usageCollector struct{}
+
+func (usageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- usageDesc
+	ch <- usageCostDesc
+}
+
+func (usageCollector) Collect(ch chan<- prometheus.Metric) {
+	for provider, t := range usage.Totals() {
+		for unit, qty := range t.Units {
+			ch <- prometheus.MustNewConstMetric(usageDesc, prometheus.CounterValue, qty, provider, unit)
+		}
+		ch <- prometheus.MustNewConstMetric(usageCostDesc, prometheus.CounterValue, t.EstimatedCostUSD, provider)
+	}
+}
+
+// tempDirInterval bounds how often a scrape walks the temp dir.
+const tempDirInterval = 30 * time.Second
+
+// WatchTempDir exports the bytes used by entries of dir whose name starts
+// with prefix (downloads, conversion scratch space and file-backed caches).
+func WatchTempDir(dir, prefix string) {
+	var (
+		mu      sync.Mutex
+		last    time.Time
+		current float64
+	)
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "temp_dir_bytes",
+		Help:      "Disk used under the temp dir by this service, refreshed at most every 30s.",
+	}, func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Since(last) >= tempDirInterval {
+			current = float64(dirUsage(dir, prefix))
+			last = time.Now()
+		}
+		return current
+	}))
+}
+
+func dirUsage(dir, prefix string) int64 {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, ent := range entries {
+		if !strings.HasPrefix(ent.Name(), prefix) {
+			continue
+		}
+		// Files vanish while requests finish; skip whatever cannot be read.
+		_ = filepath.WalkDir(filepath.Join(dir, ent.Name()), func(_ string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+			return nil
+		})
+	}
+	return total
+}
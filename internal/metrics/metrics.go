@@ -0,0 +1,177 @@
+// Package metrics exposes Prometheus metrics for the service: HTTP requests,
+// extraction latency and file sizes, upstream OCR/vision/transcription calls,
+// cache hit rates, semaphore queue depth and temp-dir disk usage.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "fileproc"
+
+// Registry holds every metric of this package plus the Go runtime and
+// process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route pattern and status code.",
+	}, []string{"endpoint", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route pattern.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"endpoint"})
+
+	extractionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "extraction_duration_seconds",
+		Help:      "Successful extractions (cache hits excluded) by file type and method.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"file_type", "method"})
+
+	extractionFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "extraction_failures_total",
+		Help:      "Failed extractions by file type.",
+	}, []string{"file_type"})
+
+	fileSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "extraction_file_size_bytes",
+		Help:      "Size of extracted files by file type.",
+		Buckets:   prometheus.ExponentialBuckets(1<<10, 4, 10), // 1KiB .. 256GiB
+	}, []string{"file_type"})
+
+	upstreamCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_calls_total",
+		Help:      "OCR, vision and transcription calls by service, provider and outcome.",
+	}, []string{"service", "provider", "outcome"})
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_call_duration_seconds",
+		Help:      "OCR, vision and transcription call latency, including retries.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"service", "provider", "outcome"})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Cache lookups by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	semaphoreWaiting = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "semaphore_waiting",
+		Help:      "Callers blocked waiting for a semaphore.",
+	}, []string{"semaphore"})
+
+	semaphoreInUse = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "semaphore_in_use",
+		Help:      "Semaphore weight currently held.",
+	}, []string{"semaphore"})
+
+	semaphoreCapacity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "semaphore_capacity",
+		Help:      "Total semaphore weight.",
+	}, []string{"semaphore"})
+)
+
+// Cache names for ObserveCache.
+const (
+	CacheResult  = "result"
+	CacheOCRPage = "ocr_page"
+)
+
+// Service names for ObserveCall.
+const (
+	ServiceOCR        = "ocr"
+	ServiceVision     = "vision"
+	ServiceTranscribe = "transcribe"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		extractionDuration, extractionFailures, fileSize,
+		upstreamCalls, upstreamDuration,
+		cacheLookups,
+		semaphoreWaiting, semaphoreInUse, semaphoreCapacity,
+		usageCollector{},
+	)
+}
+
+// Handler serves Registry in the Prometheus text (or OpenMetrics) format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTP records a finished request. endpoint is the matched route
+// pattern; unmatched requests share one label so paths cannot explode it.
+func ObserveHTTP(endpoint string, status int, d time.Duration) {
+	if endpoint == "" {
+		endpoint = "unmatched"
+	}
+	httpRequests.WithLabelValues(endpoint, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(endpoint).Observe(d.Seconds())
+}
+
+// ObserveExtraction records an extraction the router ran (not a cache hit).
+func ObserveExtraction(fileType, method string, size int64, d time.Duration, err error) {
+	if fileType == "" {
+		fileType = "unknown"
+	}
+	fileSize.WithLabelValues(fileType).Observe(float64(size))
+	if err != nil {
+		extractionFailures.WithLabelValues(fileType).Inc()
+		return
+	}
+	extractionDuration.WithLabelValues(fileType, method).Observe(d.Seconds())
+}
+
+// ObserveCall records one OCR, vision or transcription call.
+func ObserveCall(service, provider string, d time.Duration, err error) {
+	outcome := Outcome(err)
+	upstreamCalls.WithLabelValues(service, provider, outcome).Inc()
+	upstreamDuration.WithLabelValues(service, provider, outcome).Observe(d.Seconds())
+}
+
+// Outcome classifies err as "success", "timeout", "canceled" or "error".
+func Outcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return "error"
+}
+
+// ObserveCache records a cache lookup.
+func ObserveCache(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSemaphoreReportsWaitingAndHeld(t *testing.T) {
+	s := NewSemaphore("test", 1)
+	if err := s.Acquire(context.Background(), 1); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if got := testutil.ToFloat64(semaphoreInUse.WithLabelValues("test")); got != 1 {
+		t.Fatalf("expected 1 held, got %v", got)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		_ = s.Acquire(context.Background(), 1)
+		close(acquired)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for testutil.ToFloat64(semaphoreWaiting.WithLabelValues("test")) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("waiter never counted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	s.Release(1)
+	<-acquired
+	if got := testutil.ToFloat64(semaphoreWaiting.WithLabelValues("test")); got != 0 {
+		t.Fatalf("expected no waiters, got %v", got)
+	}
+	if s.TryAcquire(1) {
+		t.Fatalf("semaphore should be full")
+	}
+	s.Release(1)
+	if got := testutil.ToFloat64(semaphoreInUse.WithLabelValues("test")); got != 0 {
+		t.Fatalf("expected 0 held, got %v", got)
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	cases := map[string]error{
+		"success":  nil,
+		"timeout":  fmt.Errorf("ocr: %w", context.DeadlineExceeded),
+		"canceled": context.Canceled,
+		"error":    errors.New("boom"),
+	}
+	for want, err := range cases {
+		if got := Outcome(err); got != want {
+			t.Fatalf("Outcome(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestDirUsageCountsPrefixedEntriesOnly(t *testing.T) {
+	dir := t.TempDir()
+	mustWrite(t, filepath.Join(dir, "fileproc-a", "nested", "f.bin"), 100)
+	mustWrite(t, filepath.Join(dir, "fileproc-b.tmp"), 20)
+	mustWrite(t, filepath.Join(dir, "other", "g.bin"), 1000)
+
+	if got := dirUsage(dir, "fileproc-"); got != 120 {
+		t.Fatalf("expected 120 bytes, got %d", got)
+	}
+}
+
+func TestHandlerExposesRecordedMetrics(t *testing.T) {
+	ObserveHTTP("/extract", 200, 30*time.Millisecond)
+	ObserveExtraction("document/pdf", "hybrid", 2048, time.Second, nil)
+	ObserveCall(ServiceOCR, "mistral", time.Second, nil)
+	ObserveCache(CacheResult, true)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`fileproc_http_requests_total{endpoint="/extract",status="200"}`,
+		`fileproc_extraction_duration_seconds_count{file_type="document/pdf",method="hybrid"}`,
+		`fileproc_upstream_calls_total{outcome="success",provider="mistral",service="ocr"}`,
+		`fileproc_cache_lookups_total{cache="result",result="hit"}`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("missing %s in exposition", want)
+		}
+	}
+}
+
+func mustWrite(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
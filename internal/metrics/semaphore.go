@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/semaphore"
+)
+
+// Semaphore is a semaphore.Weighted that reports its queue depth and the
+// weight held under the given name.
+type Semaphore struct {
+	sem     *semaphore.Weighted
+	waiting prometheus.Gauge
+	inUse   prometheus.Gauge
+}
+
+func NewSemaphore(name string, n int64) *Semaphore {
+	semaphoreCapacity.WithLabelValues(name).Set(float64(n))
+	return &Semaphore{
+		sem:     semaphore.NewWeighted(n),
+		waiting: semaphoreWaiting.WithLabelValues(name),
+		inUse:   semaphoreInUse.WithLabelValues(name),
+	}
+}
+
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	s.waiting.Inc()
+	err := s.sem.Acquire(ctx, n)
+	s.waiting.Dec()
+	if err == nil {
+		s.inUse.Add(float64(n))
+	}
+	return err
+}
+
+func (s *Semaphore) TryAcquire(n int64) bool {
+	if !s.sem.TryAcquire(n) {
+		return false
+	}
+	s.inUse.Add(float64(n))
+	return true
+}
+
+func (s *Semaphore) Release(n int64) {
+	s.inUse.Sub(float64(n))
+	s.sem.Release(n)
+}
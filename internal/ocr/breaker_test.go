@@ -0,0 +1,141 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stubProvider struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (s *stubProvider) Name() string { return s.name }
+
+func (s *stubProvider) OCRDocument(ctx context.Context, req DocumentRequest) (OCRResponse, error) {
+	s.calls++
+	if s.err != nil {
+		return OCRResponse{}, s.err
+	}
+	return OCRResponse{Model: s.name, Pages: []OCRPage{{Index: 0, Markdown: "from " + s.name}}}, nil
+}
+
+func (s *stubProvider) OCRImage(ctx context.Context, req ImageRequest) (OCRResponse, error) {
+	return s.OCRDocument(ctx, DocumentRequest{})
+}
+
+func TestBreakerOpensAndProbesAfterCooldown(t *testing.T) {
+	b := NewBreaker(2, time.Minute)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if !b.Allow() {
+			t.Fatalf("closed breaker should allow call %d", i)
+		}
+		b.Failure()
+	}
+	if b.Allow() || b.State() != BreakerOpen {
+		t.Fatalf("breaker should be open after 2 failures, state=%s", b.State())
+	}
+
+	now = now.Add(time.Minute)
+	if !b.Allow() {
+		t.Fatalf("half-open breaker should let one probe through")
+	}
+	if b.Allow() {
+		t.Fatalf("only one probe may be in flight")
+	}
+	b.Failure()
+	if b.Allow() {
+		t.Fatalf("failed probe should reopen the breaker")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.Allow() {
+		t.Fatalf("expected second probe")
+	}
+	b.Success()
+	if b.State() != BreakerClosed || !b.Allow() {
+		t.Fatalf("successful probe should close the breaker")
+	}
+}
+
+func TestProvidersDoFailsOverAndSkipsOpenCircuits(t *testing.T) {
+	ctx := context.Background()
+	primary := &stubProvider{name: ProviderMistral, err: &OCRError{StatusCode: 503, Message: "overloaded"}}
+	fallback := &stubProvider{name: ProviderTesseract}
+	providers, err := NewProviders(ProviderMistral, primary, fallback)
+	if err != nil {
+		t.Fatalf("new providers: %v", err)
+	}
+	if err := providers.SetFallbacks([]string{ProviderTesseract}); err != nil {
+		t.Fatalf("set fallbacks: %v", err)
+	}
+	providers.SetBreakers(2, time.Hour)
+
+	chain, err := providers.Chain("")
+	if err != nil || len(chain) != 2 {
+		t.Fatalf("expected 2-provider chain, got %v %v", chain, err)
+	}
+	call := func(p Provider) error {
+		_, err := p.OCRDocument(ctx, DocumentRequest{})
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		used, err := providers.Do(ctx, chain, call)
+		if err != nil || used.Name() != ProviderTesseract {
+			t.Fatalf("call %d: expected tesseract fallback, got %v %v", i, used, err)
+		}
+	}
+	if primary.calls != 2 {
+		t.Fatalf("open circuit should skip mistral after 2 failures, got %d calls", primary.calls)
+	}
+	if providers.BreakerStates()[ProviderMistral] != BreakerOpen {
+		t.Fatalf("expected mistral circuit open, got %v", providers.BreakerStates())
+	}
+}
+
+func TestProvidersDoIgnoresClientErrorsForBreaker(t *testing.T) {
+	ctx := context.Background()
+	bad := &stubProvider{name: ProviderMistral, err: &OCRError{StatusCode: 422, Message: "unsupported document"}}
+	providers, _ := NewProviders(ProviderMistral, bad)
+	providers.SetBreakers(1, time.Hour)
+	chain, _ := providers.Chain("")
+
+	for i := 0; i < 3; i++ {
+		_, err := providers.Do(ctx, chain, func(p Provider) error {
+			_, err := p.OCRDocument(ctx, DocumentRequest{})
+			return err
+		})
+		var ocrErr *OCRError
+		if !errors.As(err, &ocrErr) {
+			t.Fatalf("single-provider chain should return the provider error, got %v", err)
+		}
+	}
+	if bad.calls != 3 || providers.BreakerStates()[ProviderMistral] != BreakerClosed {
+		t.Fatalf("client errors must not open the circuit: calls=%d states=%v", bad.calls, providers.BreakerStates())
+	}
+}
+
+func TestProvidersDoReportsEveryFailure(t *testing.T) {
+	ctx := context.Background()
+	providers, _ := NewProviders(ProviderMistral,
+		&stubProvider{name: ProviderMistral, err: errors.New("timeout")},
+		&stubProvider{name: ProviderTesseract, err: errors.New("tesseract not installed")})
+	_ = providers.SetFallbacks([]string{ProviderTesseract})
+	chain, _ := providers.Chain("")
+
+	_, err := providers.Do(ctx, chain, func(p Provider) error {
+		_, err := p.OCRDocument(ctx, DocumentRequest{})
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "mistral: timeout") || !strings.Contains(err.Error(), "tesseract: tesseract not installed") {
+		t.Fatalf("expected combined error, got %v", err)
+	}
+}
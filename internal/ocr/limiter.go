@@ -4,12 +4,12 @@ import (
 	"context"
 	"sync"
 
-	"golang.org/x/sync/semaphore"
+	"github.com/toricodesthings/file-processing-service/internal/metrics"
 )
 
 var (
 	limiterMu  sync.RWMutex
-	ocrLimiter *semaphore.Weighted
+	ocrLimiter *metrics.Semaphore
 )
 
 func SetConcurrencyLimit(max int64) {
@@ -19,7 +19,7 @@ func SetConcurrencyLimit(max int64) {
 		ocrLimiter = nil
 		return
 	}
-	ocrLimiter = semaphore.NewWeighted(max)
+	ocrLimiter = metrics.NewSemaphore("ocr", max)
 }
 
 func withConcurrencyLimit(ctx context.Context, fn func() (OCRResponse, error)) (OCRResponse, error) {
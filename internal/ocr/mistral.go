@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"sort"
+	"strings"
 	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/upstream"
+	"github.com/toricodesthings/file-processing-service/internal/usage"
 )
 
 type OCRPage struct {
@@ -35,21 +38,66 @@ type mistralErrorResponse struct {
 	} `json:"error"`
 }
 
-const (
-	mistralAPIURL  = "https://api.mistral.ai/v1/ocr"
-	maxRetries     = 2
-	retryDelay     = 2 * time.Second
-	requestTimeout = 120 * time.Second
-)
+// mistralBaseURL is a var so tests can point the client at a local server.
+var mistralBaseURL = "https://api.mistral.ai/v1"
+
+// UpstreamName is the upstream client name for Mistral's OCR and files APIs.
+const UpstreamName = "mistral"
+
+const requestTimeout = 120 * time.Second
+
+// Mistral is the hosted OCR provider backed by the Mistral OCR API.
+type Mistral struct {
+	apiKey         string
+	inlineMaxBytes int64
+}
+
+// NewMistral returns a Mistral provider. Local PDFs up to inlineMaxBytes are
+// sent inline; larger ones are uploaded through the files API.
+func NewMistral(apiKey string, inlineMaxBytes int64) *Mistral {
+	return &Mistral{apiKey: strings.TrimSpace(apiKey), inlineMaxBytes: inlineMaxBytes}
+}
+
+func (m *Mistral) Name() string { return ProviderMistral }
+
+// OCRDocument OCRs a PDF. Without a URL the local file is sent inline or
+// uploaded (see LocalDocumentURL).
+func (m *Mistral) OCRDocument(ctx context.Context, req DocumentRequest) (OCRResponse, error) {
+	if m.apiKey == "" {
+		return OCRResponse{}, fmt.Errorf("MISTRAL_API_KEY not configured")
+	}
+	documentURL := req.URL
+	if documentURL == "" && req.LocalPath != "" {
+		localURL, cleanup, err := m.ResolveDocument(ctx, req.LocalPath)
+		if err != nil {
+			return OCRResponse{}, err
+		}
+		defer cleanup()
+		documentURL = localURL
+	}
+	return runMistralOCR(ctx, m.apiKey, documentURL, req.Model, req.Pages0, req.ExtractHeader, req.ExtractFooter)
+}
+
+// ResolveDocument sends a local PDF inline or uploads it, see
+// LocalDocumentURL.
+func (m *Mistral) ResolveDocument(ctx context.Context, localPath string) (string, func(), error) {
+	return LocalDocumentURL(ctx, m.apiKey, localPath, m.inlineMaxBytes)
+}
 
-func RunMistralOCR(ctx context.Context, presignedURL string, model string, pages0 []int, extractHeader, extractFooter bool) (OCRResponse, error) {
-	key := os.Getenv("MISTRAL_API_KEY")
-	if key == "" {
+// OCRImage OCRs a single image. The URL (or data URI) is sent to Mistral as-is.
+func (m *Mistral) OCRImage(ctx context.Context, req ImageRequest) (OCRResponse, error) {
+	if m.apiKey == "" {
 		return OCRResponse{}, fmt.Errorf("MISTRAL_API_KEY not configured")
 	}
+	return runMistralImageOCR(ctx, m.apiKey, req.URL, req.Model)
+}
+
+// runMistralOCR OCRs a PDF. documentURL may be a presigned URL, a Mistral
+// signed file URL, or a base64 data URI (see LocalDocumentURL).
+func runMistralOCR(ctx context.Context, key, documentURL string, model string, pages0 []int, extractHeader, extractFooter bool) (OCRResponse, error) {
 
-	if presignedURL == "" {
-		return OCRResponse{}, fmt.Errorf("presigned URL required")
+	if documentURL == "" {
+		return OCRResponse{}, fmt.Errorf("document URL required")
 	}
 	if model == "" {
 		model = "mistral-ocr-latest" // Also: mistral-ocr-2512
@@ -72,7 +120,7 @@ func RunMistralOCR(ctx context.Context, presignedURL string, model string, pages
 		"model": model,
 		"document": map[string]any{
 			"type":         "document_url",
-			"document_url": presignedURL,
+			"document_url": documentURL,
 		},
 	}
 
@@ -93,57 +141,27 @@ func RunMistralOCR(ctx context.Context, presignedURL string, model string, pages
 	}
 
 	return withConcurrencyLimit(ctx, func() (OCRResponse, error) {
-		// Retry logic
-		var lastErr error
-		for attempt := 0; attempt <= maxRetries; attempt++ {
-			if attempt > 0 {
-				select {
-				case <-ctx.Done():
-					return OCRResponse{}, ctx.Err()
-				case <-time.After(retryDelay * time.Duration(attempt)):
-				}
-			}
-
-			result, err := executeOCRRequest(ctx, key, bodyBytes)
-			if err == nil {
-				return result, nil
-			}
-
-			lastErr = err
-
-			// Don't retry client errors (4xx)
-			if isClientError(err) {
-				break
-			}
+		result, err := executeOCRRequest(ctx, key, bodyBytes)
+		if err != nil {
+			return OCRResponse{}, fmt.Errorf("OCR failed: %w", err)
 		}
-
-		return OCRResponse{}, fmt.Errorf("OCR failed after %d attempts: %w", maxRetries+1, lastErr)
+		return result, nil
 	})
 }
 
+// executeOCRRequest sends one OCR call through the shared Mistral upstream
+// client, which retries throttling and server errors.
 func executeOCRRequest(ctx context.Context, apiKey string, bodyBytes []byte) (OCRResponse, error) {
-	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(reqCtx, "POST", mistralAPIURL, bytes.NewReader(bodyBytes))
-	if err != nil {
-		return OCRResponse{}, fmt.Errorf("create request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "fileproc/1.0")
-
-	client := &http.Client{
-		Timeout: requestTimeout,
-		Transport: &http.Transport{
-			MaxIdleConns:        10,
-			IdleConnTimeout:     30 * time.Second,
-			TLSHandshakeTimeout: 10 * time.Second,
-		},
-	}
-
-	resp, err := client.Do(req)
+	resp, err := upstream.For(UpstreamName).WithTimeout(requestTimeout).Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", mistralBaseURL+"/ocr", bytes.NewReader(bodyBytes))
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "fileproc/1.0")
+		return req, nil
+	})
 	if err != nil {
 		return OCRResponse{}, fmt.Errorf("request: %w", err)
 	}
@@ -165,6 +183,14 @@ func executeOCRRequest(ctx context.Context, apiKey string, bodyBytes []byte) (OC
 		return OCRResponse{}, fmt.Errorf("OCR returned no pages")
 	}
 
+	// Mistral bills per processed page; fall back to the page count when the
+	// usage block is missing.
+	billed := result.UsageInfo.PagesProcessed
+	if billed <= 0 {
+		billed = len(result.Pages)
+	}
+	usage.Record(ctx, ProviderMistral, usage.UnitPages, float64(billed))
+
 	for i, page := range result.Pages {
 		if page.Index < 0 {
 			return OCRResponse{}, fmt.Errorf("invalid page index at %d: %d", i, page.Index)
@@ -206,13 +232,6 @@ func (e *OCRError) Error() string {
 	return fmt.Sprintf("mistral OCR %d (%s): %s", e.StatusCode, e.Type, e.Message)
 }
 
-func isClientError(err error) bool {
-	if ocrErr, ok := err.(*OCRError); ok {
-		return ocrErr.StatusCode >= 400 && ocrErr.StatusCode < 500
-	}
-	return false
-}
-
 func uniqueInts(xs []int) []int {
 	if len(xs) == 0 {
 		return xs
@@ -231,15 +250,10 @@ func uniqueInts(xs []int) []int {
 	return out
 }
 
-// RunMistralImageOCR calls the Mistral OCR API with an image URL using the
+// runMistralImageOCR calls the Mistral OCR API with an image URL using the
 // "image_url" document type (as opposed to "document_url" for PDFs). The image
 // is not downloaded — the URL is sent directly to Mistral.
-func RunMistralImageOCR(ctx context.Context, imageURL string, model string) (OCRResponse, error) {
-	key := os.Getenv("MISTRAL_API_KEY")
-	if key == "" {
-		return OCRResponse{}, fmt.Errorf("MISTRAL_API_KEY not configured")
-	}
-
+func runMistralImageOCR(ctx context.Context, key, imageURL string, model string) (OCRResponse, error) {
 	if imageURL == "" {
 		return OCRResponse{}, fmt.Errorf("image URL required")
 	}
@@ -261,29 +275,10 @@ func RunMistralImageOCR(ctx context.Context, imageURL string, model string) (OCR
 	}
 
 	return withConcurrencyLimit(ctx, func() (OCRResponse, error) {
-		// Retry logic (same as PDF variant)
-		var lastErr error
-		for attempt := 0; attempt <= maxRetries; attempt++ {
-			if attempt > 0 {
-				select {
-				case <-ctx.Done():
-					return OCRResponse{}, ctx.Err()
-				case <-time.After(retryDelay * time.Duration(attempt)):
-				}
-			}
-
-			result, err := executeOCRRequest(ctx, key, bodyBytes)
-			if err == nil {
-				return result, nil
-			}
-
-			lastErr = err
-
-			if isClientError(err) {
-				break
-			}
+		result, err := executeOCRRequest(ctx, key, bodyBytes)
+		if err != nil {
+			return OCRResponse{}, fmt.Errorf("image OCR failed: %w", err)
 		}
-
-		return OCRResponse{}, fmt.Errorf("image OCR failed after %d attempts: %w", maxRetries+1, lastErr)
+		return result, nil
 	})
 }
@@ -0,0 +1,256 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/metrics"
+)
+
+const (
+	ProviderMistral   = "mistral"
+	ProviderTesseract = "tesseract"
+)
+
+// ProviderNames lists the OCR backends this build knows about.
+var ProviderNames = []string{ProviderMistral, ProviderTesseract}
+
+// IsProviderName reports whether name is a known OCR backend.
+func IsProviderName(name string) bool {
+	for _, n := range ProviderNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// paidProviders bill per page; the rest run locally at no upstream cost.
+var paidProviders = map[string]bool{ProviderMistral: true}
+
+// FreeProviders returns the providers in chain that cost nothing to call,
+// keeping their order.
+func FreeProviders(chain []Provider) []Provider {
+	out := make([]Provider, 0, len(chain))
+	for _, prov := range chain {
+		if !paidProviders[prov.Name()] {
+			out = append(out, prov)
+		}
+	}
+	return out
+}
+
+// DocumentRequest describes a PDF to OCR. URL is used when the provider can
+// fetch the document itself; LocalPath is the downloaded copy. Pages0 are
+// 0-based page indexes.
+type DocumentRequest struct {
+	URL           string
+	LocalPath     string
+	Pages0        []int
+	Model         string
+	ExtractHeader bool
+	ExtractFooter bool
+}
+
+// DocumentResolver is implemented by providers that turn a local PDF into a
+// URL before OCRing it. Callers sending the same file in several requests
+// resolve it once, pass the URL in DocumentRequest.URL and call cleanup when
+// they are done.
+type DocumentResolver interface {
+	ResolveDocument(ctx context.Context, localPath string) (url string, cleanup func(), err error)
+}
+
+// ImageRequest describes a single image to OCR. URL may be an HTTP(S) URL or
+// a data URI; LocalPath is the downloaded copy when one exists.
+type ImageRequest struct {
+	URL       string
+	LocalPath string
+	Model     string
+}
+
+// Provider is an OCR backend. Implementations must be safe for concurrent use
+// and return page markdown keyed by 0-based page index.
+type Provider interface {
+	Name() string
+	OCRDocument(ctx context.Context, req DocumentRequest) (OCRResponse, error)
+	OCRImage(ctx context.Context, req ImageRequest) (OCRResponse, error)
+}
+
+// Providers resolves a provider by name, falling back to the configured
+// default, and runs calls through an ordered fallback chain with a circuit
+// breaker per provider.
+type Providers struct {
+	def       string
+	byName    map[string]Provider
+	fallbacks []string
+	breakers  map[string]*Breaker
+}
+
+// NewProviders registers ps and uses defaultName when a request does not ask
+// for a specific provider. An empty defaultName selects the first provider.
+func NewProviders(defaultName string, ps ...Provider) (*Providers, error) {
+	if len(ps) == 0 {
+		return nil, fmt.Errorf("at least one OCR provider required")
+	}
+	out := &Providers{byName: make(map[string]Provider, len(ps)), breakers: make(map[string]*Breaker, len(ps))}
+	for _, p := range ps {
+		out.byName[p.Name()] = p
+		out.breakers[p.Name()] = NewBreaker(0, 0)
+	}
+	if defaultName == "" {
+		defaultName = ps[0].Name()
+	}
+	if _, ok := out.byName[defaultName]; !ok {
+		return nil, fmt.Errorf("unknown OCR provider %q", defaultName)
+	}
+	out.def = defaultName
+	return out, nil
+}
+
+// Default returns the name of the provider used when none is requested.
+func (p *Providers) Default() string { return p.def }
+
+// Get returns the named provider, or the default when name is empty.
+func (p *Providers) Get(name string) (Provider, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = p.def
+	}
+	prov, ok := p.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown OCR provider %q", name)
+	}
+	return prov, nil
+}
+
+// Names returns the registered provider names in sorted order.
+func (p *Providers) Names() []string {
+	names := make([]string, 0, len(p.byName))
+	for n := range p.byName {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetFallbacks sets the providers tried, in order, after the requested one
+// fails or has an open circuit.
+func (p *Providers) SetFallbacks(names []string) error {
+	fallbacks := make([]string, 0, len(names))
+	for _, n := range names {
+		n = strings.ToLower(strings.TrimSpace(n))
+		if _, ok := p.byName[n]; !ok {
+			return fmt.Errorf("unknown OCR provider %q", n)
+		}
+		fallbacks = append(fallbacks, n)
+	}
+	p.fallbacks = fallbacks
+	return nil
+}
+
+// SetBreakers replaces every provider's circuit breaker.
+func (p *Providers) SetBreakers(threshold int, cooldown time.Duration) {
+	for name := range p.byName {
+		p.breakers[name] = NewBreaker(threshold, cooldown)
+	}
+}
+
+// BreakerStates reports each provider's circuit state.
+func (p *Providers) BreakerStates() map[string]string {
+	out := make(map[string]string, len(p.breakers))
+	for name, b := range p.breakers {
+		out[name] = b.State()
+	}
+	return out
+}
+
+// Chain returns the requested provider (or the default) followed by the
+// configured fallbacks, without duplicates.
+func (p *Providers) Chain(primary string) ([]Provider, error) {
+	first, err := p.Get(primary)
+	if err != nil {
+		return nil, err
+	}
+	chain := []Provider{first}
+	for _, n := range p.fallbacks {
+		if n != first.Name() && !containsProvider(chain, n) {
+			chain = append(chain, p.byName[n])
+		}
+	}
+	return chain, nil
+}
+
+func containsProvider(chain []Provider, name string) bool {
+	for _, prov := range chain {
+		if prov.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Do calls fn with each provider in chain until one succeeds and returns the
+// provider that did. Providers with an open circuit are skipped without a
+// call. Only outages (server errors, timeouts, auth and rate limiting) count
+// against a breaker; a rejected input still moves on to the next provider.
+func (p *Providers) Do(ctx context.Context, chain []Provider, fn func(Provider) error) (Provider, error) {
+	var failures []string
+	var lastErr error
+	for i, prov := range chain {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		b := p.breakers[prov.Name()]
+		if !b.Allow() {
+			failures = append(failures, prov.Name()+": circuit open")
+			continue
+		}
+
+		start := time.Now()
+		err := fn(prov)
+		metrics.ObserveCall(metrics.ServiceOCR, prov.Name(), time.Since(start), err)
+		switch {
+		case err == nil:
+			b.Success()
+			return prov, nil
+		case ctx.Err() != nil:
+			b.Cancel()
+			return nil, err
+		case isOutage(err):
+			b.Failure()
+		default:
+			b.Cancel()
+		}
+		lastErr = err
+		failures = append(failures, fmt.Sprintf("%s: %v", prov.Name(), err))
+		if i < len(chain)-1 {
+			fmt.Fprintf(os.Stderr, "ocr provider %s failed, trying next: %v\n", prov.Name(), err)
+		}
+	}
+
+	if len(failures) == 1 && lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, fmt.Errorf("all OCR providers failed: %s", strings.Join(failures, "; "))
+}
+
+// isOutage reports whether err reflects the provider's health rather than
+// the request. Client errors other than auth, timeout and rate limiting are
+// the input's fault.
+func isOutage(err error) bool {
+	var ocrErr *OCRError
+	if !errors.As(err, &ocrErr) || ocrErr.StatusCode < 400 || ocrErr.StatusCode >= 500 {
+		return true
+	}
+	switch ocrErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
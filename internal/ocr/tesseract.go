@@ -0,0 +1,230 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/usage"
+)
+
+// TesseractConfig configures the local OCR backend. Zero values fall back to
+// the defaults below.
+type TesseractConfig struct {
+	TesseractBinary string
+	PDFToPPMBinary  string
+	Languages       string // tesseract -l value, e.g. "eng+deu"
+	DPI             int
+	PageTimeout     time.Duration // per rasterize and per recognize call
+}
+
+func (c TesseractConfig) withDefaults() TesseractConfig {
+	out := c
+	if out.TesseractBinary == "" {
+		out.TesseractBinary = "tesseract"
+	}
+	if out.PDFToPPMBinary == "" {
+		out.PDFToPPMBinary = "pdftoppm"
+	}
+	if out.Languages == "" {
+		out.Languages = "eng"
+	}
+	if out.DPI <= 0 {
+		out.DPI = 300
+	}
+	if out.PageTimeout <= 0 {
+		out.PageTimeout = 60 * time.Second
+	}
+	return out
+}
+
+const maxTesseractPageBytes = 10 << 20
+
+// Tesseract OCRs locally: PDF pages are rasterized with pdftoppm and each
+// image is read by the tesseract CLI. It needs no API key and returns plain
+// text, so header/footer extraction and the model name do not apply.
+type Tesseract struct {
+	cfg TesseractConfig
+}
+
+func NewTesseract(cfg TesseractConfig) *Tesseract {
+	return &Tesseract{cfg: cfg.withDefaults()}
+}
+
+func (t *Tesseract) Name() string { return ProviderTesseract }
+
+func (t *Tesseract) OCRDocument(ctx context.Context, req DocumentRequest) (OCRResponse, error) {
+	if req.LocalPath == "" {
+		return OCRResponse{}, fmt.Errorf("tesseract OCR requires a local document")
+	}
+	if len(req.Pages0) == 0 {
+		return OCRResponse{}, fmt.Errorf("tesseract OCR requires a page selection")
+	}
+	pages0 := append([]int(nil), req.Pages0...)
+	sort.Ints(pages0)
+	pages0 = uniqueInts(pages0)
+	for _, p := range pages0 {
+		if p < 0 || p > 10000 {
+			return OCRResponse{}, fmt.Errorf("invalid page: %d", p)
+		}
+	}
+
+	return withConcurrencyLimit(ctx, func() (OCRResponse, error) {
+		dir, err := os.MkdirTemp("", "fileproc-tesseract-*")
+		if err != nil {
+			return OCRResponse{}, fmt.Errorf("create temp dir: %w", err)
+		}
+		defer os.RemoveAll(dir)
+
+		out := OCRResponse{Model: ProviderTesseract, Pages: make([]OCRPage, 0, len(pages0))}
+		for _, p0 := range pages0 {
+			if err := ctx.Err(); err != nil {
+				return OCRResponse{}, err
+			}
+			img, err := t.rasterize(ctx, req.LocalPath, p0+1, dir)
+			if err != nil {
+				return OCRResponse{}, err
+			}
+			text, err := t.recognize(ctx, img)
+			_ = os.Remove(img)
+			if err != nil {
+				return OCRResponse{}, fmt.Errorf("page %d: %w", p0+1, err)
+			}
+			out.Pages = append(out.Pages, OCRPage{Index: p0, Markdown: text})
+		}
+		out.UsageInfo.PagesProcessed = len(out.Pages)
+		usage.Record(ctx, ProviderTesseract, usage.UnitPages, float64(len(out.Pages)))
+		return out, nil
+	})
+}
+
+// OCRImage reads the downloaded image, or decodes a data URI into a temp file
+// when no local copy exists. Remote URLs are never fetched here.
+func (t *Tesseract) OCRImage(ctx context.Context, req ImageRequest) (OCRResponse, error) {
+	path := req.LocalPath
+	if path == "" {
+		tmp, err := writeDataURI(req.URL)
+		if err != nil {
+			return OCRResponse{}, err
+		}
+		defer os.Remove(tmp)
+		path = tmp
+	}
+
+	return withConcurrencyLimit(ctx, func() (OCRResponse, error) {
+		text, err := t.recognize(ctx, path)
+		if err != nil {
+			return OCRResponse{}, err
+		}
+		usage.Record(ctx, ProviderTesseract, usage.UnitPages, 1)
+		return OCRResponse{
+			Model:     ProviderTesseract,
+			Pages:     []OCRPage{{Index: 0, Markdown: text}},
+			UsageInfo: UsageInfo{PagesProcessed: 1},
+		}, nil
+	})
+}
+
+// rasterize renders one 1-based page to a PNG and returns its path.
+func (t *Tesseract) rasterize(ctx context.Context, pdfPath string, page int, dir string) (string, error) {
+	prefix := filepath.Join(dir, "page-"+strconv.Itoa(page))
+	_, err := t.run(ctx, t.cfg.PDFToPPMBinary,
+		"-r", strconv.Itoa(t.cfg.DPI),
+		"-f", strconv.Itoa(page),
+		"-l", strconv.Itoa(page),
+		"-singlefile",
+		"-png",
+		pdfPath,
+		prefix,
+	)
+	if err != nil {
+		return "", fmt.Errorf("rasterize page %d: %w", page, err)
+	}
+	return prefix + ".png", nil
+}
+
+func (t *Tesseract) recognize(ctx context.Context, imagePath string) (string, error) {
+	out, err := t.run(ctx, t.cfg.TesseractBinary, imagePath, "stdout", "-l", t.cfg.Languages)
+	if err != nil {
+		return "", err
+	}
+	if len(out) > maxTesseractPageBytes {
+		return "", fmt.Errorf("tesseract output too large: %dMB", len(out)/(1<<20))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (t *Tesseract) run(ctx context.Context, binary string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.cfg.PageTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binary, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &limitedBuffer{buf: &stdout, max: maxTesseractPageBytes + 1}
+	cmd.Stderr = &limitedBuffer{buf: &stderr, max: 64 << 10}
+
+	if err := cmd.Run(); err != nil {
+		tool := filepath.Base(binary)
+		switch {
+		case ctx.Err() == context.DeadlineExceeded:
+			return nil, fmt.Errorf("%s timeout after %s", tool, t.cfg.PageTimeout)
+		case errors.Is(err, exec.ErrNotFound):
+			return nil, fmt.Errorf("%s not installed", tool)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s failed: %s", tool, msg)
+		}
+		return nil, fmt.Errorf("%s failed: %w", tool, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// limitedBuffer keeps the first max bytes and silently drops the rest so a
+// runaway process cannot exhaust memory; callers check the length.
+type limitedBuffer struct {
+	buf *bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// writeDataURI decodes a base64 data URI into a temp file.
+func writeDataURI(uri string) (string, error) {
+	header, payload, ok := strings.Cut(uri, ",")
+	if !ok || !strings.HasPrefix(strings.ToLower(header), "data:") || !strings.HasSuffix(strings.ToLower(header), ";base64") {
+		return "", fmt.Errorf("tesseract OCR requires a local image or base64 data URI")
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("decode data URI: %w", err)
+	}
+	f, err := os.CreateTemp("", "fileproc-ocr-image-*")
+	if err != nil {
+		return "", fmt.Errorf("create temp image: %w", err)
+	}
+	_, werr := f.Write(data)
+	cerr := f.Close()
+	if werr != nil || cerr != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("write temp image: %w", errors.Join(werr, cerr))
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,101 @@
+package ocr
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFakeTool installs a shell script standing in for pdftoppm or tesseract.
+func writeFakeTool(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return p
+}
+
+func newFakeTesseract(t *testing.T) *Tesseract {
+	t.Helper()
+	dir := t.TempDir()
+	// pdftoppm -r DPI -f N -l N -singlefile -png in.pdf prefix: write the page
+	// number into prefix.png so the fake tesseract can echo it back.
+	pdftoppm := writeFakeTool(t, dir, "pdftoppm", `eval "prefix=\${$#}"; echo "text of page $4" > "$prefix.png"`+"\n")
+	// tesseract image stdout -l lang
+	tesseract := writeFakeTool(t, dir, "tesseract", `cat "$1"; echo "lang=$4"`+"\n")
+	return NewTesseract(TesseractConfig{TesseractBinary: tesseract, PDFToPPMBinary: pdftoppm, Languages: "eng+deu"})
+}
+
+func TestTesseractOCRDocumentRasterizesSelectedPages(t *testing.T) {
+	p := newFakeTesseract(t)
+	pdf := writeTempPDF(t, "%PDF-1.4 scan")
+
+	resp, err := p.OCRDocument(context.Background(), DocumentRequest{LocalPath: pdf, Pages0: []int{2, 0, 2}})
+	if err != nil {
+		t.Fatalf("ocr document: %v", err)
+	}
+	if len(resp.Pages) != 2 || resp.UsageInfo.PagesProcessed != 2 {
+		t.Fatalf("expected 2 unique pages, got %+v", resp)
+	}
+	if resp.Pages[0].Index != 0 || resp.Pages[0].Markdown != "text of page 1\nlang=eng+deu" {
+		t.Fatalf("unexpected first page: %+v", resp.Pages[0])
+	}
+	if resp.Pages[1].Index != 2 || !strings.HasPrefix(resp.Pages[1].Markdown, "text of page 3") {
+		t.Fatalf("unexpected second page: %+v", resp.Pages[1])
+	}
+}
+
+func TestTesseractOCRImageDecodesDataURI(t *testing.T) {
+	p := newFakeTesseract(t)
+	uri := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("receipt total 42"))
+
+	resp, err := p.OCRImage(context.Background(), ImageRequest{URL: uri})
+	if err != nil {
+		t.Fatalf("ocr image: %v", err)
+	}
+	if len(resp.Pages) != 1 || !strings.HasPrefix(resp.Pages[0].Markdown, "receipt total 42") {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	if _, err := p.OCRImage(context.Background(), ImageRequest{URL: "https://example.com/a.png"}); err == nil {
+		t.Fatalf("remote URLs must not be fetched by the local provider")
+	}
+}
+
+func TestTesseractReportsMissingBinary(t *testing.T) {
+	p := NewTesseract(TesseractConfig{PDFToPPMBinary: filepath.Join(t.TempDir(), "missing-pdftoppm")})
+	_, err := p.OCRDocument(context.Background(), DocumentRequest{LocalPath: writeTempPDF(t, "%PDF"), Pages0: []int{0}})
+	if err == nil || !strings.Contains(err.Error(), "missing-pdftoppm") {
+		t.Fatalf("expected missing binary error, got %v", err)
+	}
+}
+
+func TestProvidersResolveDefaultAndByName(t *testing.T) {
+	providers, err := NewProviders(ProviderTesseract, NewMistral("", 0), NewTesseract(TesseractConfig{}))
+	if err != nil {
+		t.Fatalf("new providers: %v", err)
+	}
+	if p, _ := providers.Get(""); p.Name() != ProviderTesseract {
+		t.Fatalf("empty name should select the default, got %s", p.Name())
+	}
+	if p, _ := providers.Get(" Mistral "); p.Name() != ProviderMistral {
+		t.Fatalf("expected mistral, got %s", p.Name())
+	}
+	if _, err := providers.Get("textract"); err == nil {
+		t.Fatalf("expected unknown provider error")
+	}
+	if _, err := NewProviders("textract", NewMistral("", 0)); err == nil {
+		t.Fatalf("expected unknown default error")
+	}
+}
+
+func TestMistralRequiresAPIKey(t *testing.T) {
+	_, err := NewMistral("", 0).OCRImage(context.Background(), ImageRequest{URL: "https://example.com/a.png"})
+	if err == nil || !strings.Contains(err.Error(), "MISTRAL_API_KEY") {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+}
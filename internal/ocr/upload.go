@@ -0,0 +1,186 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/upstream"
+)
+
+const (
+	uploadTimeout       = 120 * time.Second
+	signedURLExpiryHrs  = 1
+	defaultInlineMaxPDF = 10 << 20
+)
+
+type uploadedFile struct {
+	ID string `json:"id"`
+}
+
+type signedURLResponse struct {
+	URL string `json:"url"`
+}
+
+// LocalDocumentURL turns a local PDF into a document URL Mistral OCR can read.
+// Files up to inlineMaxBytes are sent inline as a base64 data URI; larger files
+// are uploaded through the Mistral files API and referenced by a short-lived
+// signed URL. The returned cleanup deletes any uploaded file and is never nil.
+func LocalDocumentURL(ctx context.Context, apiKey, pdfPath string, inlineMaxBytes int64) (string, func(), error) {
+	noop := func() {}
+
+	st, err := os.Stat(pdfPath)
+	if err != nil {
+		return "", noop, fmt.Errorf("stat local document: %w", err)
+	}
+	if st.Size() == 0 {
+		return "", noop, fmt.Errorf("local document is empty")
+	}
+	if inlineMaxBytes <= 0 {
+		inlineMaxBytes = defaultInlineMaxPDF
+	}
+
+	if st.Size() <= inlineMaxBytes {
+		data, err := os.ReadFile(pdfPath)
+		if err != nil {
+			return "", noop, fmt.Errorf("read local document: %w", err)
+		}
+		return "data:application/pdf;base64," + base64.StdEncoding.EncodeToString(data), noop, nil
+	}
+
+	if apiKey == "" {
+		return "", noop, fmt.Errorf("MISTRAL_API_KEY not configured")
+	}
+
+	fileID, err := uploadOCRFile(ctx, apiKey, pdfPath)
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup := func() {
+		// The request context may already be done; give deletion its own budget.
+		delCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := deleteOCRFile(delCtx, apiKey, fileID); err != nil {
+			fmt.Fprintf(os.Stderr, "ocr upload cleanup failed: %v\n", err)
+		}
+	}
+
+	signed, err := signedFileURL(ctx, apiKey, fileID)
+	if err != nil {
+		cleanup()
+		return "", noop, err
+	}
+	return signed, cleanup, nil
+}
+
+func uploadOCRFile(ctx context.Context, apiKey, pdfPath string) (string, error) {
+	resp, err := upstream.For(UpstreamName).WithTimeout(uploadTimeout).Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		// Reopened per attempt: the streamed body cannot be replayed.
+		f, err := os.Open(pdfPath)
+		if err != nil {
+			return nil, fmt.Errorf("open local document: %w", err)
+		}
+
+		// Stream the multipart body so large PDFs are never fully buffered.
+		pr, pw := io.Pipe()
+		mw := multipart.NewWriter(pw)
+		go func() {
+			defer f.Close()
+			err := mw.WriteField("purpose", "ocr")
+			if err == nil {
+				var fw io.Writer
+				fw, err = mw.CreateFormFile("file", filepath.Base(pdfPath))
+				if err == nil {
+					_, err = io.Copy(fw, f)
+				}
+			}
+			if err == nil {
+				err = mw.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+
+		req, err := http.NewRequestWithContext(ctx, "POST", mistralBaseURL+"/files", pr)
+		if err != nil {
+			pr.Close()
+			return nil, fmt.Errorf("create upload request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("User-Agent", "fileproc/1.0")
+		return req, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("upload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", parseErrorResponse(resp)
+	}
+
+	var out uploadedFile
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return "", fmt.Errorf("decode upload response: %w", err)
+	}
+	if out.ID == "" {
+		return "", fmt.Errorf("upload response missing file id")
+	}
+	return out.ID, nil
+}
+
+func signedFileURL(ctx context.Context, apiKey, fileID string) (string, error) {
+	endpoint := fmt.Sprintf("%s/files/%s/url?expiry=%d", mistralBaseURL, url.PathEscape(fileID), signedURLExpiryHrs)
+	body, err := doFileRequest(ctx, apiKey, "GET", endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	var out signedURLResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", fmt.Errorf("decode signed url response: %w", err)
+	}
+	if out.URL == "" {
+		return "", fmt.Errorf("signed url response missing url")
+	}
+	return out.URL, nil
+}
+
+func deleteOCRFile(ctx context.Context, apiKey, fileID string) error {
+	_, err := doFileRequest(ctx, apiKey, "DELETE", mistralBaseURL+"/files/"+url.PathEscape(fileID))
+	return err
+}
+
+func doFileRequest(ctx context.Context, apiKey, method, endpoint string) ([]byte, error) {
+	resp, err := upstream.For(UpstreamName).WithTimeout(30*time.Second).Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		req.Header.Set("User-Agent", "fileproc/1.0")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, parseErrorResponse(resp)
+	}
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(resp.Body, 1<<20)); err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
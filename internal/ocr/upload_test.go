@@ -0,0 +1,82 @@
+package ocr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func writeTempPDF(t *testing.T, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "scan.pdf")
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatalf("write pdf: %v", err)
+	}
+	return p
+}
+
+func TestLocalDocumentURLInlinesSmallFiles(t *testing.T) {
+	p := writeTempPDF(t, "%PDF-1.4 tiny")
+
+	u, cleanup, err := LocalDocumentURL(context.Background(), "", p, 1<<20)
+	if err != nil {
+		t.Fatalf("local document url: %v", err)
+	}
+	defer cleanup()
+
+	if !strings.HasPrefix(u, "data:application/pdf;base64,") {
+		t.Fatalf("expected data URI, got %q", u)
+	}
+}
+
+func TestLocalDocumentURLUploadsLargeFiles(t *testing.T) {
+	var deleted atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("missing auth header")
+		}
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/files":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("parse multipart: %v", err)
+			}
+			if r.FormValue("purpose") != "ocr" {
+				t.Errorf("expected purpose=ocr, got %q", r.FormValue("purpose"))
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "file-123"})
+		case r.Method == "GET" && r.URL.Path == "/files/file-123/url":
+			_ = json.NewEncoder(w).Encode(map[string]any{"url": "https://signed.example/file-123"})
+		case r.Method == "DELETE" && r.URL.Path == "/files/file-123":
+			deleted.Store(true)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "file-123", "deleted": true})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	orig := mistralBaseURL
+	mistralBaseURL = srv.URL
+	defer func() { mistralBaseURL = orig }()
+
+	p := writeTempPDF(t, "%PDF-1.4 "+strings.Repeat("x", 64))
+
+	u, cleanup, err := LocalDocumentURL(context.Background(), "test-key", p, 16)
+	if err != nil {
+		t.Fatalf("local document url: %v", err)
+	}
+	if u != "https://signed.example/file-123" {
+		t.Fatalf("unexpected signed url %q", u)
+	}
+	cleanup()
+	if !deleted.Load() {
+		t.Fatalf("expected uploaded file to be deleted on cleanup")
+	}
+}
@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+	return rec
+}
+
+func TestMiddlewareContinuesTraceparent(t *testing.T) {
+	rec := recordSpans(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "child")
+		span.End()
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/abc", nil)
+	req.Header.Set("traceparent", parent)
+	Middleware(mux).ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	child, server := spans[0], spans[1]
+	if server.Name() != "GET /jobs/{id}" {
+		t.Fatalf("server span name = %q", server.Name())
+	}
+	if server.SpanKind() != trace.SpanKindServer {
+		t.Fatalf("server span kind = %v", server.SpanKind())
+	}
+	if got := server.Parent().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("server span did not continue the caller's trace: %s", got)
+	}
+	if child.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Fatal("handler span is not a child of the server span")
+	}
+}
+
+func TestInjectExtractRoundTrip(t *testing.T) {
+	recordSpans(t)
+
+	ctx, span := Start(context.Background(), "submit")
+	defer span.End()
+
+	carrier := Inject(ctx)
+	if carrier["traceparent"] == "" {
+		t.Fatalf("traceparent not injected: %v", carrier)
+	}
+	got := trace.SpanContextFromContext(Extract(context.Background(), carrier))
+	if got.TraceID() != span.SpanContext().TraceID() || !got.IsRemote() {
+		t.Fatalf("extracted %v, want remote span of trace %s", got, span.SpanContext().TraceID())
+	}
+
+	if Inject(context.Background()) != nil {
+		t.Fatal("Inject without a span should return nil")
+	}
+}
+
+func TestTracesURL(t *testing.T) {
+	cases := map[string]string{
+		"http://collector:4318":              "http://collector:4318/v1/traces",
+		"https://collector:4318/":            "https://collector:4318/v1/traces",
+		"https://otlp.example.com/custom/v1": "https://otlp.example.com/custom/v1",
+	}
+	for in, want := range cases {
+		got, err := tracesURL(in)
+		if err != nil || got != want {
+			t.Fatalf("tracesURL(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"collector:4318", "ftp://collector", "http://"} {
+		if _, err := tracesURL(bad); err == nil {
+			t.Fatalf("tracesURL(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSetupWithoutEndpointIsNoop(t *testing.T) {
+	prevTP := otel.GetTracerProvider()
+	shutdown, err := Setup(context.Background(), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if otel.GetTracerProvider() != prevTP {
+		t.Fatal("Setup without an endpoint replaced the tracer provider")
+	}
+}
@@ -11,6 +11,10 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/metrics"
+	"github.com/toricodesthings/file-processing-service/internal/upstream"
+	"github.com/toricodesthings/file-processing-service/internal/usage"
 )
 
 const (
@@ -18,6 +22,9 @@ const (
 	defaultModel   = "whisper-large-v3-turbo"
 )
 
+// UpstreamName is the upstream client name for Groq.
+const UpstreamName = "groq"
+
 var ErrAPIKeyMissing = errors.New("GROQ_API_KEY not set")
 
 type Client struct {
@@ -77,7 +84,7 @@ func NewClient(apiKey, apiURL string, timeout time.Duration) *Client {
 	return &Client{apiKey: apiKey, apiURL: apiURL, timeout: timeout}
 }
 
-func (c *Client) Transcribe(ctx context.Context, fileName string, fileContent []byte, opts Options) (Response, error) {
+func (c *Client) Transcribe(ctx context.Context, fileName string, fileContent []byte, opts Options) (out Response, err error) {
 	if strings.TrimSpace(c.apiKey) == "" {
 		return Response{}, ErrAPIKeyMissing
 	}
@@ -87,6 +94,8 @@ func (c *Client) Transcribe(ctx context.Context, fileName string, fileContent []
 	if strings.TrimSpace(fileName) == "" {
 		fileName = "audio.bin"
 	}
+	start := time.Now()
+	defer func() { metrics.ObserveCall(metrics.ServiceTranscribe, UpstreamName, time.Since(start), err) }()
 
 	model := strings.TrimSpace(opts.Model)
 	if model == "" {
@@ -96,6 +105,9 @@ func (c *Client) Transcribe(ctx context.Context, fileName string, fileContent []
 	if responseFormat == "" {
 		responseFormat = "verbose_json"
 	}
+	if !validResponseFormats[responseFormat] {
+		return Response{}, fmt.Errorf("unsupported response format %q", responseFormat)
+	}
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -107,7 +119,9 @@ func (c *Client) Transcribe(ctx context.Context, fileName string, fileContent []
 	_, _ = fw.Write(fileContent)
 
 	_ = writer.WriteField("model", model)
-	_ = writer.WriteField("response_format", responseFormat)
+	// Groq bills by audio duration, which only verbose_json reports, so it is
+	// always requested and the asked-for format is rendered from it below.
+	_ = writer.WriteField("response_format", "verbose_json")
 	if strings.TrimSpace(opts.Language) != "" {
 		_ = writer.WriteField("language", strings.TrimSpace(opts.Language))
 	}
@@ -119,16 +133,19 @@ func (c *Client) Transcribe(ctx context.Context, fileName string, fileContent []
 	}
 	_ = writer.Close()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL, body)
-	if err != nil {
-		return Response{}, err
-	}
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("User-Agent", "fileproc/2.0")
-
-	httpClient := &http.Client{Timeout: c.timeout}
-	resp, err := httpClient.Do(req)
+	// The shared Groq client paces bulk jobs and waits out 429s instead of
+	// retrying straight into the rate limit.
+	payload := body.Bytes()
+	resp, err := upstream.For(UpstreamName).WithTimeout(c.timeout).Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("User-Agent", "fileproc/2.0")
+		return req, nil
+	})
 	if err != nil {
 		return Response{}, err
 	}
@@ -143,11 +160,24 @@ func (c *Client) Transcribe(ctx context.Context, fileName string, fileContent []
 		return Response{}, parseAPIError(resp.StatusCode, bodyBytes)
 	}
 
-	var out Response
 	if err := json.Unmarshal(bodyBytes, &out); err != nil {
 		return Response{}, err
 	}
-	return out, nil
+	usage.Record(ctx, UpstreamName, usage.UnitAudioSeconds, out.Duration)
+	return renderResponse(out, responseFormat), nil
+}
+
+// validResponseFormats are the response formats Groq's transcription API
+// accepts.
+var validResponseFormats = map[string]bool{"json": true, "text": true, "verbose_json": true}
+
+// renderResponse trims a verbose_json response to what responseFormat
+// returns: json and text carry only the transcript.
+func renderResponse(out Response, responseFormat string) Response {
+	if responseFormat == "verbose_json" {
+		return out
+	}
+	return Response{Text: out.Text}
 }
 
 func parseAPIError(statusCode int, body []byte) error {
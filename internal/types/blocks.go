@@ -0,0 +1,37 @@
+package types
+
+// Block types in the structured document model.
+const (
+	BlockHeading   = "heading"
+	BlockParagraph = "paragraph"
+	BlockListItem  = "list-item"
+	BlockTable     = "table"
+	BlockCode      = "code"
+	BlockQuote     = "quote"
+	BlockImage     = "image"
+	BlockPageBreak = "page-break"
+)
+
+// Block is one node of a document's structure, as the extractor parsed it
+// before flattening it into markdown. Type says which of the other fields
+// are set.
+type Block struct {
+	Type     string     `json:"type"`
+	Text     string     `json:"text,omitempty"`     // heading, paragraph, list-item, code; image: alt text or name
+	Level    int        `json:"level,omitempty"`    // heading: 1-6; list-item: nesting depth, 0 at the top
+	Ordered  bool       `json:"ordered,omitempty"`  // list-item: numbered rather than bulleted
+	Language string     `json:"language,omitempty"` // code, when known
+	Cells    [][]string `json:"cells,omitempty"`    // table: rows of cell text, header row first
+	Children []Block    `json:"children,omitempty"` // quote: the quoted blocks
+	Source   Source     `json:"source"`
+}
+
+// Source locates a block in the original file. Only the fields that apply
+// to the format are set.
+type Source struct {
+	Page    int    `json:"page,omitempty"`    // 1-based page or slide
+	Sheet   string `json:"sheet,omitempty"`   // spreadsheet sheet name
+	Part    string `json:"part,omitempty"`    // entry inside a container, e.g. "word/document.xml" or an EPUB chapter
+	Element int    `json:"element,omitempty"` // 1-based position among the block-level elements of Part or Page
+	Line    int    `json:"line,omitempty"`    // 1-based first line, for text sources
+}
@@ -0,0 +1,21 @@
+package types
+
+// Table is one table found in a document, with the structure its markdown
+// rendering loses.
+type Table struct {
+	Source Source `json:"source"`
+	// HeaderRows counts the leading rows that are headers. Formats that
+	// don't mark them report 1, as their markdown rendering does.
+	HeaderRows int        `json:"headerRows"`
+	Cells      [][]string `json:"cells"` // rows of cell text, padded to the same width
+	Merges     []Merge    `json:"merges,omitempty"`
+}
+
+// Merge is a merged cell: the cell at Row, Col (0-based) spans RowSpan rows
+// and ColSpan columns. The other cells it covers are empty in Cells.
+type Merge struct {
+	Row     int `json:"row"`
+	Col     int `json:"col"`
+	RowSpan int `json:"rowSpan"`
+	ColSpan int `json:"colSpan"`
+}
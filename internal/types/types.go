@@ -10,12 +10,58 @@ type HybridProcessorOptions struct {
 	ExtractHeader bool    `json:"extractHeader"`
 	ExtractFooter bool    `json:"extractFooter"`
 	OCRModel      *string `json:"ocrModel"`
+	OCRProvider   string  `json:"ocrProvider"` // "mistral" | "tesseract"; empty = server default
+
+	// DocumentHash is the SHA-256 of the PDF, set by the caller when it is
+	// already known. It keys the per-page OCR cache.
+	DocumentHash string `json:"-"`
+
+	// Progress, when set by the caller, receives page and OCR events.
+	Progress ProgressFunc `json:"-"`
 
 	// Preview-only knobs (text-layer only)
 	PreviewMaxPages int `json:"previewMaxPages"` // default e.g. 8
 	PreviewMaxChars int `json:"previewMaxChars"` // default e.g. 20000
 }
 
+// Progress stages reported while an extraction runs.
+const (
+	ProgressDownloaded           = "download_complete"
+	ProgressPageCount            = "page_count"
+	ProgressPage                 = "page"
+	ProgressOCRStarted           = "ocr_started"
+	ProgressOCRFinished          = "ocr_finished"
+	ProgressTranscriptionStarted = "transcription_started"
+)
+
+// ProgressEvent describes one step of a long extraction. Stage says which of
+// the other fields are set.
+type ProgressEvent struct {
+	Stage      string `json:"stage"`
+	Bytes      int64  `json:"bytes,omitempty"`      // download_complete
+	MIMEType   string `json:"mimeType,omitempty"`   // download_complete
+	FileType   string `json:"fileType,omitempty"`   // download_complete: extractor that will run
+	TotalPages int    `json:"totalPages,omitempty"` // page_count
+	Page       int    `json:"page,omitempty"`       // page: 1-based page number
+	Method     string `json:"method,omitempty"`     // page: "text-layer" | "needs-ocr"
+	WordCount  int    `json:"wordCount,omitempty"`  // page
+	Pages      int    `json:"pages,omitempty"`      // page_count: pages selected; ocr_*: pages in the batch
+	Cached     int    `json:"cached,omitempty"`     // ocr_finished: pages served from the OCR cache
+	Provider   string `json:"provider,omitempty"`   // ocr_started, transcription_started
+	Error      string `json:"error,omitempty"`      // ocr_finished
+}
+
+// ProgressFunc receives progress events. It may be called from several
+// goroutines at once; a nil ProgressFunc discards events.
+type ProgressFunc func(ProgressEvent)
+
+// Report delivers ev when f is set.
+func (f ProgressFunc) Report(ev ProgressEvent) {
+	if f != nil {
+		f(ev)
+	}
+}
+
 type ExtractRequest struct {
 	PresignedURL string                 `json:"presignedUrl"`
 	Options      HybridProcessorOptions `json:"options"`
@@ -24,7 +70,7 @@ type ExtractRequest struct {
 type PageExtractionResult struct {
 	PageNumber int    `json:"pageNumber"`
 	Text       string `json:"text"`
-	Method     string `json:"method"` // "text-layer" | "ocr"
+	Method     string `json:"method"` // "text-layer" | "ocr:<provider>" | "needs-ocr"
 	WordCount  int    `json:"wordCount"`
 }
 
@@ -35,10 +81,29 @@ type HybridExtractionResult struct {
 	TotalPages         int                    `json:"totalPages"`
 	TextLayerPages     int                    `json:"textLayerPages"`
 	OCRPages           int                    `json:"ocrPages"`
+	OCRCachedPages     int                    `json:"ocrCachedPages"`
 	CostSavingsPercent int                    `json:"costSavingsPercent"`
+	NeedsOCR           bool                   `json:"needsOcr"`                 // pages still lack text after OCR
+	BudgetExceeded     bool                   `json:"budgetExceeded,omitempty"` // paid OCR was skipped
 	Error              *string                `json:"error,omitempty"`
 }
 
+// HybridStreamSummary is returned by Processor.StreamHybrid after the last
+// page has been emitted. It carries the counts of HybridExtractionResult but
+// no text.
+type HybridStreamSummary struct {
+	TotalPages         int     `json:"totalPages"`
+	TextLayerPages     int     `json:"textLayerPages"`
+	OCRPages           int     `json:"ocrPages"`
+	OCRCachedPages     int     `json:"ocrCachedPages"`
+	NeedsOCRPages      int     `json:"needsOcrPages"`
+	CostSavingsPercent int     `json:"costSavingsPercent"`
+	WordCount          int     `json:"wordCount"`
+	CharCount          int     `json:"charCount"`
+	BudgetExceeded     bool    `json:"budgetExceeded,omitempty"`
+	Error              *string `json:"error,omitempty"` // OCR failure; pages that needed it were emitted as needs-ocr
+}
+
 type PreviewResult struct {
 	Success        bool    `json:"success"`
 	NeedsOCR       bool    `json:"needsOcr"`
@@ -56,10 +121,15 @@ type ImageExtractRequest struct {
 }
 
 type ImageExtractionResult struct {
-	Success     bool    `json:"success"`
-	Text        string  `json:"text"`                  // Primary text for embedding (OCR transcription OR vision description)
-	Method      string  `json:"method,omitempty"`      // "ocr" | "vision" | "ocr+vision"
-	ImageType   string  `json:"imageType,omitempty"`   // "handwriting" | "photo" | "diagram" | etc.
-	Description string  `json:"description,omitempty"` // Vision-generated description (present when vision ran)
-	Error       *string `json:"error,omitempty"`
+	Success     bool   `json:"success"`
+	Text        string `json:"text"`                  // Primary text for embedding (OCR transcription OR vision description)
+	Method      string `json:"method,omitempty"`      // "ocr" | "vision" | "ocr+vision"
+	ImageType   string `json:"imageType,omitempty"`   // "handwriting" | "photo" | "diagram" | etc.
+	Description string `json:"description,omitempty"` // Vision-generated description (present when vision ran)
+	OCRProvider string `json:"ocrProvider,omitempty"` // provider that served the OCR text, if used
+	// BudgetExceeded is set when paid OCR and vision were skipped; NeedsOCR
+	// then reports that no free provider produced text.
+	BudgetExceeded bool    `json:"budgetExceeded,omitempty"`
+	NeedsOCR       bool    `json:"needsOcr,omitempty"`
+	Error          *string `json:"error,omitempty"`
 }
@@ -0,0 +1,334 @@
+// Package upstream is the shared HTTP client for third-party APIs (Mistral,
+// OpenRouter, Groq). Each named upstream has one client so its token bucket
+// and throttle state are shared by every caller in the process.
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"golang.org/x/time/rate"
+)
+
+// Policy controls retries and pacing for one upstream.
+type Policy struct {
+	MaxRetries int           // retries after the first attempt
+	BaseDelay  time.Duration // first backoff; doubled on every retry
+	MaxDelay   time.Duration // cap for backoff; longer server-requested waits are not retried
+	RatePerSec float64       // token bucket refill rate; <= 0 disables the bucket
+	Burst      int
+}
+
+func (p Policy) withDefaults() Policy {
+	out := p
+	if out.MaxRetries < 0 {
+		out.MaxRetries = 0
+	}
+	if out.BaseDelay <= 0 {
+		out.BaseDelay = time.Second
+	}
+	if out.MaxDelay <= 0 {
+		out.MaxDelay = 60 * time.Second
+	}
+	if out.Burst <= 0 {
+		out.Burst = 1
+	}
+	return out
+}
+
+// DefaultPolicy is used for upstreams that were never configured.
+var DefaultPolicy = Policy{MaxRetries: 2, BaseDelay: time.Second, MaxDelay: 60 * time.Second}
+
+// Client sends requests to one upstream with backoff, jitter, header-driven
+// waits and a token bucket. Copies made by WithTimeout share all of that state.
+type Client struct {
+	name     string
+	policy   Policy
+	http     *http.Client
+	limiter  *rate.Limiter // nil when unlimited
+	throttle *throttle
+	timeout  time.Duration // per attempt, including reading the body; 0 = none
+}
+
+// throttle holds the time before which no request may start, set when the
+// upstream reports that its rate limit is exhausted.
+type throttle struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+var (
+	registryMu sync.Mutex
+	clients    = map[string]*Client{}
+	now        = time.Now
+)
+
+// Configure installs the policy for the named upstream. Call it at startup;
+// callers that already hold the previous client keep using it.
+func Configure(name string, p Policy) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	clients[name] = newClient(name, p)
+}
+
+// For returns the shared client for the named upstream, creating one with
+// DefaultPolicy when it was never configured.
+func For(name string) *Client {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	c, ok := clients[name]
+	if !ok {
+		c = newClient(name, DefaultPolicy)
+		clients[name] = c
+	}
+	return c
+}
+
+func newClient(name string, p Policy) *Client {
+	p = p.withDefaults()
+	c := &Client{
+		name:   name,
+		policy: p,
+		http: &http.Client{Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     30 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		}},
+		throttle: &throttle{},
+	}
+	if p.RatePerSec > 0 {
+		c.limiter = rate.NewLimiter(rate.Limit(p.RatePerSec), p.Burst)
+	}
+	return c
+}
+
+// WithTimeout returns a copy of c that bounds every attempt by d.
+func (c *Client) WithTimeout(d time.Duration) *Client {
+	cp := *c
+	cp.timeout = d
+	return &cp
+}
+
+// Do sends the request returned by build, retrying network errors, 429 and
+// 5xx responses. build is called once per attempt (request bodies are
+// consumed) with a context bounded by the attempt timeout. The final
+// response is returned whatever its status; the caller closes its body and
+// maps non-2xx statuses to its own error type.
+func (c *Client) Do(ctx context.Context, build func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		// One client span per attempt; trace headers are not sent to third parties.
+		spanCtx, span := tracing.StartClient(ctx, c.name+" request",
+			attribute.String("upstream.name", c.name),
+			attribute.Int("upstream.attempt", attempt+1),
+		)
+		attemptCtx, cancel := spanCtx, context.CancelFunc(func() {})
+		if c.timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(spanCtx, c.timeout)
+		}
+		req, err := build(attemptCtx)
+		if err != nil {
+			cancel()
+			tracing.End(span, err)
+			return nil, err
+		}
+		span.SetAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Hostname()),
+		)
+
+		var delay time.Duration
+		resp, err := c.http.Do(req)
+		if err != nil {
+			cancel()
+			tracing.End(span, err)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if attempt >= c.policy.MaxRetries {
+				return nil, fmt.Errorf("%s request failed after %d attempts: %w", c.name, attempt+1, err)
+			}
+			delay = c.backoff(attempt)
+			fmt.Fprintf(os.Stderr, "[upstream] %s attempt %d failed, retrying in %s: %v\n", c.name, attempt+1, delay.Round(time.Millisecond), err)
+		} else {
+			c.observe(resp.Header)
+			span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+			if resp.StatusCode >= 400 {
+				span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+			}
+			span.End()
+			if !retryableStatus(resp.StatusCode) || attempt >= c.policy.MaxRetries {
+				resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+				return resp, nil
+			}
+			delay = retryAfter(resp.Header, now())
+			if delay > c.policy.MaxDelay {
+				// Waiting that long would outlive most callers; report the throttle instead.
+				resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+				return resp, nil
+			}
+			if delay <= 0 {
+				delay = c.backoff(attempt)
+			}
+			if resp.StatusCode == http.StatusTooManyRequests {
+				c.pause(delay)
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			cancel()
+			fmt.Fprintf(os.Stderr, "[upstream] %s returned %d, retrying in %s\n", c.name, resp.StatusCode, delay.Round(time.Millisecond))
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// wait blocks until the shared throttle has passed and a token is available.
+func (c *Client) wait(ctx context.Context) error {
+	c.throttle.mu.Lock()
+	until := c.throttle.until
+	c.throttle.mu.Unlock()
+	if d := until.Sub(now()); d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	if c.limiter != nil {
+		return c.limiter.Wait(ctx)
+	}
+	return nil
+}
+
+// pause holds back every caller of this upstream for d.
+func (c *Client) pause(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	if d > c.policy.MaxDelay {
+		d = c.policy.MaxDelay
+	}
+	c.throttle.mu.Lock()
+	defer c.throttle.mu.Unlock()
+	if until := now().Add(d); until.After(c.throttle.until) {
+		c.throttle.until = until
+	}
+}
+
+// observe pauses the upstream when a response says the request quota is used up.
+func (c *Client) observe(h http.Header) {
+	for _, key := range []string{"X-Ratelimit-Remaining-Requests", "X-Ratelimit-Remaining"} {
+		if strings.TrimSpace(h.Get(key)) == "0" {
+			c.pause(resetDelay(h, now()))
+			return
+		}
+	}
+}
+
+// backoff is exponential with equal jitter: half the delay is fixed, the
+// other half random, so concurrent callers spread out.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.policy.BaseDelay << attempt
+	if d <= 0 || d > c.policy.MaxDelay {
+		d = c.policy.MaxDelay
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter reads the server-requested wait: Retry-After (seconds or HTTP
+// date), retry-after-ms, then the x-ratelimit reset headers.
+func retryAfter(h http.Header, at time.Time) time.Duration {
+	if v := strings.TrimSpace(h.Get("Retry-After-Ms")); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms >= 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	if v := strings.TrimSpace(h.Get("Retry-After")); v != "" {
+		if secs, err := strconv.ParseFloat(v, 64); err == nil && secs >= 0 {
+			return time.Duration(secs * float64(time.Second))
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return t.Sub(at)
+		}
+	}
+	return resetDelay(h, at)
+}
+
+// resetDelay reads rate-limit reset headers. Groq/OpenAI send durations such
+// as "2m59.56s" per quota; OpenRouter sends an epoch timestamp in ms.
+func resetDelay(h http.Header, at time.Time) time.Duration {
+	var longest time.Duration
+	for _, key := range []string{"X-Ratelimit-Reset-Requests", "X-Ratelimit-Reset-Tokens", "X-Ratelimit-Reset"} {
+		if d := parseReset(strings.TrimSpace(h.Get(key)), at); d > longest {
+			longest = d
+		}
+	}
+	return longest
+}
+
+func parseReset(v string, at time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return d
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	switch {
+	case n > 1e12: // epoch milliseconds
+		return time.UnixMilli(int64(n)).Sub(at)
+	case n > 1e9: // epoch seconds
+		return time.Unix(int64(n), 0).Sub(at)
+	default: // seconds
+		return time.Duration(n * float64(time.Second))
+	}
+}
+
+// cancelOnClose releases the attempt context once the caller is done with the body.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
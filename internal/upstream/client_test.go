@@ -0,0 +1,242 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func post(url, body string) func(ctx context.Context) (*http.Request, error) {
+	return func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(body))
+	}
+}
+
+func fastPolicy() Policy {
+	return Policy{MaxRetries: 2, BaseDelay: 5 * time.Millisecond, MaxDelay: time.Second}
+}
+
+func TestDoRetriesServerErrorsAndReplaysBody(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if string(b) != "payload" {
+			t.Errorf("attempt %d got body %q", calls.Load()+1, b)
+		}
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	resp, err := newClient("test", fastPolicy()).WithTimeout(time.Second).Do(context.Background(), post(srv.URL, "payload"))
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(b) != "ok" || calls.Load() != 3 {
+		t.Fatalf("unexpected result: status=%d body=%q calls=%d", resp.StatusCode, b, calls.Load())
+	}
+}
+
+func TestDoTracesEachAttemptWithoutLeakingTraceHeaders(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	}()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("traceparent") != "" {
+			t.Error("trace context sent to the upstream")
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	resp, err := newClient("test", fastPolicy()).Do(context.Background(), post(srv.URL, "payload"))
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want one per attempt", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error || spans[1].Status().Code == codes.Error {
+		t.Fatalf("unexpected span statuses: %v, %v", spans[0].Status(), spans[1].Status())
+	}
+}
+
+func TestDoReturnsClientErrorsWithoutRetrying(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}))
+	defer srv.Close()
+
+	resp, err := newClient("test", fastPolicy()).Do(context.Background(), post(srv.URL, ""))
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity || calls.Load() != 1 {
+		t.Fatalf("expected a single 422, got status=%d calls=%d", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestDoWaitsForRateLimitResetAndThrottlesOtherCallers(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("X-Ratelimit-Reset-Requests", "150ms")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := newClient("test", fastPolicy())
+	start := time.Now()
+	resp, err := c.Do(context.Background(), post(srv.URL, ""))
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("expected to wait for the reset header, waited %s", elapsed)
+	}
+	if c.throttle.until.IsZero() {
+		t.Fatalf("429 should pause every caller of the upstream")
+	}
+}
+
+func TestDoGivesUpWhenServerAsksForTooLongAWait(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	resp, err := newClient("test", fastPolicy()).Do(context.Background(), post(srv.URL, ""))
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || calls.Load() != 1 {
+		t.Fatalf("expected the 429 to be returned at once, got status=%d calls=%d", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestDoPausesWhenQuotaIsExhausted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "0")
+		w.Header().Set("X-Ratelimit-Reset-Requests", "100ms")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := newClient("test", fastPolicy())
+	for i := 0; i < 2; i++ {
+		resp, err := c.Do(context.Background(), post(srv.URL, ""))
+		if err != nil {
+			t.Fatalf("do: %v", err)
+		}
+		resp.Body.Close()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.Do(ctx, post(srv.URL, "")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the exhausted quota to hold the request back, got %v", err)
+	}
+}
+
+func TestDoHonorsTokenBucket(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	p := fastPolicy()
+	p.RatePerSec = 20
+	p.Burst = 1
+	c := newClient("test", p)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		resp, err := c.Do(context.Background(), post(srv.URL, ""))
+		if err != nil {
+			t.Fatalf("do: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("3 requests at 20/s with burst 1 should take ~100ms, took %s", elapsed)
+	}
+}
+
+func TestDoStopsBackoffOnCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	p := fastPolicy()
+	p.BaseDelay = time.Minute
+	p.MaxDelay = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := newClient("test", p).Do(ctx, post(srv.URL, "")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("cancellation should interrupt the backoff")
+	}
+}
+
+func TestRetryAfterParsesHeaderFormats(t *testing.T) {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"seconds", http.Header{"Retry-After": {"7"}}, 7 * time.Second},
+		{"http date", http.Header{"Retry-After": {at.Add(90 * time.Second).Format(http.TimeFormat)}}, 90 * time.Second},
+		{"milliseconds", http.Header{"Retry-After-Ms": {"250"}}, 250 * time.Millisecond},
+		{"groq duration", http.Header{"X-Ratelimit-Reset-Requests": {"2m59.5s"}, "X-Ratelimit-Reset-Tokens": {"6s"}}, 2*time.Minute + 59500*time.Millisecond},
+		{"epoch ms", http.Header{"X-Ratelimit-Reset": {"1767225605000"}}, 5 * time.Second},
+		{"none", http.Header{}, 0},
+	}
+	for _, tc := range cases {
+		if got := retryAfter(tc.header, at); got != tc.want {
+			t.Fatalf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
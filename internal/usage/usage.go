@@ -0,0 +1,163 @@
+// Package usage records billable upstream usage (OCR pages, vision tokens,
+// transcribed audio) per request and for the whole process, and prices it
+// with a configurable table.
+package usage
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Units recorded by the upstream clients.
+const (
+	UnitPages        = "pages"
+	UnitInputTokens  = "input_tokens"
+	UnitOutputTokens = "output_tokens"
+	UnitAudioSeconds = "audio_seconds"
+)
+
+// Prices maps "provider:unit" to USD per unit. Missing entries cost nothing.
+type Prices map[string]float64
+
+func PriceKey(provider, unit string) string { return provider + ":" + unit }
+
+// Item is one provider/unit line of a usage summary.
+type Item struct {
+	Provider string  `json:"provider"`
+	Unit     string  `json:"unit"`
+	Quantity float64 `json:"quantity"`
+	CostUSD  float64 `json:"costUsd"`
+}
+
+// Summary is what one request spent.
+type Summary struct {
+	Items            []Item  `json:"items"`
+	EstimatedCostUSD float64 `json:"estimatedCostUsd"`
+}
+
+// ProviderTotals is the process-wide usage of one provider.
+type ProviderTotals struct {
+	Units            map[string]float64 `json:"units"`
+	EstimatedCostUSD float64            `json:"estimatedCostUsd"`
+}
+
+type lineKey struct{ provider, unit string }
+
+// Meter accumulates usage for one request. It is safe for concurrent use.
+type Meter struct {
+	mu    sync.Mutex
+	lines map[lineKey]float64
+}
+
+func NewMeter() *Meter {
+	return &Meter{lines: make(map[lineKey]float64)}
+}
+
+func (m *Meter) add(provider, unit string, qty float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lines[lineKey{provider, unit}] += qty
+}
+
+// Summary prices the recorded usage. It returns nil when nothing was recorded.
+func (m *Meter) Summary() *Summary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.lines) == 0 {
+		return nil
+	}
+	prices := currentPrices()
+	out := &Summary{Items: make([]Item, 0, len(m.lines))}
+	for k, qty := range m.lines {
+		cost := qty * prices[PriceKey(k.provider, k.unit)]
+		out.Items = append(out.Items, Item{Provider: k.provider, Unit: k.unit, Quantity: qty, CostUSD: roundUSD(cost)})
+		out.EstimatedCostUSD += cost
+	}
+	sort.Slice(out.Items, func(i, j int) bool {
+		if out.Items[i].Provider != out.Items[j].Provider {
+			return out.Items[i].Provider < out.Items[j].Provider
+		}
+		return out.Items[i].Unit < out.Items[j].Unit
+	})
+	out.EstimatedCostUSD = roundUSD(out.EstimatedCostUSD)
+	return out
+}
+
+type meterKey struct{}
+
+// WithMeter attaches m to ctx so upstream clients can record into it.
+func WithMeter(ctx context.Context, m *Meter) context.Context {
+	return context.WithValue(ctx, meterKey{}, m)
+}
+
+// Pending returns the estimated cost recorded so far by the request's meter.
+func Pending(ctx context.Context) float64 {
+	m, ok := ctx.Value(meterKey{}).(*Meter)
+	if !ok || m == nil {
+		return 0
+	}
+	if sum := m.Summary(); sum != nil {
+		return sum.EstimatedCostUSD
+	}
+	return 0
+}
+
+// Record adds usage to the process totals and to the request's meter, if any.
+func Record(ctx context.Context, provider, unit string, qty float64) {
+	if qty <= 0 {
+		return
+	}
+	global.add(provider, unit, qty)
+	if m, ok := ctx.Value(meterKey{}).(*Meter); ok && m != nil {
+		m.add(provider, unit, qty)
+	}
+}
+
+var (
+	global = NewMeter()
+
+	pricesMu sync.RWMutex
+	prices   = Prices{}
+)
+
+// SetPrices replaces the price table used for estimates.
+func SetPrices(p Prices) {
+	pricesMu.Lock()
+	defer pricesMu.Unlock()
+	prices = make(Prices, len(p))
+	for k, v := range p {
+		prices[k] = v
+	}
+}
+
+func currentPrices() Prices {
+	pricesMu.RLock()
+	defer pricesMu.RUnlock()
+	return prices
+}
+
+// Totals reports process-wide usage grouped by provider.
+func Totals() map[string]ProviderTotals {
+	sum := global.Summary()
+	out := map[string]ProviderTotals{}
+	if sum == nil {
+		return out
+	}
+	for _, it := range sum.Items {
+		t, ok := out[it.Provider]
+		if !ok {
+			t = ProviderTotals{Units: map[string]float64{}}
+		}
+		t.Units[it.Unit] = it.Quantity
+		t.EstimatedCostUSD = roundUSD(t.EstimatedCostUSD + it.CostUSD)
+		out[it.Provider] = t
+	}
+	return out
+}
+
+// roundUSD keeps estimates readable; sub-micro-dollar noise is not meaningful.
+func roundUSD(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
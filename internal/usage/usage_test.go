@@ -0,0 +1,62 @@
+package usage
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestMeterSummaryPricesAndSortsItems(t *testing.T) {
+	SetPrices(Prices{
+		PriceKey("mistral", UnitPages):          0.001,
+		PriceKey("openrouter", UnitInputTokens): 0.5 / 1e6,
+	})
+	defer SetPrices(nil)
+
+	m := NewMeter()
+	if m.Summary() != nil {
+		t.Fatalf("empty meter should have no summary")
+	}
+	ctx := WithMeter(context.Background(), m)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Record(ctx, "mistral", UnitPages, 1)
+		}()
+	}
+	wg.Wait()
+	Record(ctx, "openrouter", UnitInputTokens, 2000)
+	Record(ctx, "groq", UnitAudioSeconds, 30) // unpriced
+	Record(ctx, "groq", UnitAudioSeconds, 0)  // ignored
+
+	sum := m.Summary()
+	if len(sum.Items) != 3 {
+		t.Fatalf("expected 3 items, got %+v", sum.Items)
+	}
+	if sum.Items[0].Provider != "groq" || sum.Items[1].Provider != "mistral" || sum.Items[2].Provider != "openrouter" {
+		t.Fatalf("items not sorted by provider: %+v", sum.Items)
+	}
+	if sum.Items[0].CostUSD != 0 || sum.Items[0].Quantity != 30 {
+		t.Fatalf("unexpected groq item: %+v", sum.Items[0])
+	}
+	if sum.Items[1].Quantity != 10 || sum.Items[1].CostUSD != 0.01 {
+		t.Fatalf("unexpected mistral item: %+v", sum.Items[1])
+	}
+	if sum.EstimatedCostUSD != 0.011 {
+		t.Fatalf("expected total 0.011, got %v", sum.EstimatedCostUSD)
+	}
+}
+
+func TestRecordWithoutMeterUpdatesTotals(t *testing.T) {
+	before := Totals()["totals-test"].Units[UnitPages]
+	Record(context.Background(), "totals-test", UnitPages, 3)
+	Record(context.Background(), "totals-test", UnitOutputTokens, 7)
+
+	got := Totals()["totals-test"]
+	if got.Units[UnitPages] != before+3 || got.Units[UnitOutputTokens] != 7 {
+		t.Fatalf("unexpected totals: %+v", got)
+	}
+}
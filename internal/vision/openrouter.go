@@ -10,6 +10,10 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/toricodesthings/file-processing-service/internal/metrics"
+	"github.com/toricodesthings/file-processing-service/internal/upstream"
+	"github.com/toricodesthings/file-processing-service/internal/usage"
 )
 
 // ── Public types ─────────────────────────────────────────────────────────────
@@ -23,12 +27,13 @@ type VisionResult struct {
 
 // ── Config ───────────────────────────────────────────────────────────────────
 
-const (
-	openRouterAPIURL   = "https://openrouter.ai/api/v1/chat/completions"
-	visionMaxRetries   = 1
-	visionRetryDelay   = 2 * time.Second
-	defaultVisionModel = "google/gemma-3-27b-it"
-)
+// UpstreamName is the upstream client name for OpenRouter.
+const UpstreamName = "openrouter"
+
+const defaultVisionModel = "google/gemma-3-27b-it"
+
+// openRouterAPIURL is a var so tests can point the client at a local server.
+var openRouterAPIURL = "https://openrouter.ai/api/v1/chat/completions"
 
 // classificationPrompt asks the model to classify the image and produce a
 // description in a single pass.  The structured-output JSON schema enforces
@@ -82,9 +87,15 @@ var classificationSchema = map[string]any{
 type chatCompletionResponse struct {
 	ID      string                  `json:"id"`
 	Choices []chatCompletionChoice  `json:"choices"`
+	Usage   *chatCompletionUsage    `json:"usage,omitempty"`
 	Error   *openRouterErrorPayload `json:"error,omitempty"`
 }
 
+type chatCompletionUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
 type chatCompletionChoice struct {
 	Index        int                   `json:"index"`
 	Message      chatCompletionMessage `json:"message"`
@@ -113,18 +124,11 @@ func (e *VisionError) Error() string {
 	return fmt.Sprintf("openrouter vision %d (%s): %s", e.StatusCode, e.Code, e.Message)
 }
 
-func isVisionClientError(err error) bool {
-	if ve, ok := err.(*VisionError); ok {
-		return ve.StatusCode >= 400 && ve.StatusCode < 500
-	}
-	return false
-}
-
 // ── Public API ───────────────────────────────────────────────────────────────
 
 // RunVisionClassification sends an image URL to a vision model via OpenRouter
 // and returns a structured classification + description.
-func RunVisionClassification(ctx context.Context, imageURL string, model string, timeout time.Duration) (VisionResult, error) {
+func RunVisionClassification(ctx context.Context, imageURL string, model string, timeout time.Duration) (res VisionResult, err error) {
 	key := os.Getenv("OPENROUTER_API_KEY")
 	if key == "" {
 		return VisionResult{}, fmt.Errorf("OPENROUTER_API_KEY not configured")
@@ -138,6 +142,8 @@ func RunVisionClassification(ctx context.Context, imageURL string, model string,
 	if timeout <= 0 {
 		timeout = 30 * time.Second
 	}
+	start := time.Now()
+	defer func() { metrics.ObserveCall(metrics.ServiceVision, UpstreamName, time.Since(start), err) }()
 
 	// Build the chat completion request body
 	body := map[string]any{
@@ -168,57 +174,28 @@ func RunVisionClassification(ctx context.Context, imageURL string, model string,
 		return VisionResult{}, fmt.Errorf("marshal request: %w", err)
 	}
 
-	// Retry loop
-	var lastErr error
-	for attempt := 0; attempt <= visionMaxRetries; attempt++ {
-		if attempt > 0 {
-			select {
-			case <-ctx.Done():
-				return VisionResult{}, ctx.Err()
-			case <-time.After(visionRetryDelay * time.Duration(attempt)):
-			}
-		}
-
-		result, err := executeVisionRequest(ctx, key, bodyBytes, timeout)
-		if err == nil {
-			return result, nil
-		}
-		lastErr = err
-
-		// Don't retry client errors (4xx)
-		if isVisionClientError(err) {
-			break
-		}
+	result, err := executeVisionRequest(ctx, key, bodyBytes, timeout)
+	if err != nil {
+		return VisionResult{}, fmt.Errorf("vision classification failed: %w", err)
 	}
-
-	return VisionResult{}, fmt.Errorf("vision classification failed after %d attempts: %w", visionMaxRetries+1, lastErr)
+	return result, nil
 }
 
 // ── Internal ─────────────────────────────────────────────────────────────────
 
+// executeVisionRequest sends the completion through the shared OpenRouter
+// upstream client, which retries throttling and server errors.
 func executeVisionRequest(ctx context.Context, apiKey string, bodyBytes []byte, timeout time.Duration) (VisionResult, error) {
-	reqCtx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(reqCtx, "POST", openRouterAPIURL, bytes.NewReader(bodyBytes))
-	if err != nil {
-		return VisionResult{}, fmt.Errorf("create request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "fileproc/1.0")
-
-	client := &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			MaxIdleConns:        10,
-			IdleConnTimeout:     30 * time.Second,
-			TLSHandshakeTimeout: 10 * time.Second,
-		},
-	}
-
-	resp, err := client.Do(req)
+	resp, err := upstream.For(UpstreamName).WithTimeout(timeout).Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", openRouterAPIURL, bytes.NewReader(bodyBytes))
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "fileproc/1.0")
+		return req, nil
+	})
 	if err != nil {
 		return VisionResult{}, fmt.Errorf("request: %w", err)
 	}
@@ -241,6 +218,12 @@ func executeVisionRequest(ctx context.Context, apiKey string, bodyBytes []byte,
 		return VisionResult{}, fmt.Errorf("decode response: %w", err)
 	}
 
+	// Tokens are billed even when the content turns out to be unusable.
+	if u := completionResp.Usage; u != nil {
+		usage.Record(ctx, UpstreamName, usage.UnitInputTokens, float64(u.PromptTokens))
+		usage.Record(ctx, UpstreamName, usage.UnitOutputTokens, float64(u.CompletionTokens))
+	}
+
 	// Check for inline error (OpenRouter can return 200 with an error object)
 	if completionResp.Error != nil && completionResp.Error.Message != "" {
 		return VisionResult{}, &VisionError{
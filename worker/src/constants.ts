@@ -1,8 +1,13 @@
 export const ROUTES = {
   HEALTH: "/health",
+  CAPABILITIES: "/api/capabilities",
   PREVIEW: "/api/preview",
   EXTRACT: "/api/extract",
+  EXTRACT_BATCH: "/api/extract/batch",
+  EXTRACT_STREAM: "/api/extract/stream",
+  DETECT: "/api/detect",
   FILE_PRESIGN: "/api/file/presign",
+  JOBS: "/api/jobs",
 } as const;
 
 export const CONTAINER = {
@@ -10,8 +15,13 @@ export const CONTAINER = {
   PORT: 8080,
 
   HEALTH_URL: "http://container/health",
+  CAPABILITIES_URL: "http://container/capabilities",
   PREVIEW_URL: "http://container/preview",
   EXTRACT_URL: "http://container/extract",
+  EXTRACT_BATCH_URL: "http://container/extract/batch",
+  EXTRACT_STREAM_URL: "http://container/extract/stream",
+  DETECT_URL: "http://container/detect",
+  JOBS_URL: "http://container/jobs",
 
   START_TIMEOUT_MS: 30_000,
   HEALTH_TIMEOUT_MS: 5_000,
@@ -27,12 +37,17 @@ export const CONTAINER = {
 
 export const LIMITS = {
   JSON_BODY_MAX_BYTES: 2 * 1024 * 1024,
+  /** Async jobs may wait in the queue, so their download URL must outlive it. */
+  JOB_PRESIGN_EXPIRES_SECONDS: 3600,
+  BATCH_MAX_ITEMS: 200,
+  /** Batch items download as their turn comes up, not when the request arrives. */
+  BATCH_PRESIGN_EXPIRES_SECONDS: 1800,
 } as const;
 
 export const CORS_HEADERS: Record<string, string> = {
   "Access-Control-Allow-Origin": "*",
   "Access-Control-Allow-Methods": "GET, POST, OPTIONS",
-  "Access-Control-Allow-Headers": "Content-Type",
+  "Access-Control-Allow-Headers": "Content-Type, X-Tenant-ID, traceparent, tracestate",
   "Access-Control-Max-Age": "86400",
 };
 
@@ -17,6 +17,9 @@ export interface Env {
   R2_ACCESS_KEY_ID?: string;
   R2_SECRET_ACCESS_KEY?: string;
   R2_BUCKET_NAME?: string;
+  /** HMAC secret for async job webhooks; webhooks are disabled when unset */
+  JOB_WEBHOOK_SECRET?: string;
+  JOB_WEBHOOK_ALLOWED_HOST_SUFFIXES?: string;
 }
 
 export class FileProcContainer extends Container {
@@ -52,6 +55,8 @@ async function getReadyInstance(env: Env): Promise<FileProcContainer> {
         INTERNAL_SHARED_SECRET: env.INTERNAL_SHARED_SECRET || "",
         ALLOWED_PRESIGNED_HOST_SUFFIXES: env.ALLOWED_PRESIGNED_HOST_SUFFIXES || "",
         ALLOW_PRIVATE_DOWNLOAD_URLS: env.ALLOW_PRIVATE_DOWNLOAD_URLS || "",
+        JOB_WEBHOOK_SECRET: env.JOB_WEBHOOK_SECRET || "",
+        JOB_WEBHOOK_ALLOWED_HOST_SUFFIXES: env.JOB_WEBHOOK_ALLOWED_HOST_SUFFIXES || "",
       },
     },
     ports: CONTAINER.PORT,
@@ -98,6 +103,34 @@ function getClientIdentifier(req: Request): string {
   return req.headers.get("CF-Connecting-IP") || "unknown";
 }
 
+// Tenant whose spend budget pays for paid extraction. The container validates it.
+function tenantHeaders(req: Request): Record<string, string> {
+  const tenant = req.headers.get("X-Tenant-ID")?.trim();
+  return tenant ? { "X-Tenant-ID": tenant } : {};
+}
+
+const TRACEPARENT_RE = /^00-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$/;
+
+function randomHex(bytes: number): string {
+  const buf = new Uint8Array(bytes);
+  crypto.getRandomValues(buf);
+  return Array.from(buf, (b) => b.toString(16).padStart(2, "0")).join("");
+}
+
+// W3C trace context for the container. A valid incoming traceparent is
+// forwarded with its tracestate; otherwise the Worker starts a new trace.
+function traceHeaders(req: Request): Record<string, string> {
+  const incoming = req.headers.get("traceparent")?.trim().toLowerCase() || "";
+  const m = TRACEPARENT_RE.exec(incoming);
+  if (m && !/^0+$/.test(m[1]) && !/^0+$/.test(m[2])) {
+    const headers: Record<string, string> = { traceparent: incoming };
+    const state = req.headers.get("tracestate")?.trim();
+    if (state) headers.tracestate = state;
+    return headers;
+  }
+  return { traceparent: `00-${randomHex(16)}-${randomHex(8)}-01` };
+}
+
 function isAllowedR2Key(key: string): boolean {
   const trimmed = key.trim();
   if (trimmed === "") return false;
@@ -244,7 +277,14 @@ async function createPresignedUrlViaS3(
 // ---- File source resolution for extract/preview ----
 
 type FileSource =
-  | { type: "stream"; body: ReadableStream; fileName: string; contentType: string; size: number }
+  | {
+      type: "stream";
+      body: ReadableStream;
+      fileName: string;
+      contentType: string;
+      size: number;
+      options?: Record<string, unknown>;
+    }
   | { type: "url"; presignedUrl: string; fileName: string; options?: Record<string, unknown> };
 
 /**
@@ -271,6 +311,7 @@ async function resolveFileSource(body: any, bucket: R2Bucket, env: Env): Promise
       fileName,
       contentType: object.httpMetadata?.contentType || "application/octet-stream",
       size: object.size,
+      options: body.options,
     };
   }
 
@@ -301,18 +342,29 @@ function buildContainerRequest(
   containerUrl: string,
   source: FileSource,
   auth: string,
-  clientId: string
+  clientId: string,
+  extraHeaders: Record<string, string> = {}
 ): Request {
   if (source.type === "stream") {
+    const headers: Record<string, string> = {
+      "Content-Type": source.contentType,
+      "Content-Length": String(source.size),
+      "X-File-Name": source.fileName,
+      "X-Internal-Auth": auth,
+      "X-Forwarded-For": clientId,
+      ...extraHeaders,
+    };
+    // Options ride in a header because the body carries the file bytes.
+    // Non-ASCII is \u-escaped so the value stays a valid header ByteString.
+    if (source.options && typeof source.options === "object") {
+      headers["X-Extract-Options"] = JSON.stringify(source.options).replace(
+        /[\u007f-\uffff]/g,
+        (c) => "\\u" + c.charCodeAt(0).toString(16).padStart(4, "0")
+      );
+    }
     return new Request(containerUrl, {
       method: "POST",
-      headers: {
-        "Content-Type": source.contentType,
-        "Content-Length": String(source.size),
-        "X-File-Name": source.fileName,
-        "X-Internal-Auth": auth,
-        "X-Forwarded-For": clientId,
-      },
+      headers,
       body: source.body,
     });
   }
@@ -324,6 +376,7 @@ function buildContainerRequest(
       "Content-Type": "application/json",
       "X-Internal-Auth": auth,
       "X-Forwarded-For": clientId,
+      ...extraHeaders,
     },
     body: JSON.stringify({
       presignedUrl: source.presignedUrl,
@@ -370,6 +423,26 @@ export default {
         }
       }
 
+      if (url.pathname === ROUTES.CAPABILITIES && req.method === "GET") {
+        const inst = await getReadyInstance(env);
+        const resp = await inst.fetch(
+          new Request(CONTAINER.CAPABILITIES_URL, {
+            method: "GET",
+            headers: { "X-Internal-Auth": env.INTERNAL_SHARED_SECRET, ...traceHeaders(req) },
+          })
+        );
+
+        return new Response(await resp.text(), {
+          status: resp.status,
+          headers: {
+            "Content-Type": "application/json",
+            // Only changes on deploy or when a binary goes missing.
+            ...(resp.ok ? { "Cache-Control": "public, max-age=300" } : {}),
+            ...CORS_HEADERS,
+          },
+        });
+      }
+
       if (url.pathname === ROUTES.PREVIEW && req.method === "POST") {
         const clientId = getClientIdentifier(req);
         const rateLimit = await checkRateLimit(env.RATE_LIMITER, clientId);
@@ -405,7 +478,8 @@ export default {
           CONTAINER.PREVIEW_URL,
           source,
           env.INTERNAL_SHARED_SECRET,
-          clientId
+          clientId,
+          traceHeaders(req)
         );
         const resp = await inst.fetch(containerReq);
 
@@ -421,7 +495,15 @@ export default {
         });
       }
 
-      if (url.pathname === ROUTES.EXTRACT && req.method === "POST") {
+      if (
+        (url.pathname === ROUTES.EXTRACT || url.pathname === ROUTES.EXTRACT_STREAM || url.pathname === ROUTES.DETECT) &&
+        req.method === "POST"
+      ) {
+        const stream = url.pathname === ROUTES.EXTRACT_STREAM;
+        const detect = url.pathname === ROUTES.DETECT;
+        // Clients that accept NDJSON get PDF pages as they finish instead of one buffered result.
+        const pageStream =
+          url.pathname === ROUTES.EXTRACT && (req.headers.get("Accept") || "").includes("application/x-ndjson");
         const clientId = getClientIdentifier(req);
         const rateLimit = await checkRateLimit(env.RATE_LIMITER, clientId);
         if (!rateLimit.allowed) {
@@ -453,10 +535,15 @@ export default {
 
         const inst = await getReadyInstance(env);
         const containerReq = buildContainerRequest(
-          CONTAINER.EXTRACT_URL,
+          stream ? CONTAINER.EXTRACT_STREAM_URL : detect ? CONTAINER.DETECT_URL : CONTAINER.EXTRACT_URL,
           source,
           env.INTERNAL_SHARED_SECRET,
-          clientId
+          clientId,
+          {
+            ...tenantHeaders(req),
+            ...traceHeaders(req),
+            ...(pageStream ? { Accept: "application/x-ndjson" } : {}),
+          }
         );
         const resp = await inst.fetch(containerReq);
 
@@ -466,12 +553,96 @@ export default {
           });
         }
 
+        // Pass event and page streams through unbuffered so clients see them as they happen.
+        if ((stream || pageStream) && resp.ok) {
+          return new Response(resp.body, {
+            status: resp.status,
+            headers: {
+              "Content-Type": resp.headers.get("Content-Type") || (stream ? "text/event-stream" : "application/x-ndjson"),
+              "Cache-Control": "no-cache",
+              ...CORS_HEADERS,
+            },
+          });
+        }
+
         return new Response(await resp.text(), {
           status: resp.status,
           headers: { "Content-Type": "application/json", ...CORS_HEADERS },
         });
       }
 
+      if (url.pathname === ROUTES.EXTRACT_BATCH && req.method === "POST") {
+        const clientId = getClientIdentifier(req);
+        const rateLimit = await checkRateLimit(env.RATE_LIMITER, clientId);
+        if (!rateLimit.allowed) {
+          return json(
+            { success: false, error: "Rate limit exceeded", code: "rate_limit" },
+            { status: 429, headers: { "Retry-After": "60" } }
+          );
+        }
+
+        const body = await parseJSONBody(req);
+        const rawItems = Array.isArray(body?.items) ? body.items : null;
+        if (!rawItems || rawItems.length === 0) {
+          return json({ success: false, error: "items required", code: "bad_request" }, { status: 400 });
+        }
+        if (rawItems.length > LIMITS.BATCH_MAX_ITEMS) {
+          return json(
+            { success: false, error: `batch exceeds ${LIMITS.BATCH_MAX_ITEMS} items`, code: "bad_request" },
+            { status: 400 }
+          );
+        }
+
+        // Items are downloaded by the container, so R2 keys are presigned here.
+        // Invalid items are forwarded with an empty URL and fail individually.
+        const items = await Promise.all(
+          rawItems.map(async (item: any) => {
+            const key = getStringField(item, "key");
+            let presignedUrl = getStringField(item, "presignedUrl");
+            let fileName = getStringField(item, "fileName");
+            if (key) {
+              presignedUrl = "";
+              if (isAllowedR2Key(key)) {
+                try {
+                  presignedUrl = await createPresignedUrlViaS3(env, key, LIMITS.BATCH_PRESIGN_EXPIRES_SECONDS);
+                } catch (err: any) {
+                  console.error("batch presign failed", { error: err?.message || "unknown" });
+                }
+              }
+              fileName = fileName || key.split("/").pop() || "file";
+            }
+            return { presignedUrl, fileName: fileName || "file", options: item?.options };
+          })
+        );
+
+        const batchBody: Record<string, unknown> = { items };
+        if (typeof body.parallelism === "number") batchBody.parallelism = body.parallelism;
+
+        const inst = await getReadyInstance(env);
+        const resp = await inst.fetch(
+          new Request(CONTAINER.EXTRACT_BATCH_URL, {
+            method: "POST",
+            headers: {
+              "Content-Type": "application/json",
+              "X-Internal-Auth": env.INTERNAL_SHARED_SECRET,
+              "X-Forwarded-For": clientId,
+              ...tenantHeaders(req),
+              ...traceHeaders(req),
+            },
+            body: JSON.stringify(batchBody),
+          })
+        );
+
+        // Pass the NDJSON stream through unbuffered so clients see items as they finish.
+        return new Response(resp.body, {
+          status: resp.status,
+          headers: {
+            "Content-Type": resp.headers.get("Content-Type") || "application/x-ndjson",
+            ...CORS_HEADERS,
+          },
+        });
+      }
+
       if (url.pathname === ROUTES.FILE_PRESIGN && req.method === "POST") {
         const clientId = getClientIdentifier(req);
         const rateLimit = await checkRateLimit(env.RATE_LIMITER, clientId);
@@ -514,6 +685,103 @@ export default {
         }
       }
 
+      if (url.pathname === ROUTES.JOBS && req.method === "POST") {
+        const clientId = getClientIdentifier(req);
+        const rateLimit = await checkRateLimit(env.RATE_LIMITER, clientId);
+        if (!rateLimit.allowed) {
+          return json(
+            { success: false, error: "Rate limit exceeded", code: "rate_limit" },
+            { status: 429, headers: { "Retry-After": "60" } }
+          );
+        }
+
+        const body = await parseJSONBody(req);
+
+        // Jobs run after this request returns, so an R2 key cannot be streamed;
+        // presign it instead and let the container download when the job starts.
+        let presignedUrl = getStringField(body, "presignedUrl");
+        let fileName = getStringField(body, "fileName");
+        const key = getStringField(body, "key");
+        if (key) {
+          if (!isAllowedR2Key(key)) {
+            return json({ success: false, error: "Invalid key", code: "bad_request" }, { status: 400 });
+          }
+          const headResult = await env.FILE_BUCKET.head(key);
+          if (!headResult) {
+            return json({ success: false, error: "Not found", code: "not_found" }, { status: 404 });
+          }
+          try {
+            presignedUrl = await createPresignedUrlViaS3(env, key, LIMITS.JOB_PRESIGN_EXPIRES_SECONDS);
+          } catch (err: any) {
+            if (err?.message === "presign_unsupported") {
+              return json(
+                { success: false, error: "Jobs from R2 keys require S3 credentials", code: "internal_error" },
+                { status: 500 }
+              );
+            }
+            throw err;
+          }
+          fileName = fileName || key.split("/").pop() || "file";
+        } else if (presignedUrl) {
+          if (!isAllowedPresignedUrl(presignedUrl, allowedPresignedHostSuffixes(env), allowPrivateDownloadUrls(env))) {
+            return json({ success: false, error: "Invalid presigned URL host", code: "bad_request" }, { status: 400 });
+          }
+        } else {
+          return json(
+            { success: false, error: "presignedUrl or key required", code: "bad_request" },
+            { status: 400 }
+          );
+        }
+
+        const jobBody: Record<string, unknown> = {
+          presignedUrl,
+          fileName: fileName || "file",
+          options: body.options,
+        };
+        const webhookUrl = getStringField(body, "webhookUrl");
+        if (webhookUrl) jobBody.webhookUrl = webhookUrl;
+
+        const inst = await getReadyInstance(env);
+        const resp = await inst.fetch(
+          new Request(CONTAINER.JOBS_URL, {
+            method: "POST",
+            headers: {
+              "Content-Type": "application/json",
+              "X-Internal-Auth": env.INTERNAL_SHARED_SECRET,
+              "X-Forwarded-For": clientId,
+              ...tenantHeaders(req),
+              ...traceHeaders(req),
+            },
+            body: JSON.stringify(jobBody),
+          })
+        );
+
+        return new Response(await resp.text(), {
+          status: resp.status,
+          headers: { "Content-Type": "application/json", ...CORS_HEADERS },
+        });
+      }
+
+      if (url.pathname.startsWith(ROUTES.JOBS + "/") && req.method === "GET") {
+        const id = url.pathname.slice(ROUTES.JOBS.length + 1);
+        if (!/^[a-f0-9]{32}$/.test(id)) {
+          return json({ success: false, error: "Not found", code: "not_found" }, { status: 404 });
+        }
+
+        const inst = await getReadyInstance(env);
+        const resp = await inst.fetch(
+          new Request(`${CONTAINER.JOBS_URL}/${id}`, {
+            method: "GET",
+            headers: { "X-Internal-Auth": env.INTERNAL_SHARED_SECRET, ...traceHeaders(req) },
+          })
+        );
+
+        return new Response(await resp.text(), {
+          status: resp.status,
+          headers: { "Content-Type": "application/json", ...CORS_HEADERS },
+        });
+      }
+
       return json({ success: false, error: "Not found", code: "not_found" }, { status: 404 });
     } catch (error: any) {
       const isTimeout = error?.message?.includes("timeout") || error?.name === "TimeoutError";